@@ -3,19 +3,23 @@ package backup
 import (
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime"
 	"mime/multipart"
 	"os"
-	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influx-cli/v2/api"
 	"github.com/influxdata/influx-cli/v2/clients"
 	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/pkg/duration"
 )
 
 type Client struct {
@@ -27,6 +31,10 @@ type Client struct {
 	baseName       string
 	bucketMetadata []api.BucketMetadataManifest
 	manifest       br.Manifest
+	storage        br.Storage
+	previousShards map[int64]previousShard
+	secret         *br.EncryptionSecret
+	encryption     *br.Encryption
 }
 
 type Params struct {
@@ -38,15 +46,36 @@ type Params struct {
 	// If not set, all buckets within the org filter will be included.
 	clients.BucketParams
 
-	// Path to the directory where backup files should be written.
+	// Path to the directory where backup files should be written, or an s3:// URL to write
+	// them to S3-compatible object storage.
 	Path string
 
+	// S3 configures the connection to object storage, for S3 paths.
+	S3 br.S3Config
+
+	// Encryption configures the passphrase or key used to encrypt backup files.
+	// If not set, files are written unencrypted.
+	Encryption br.EncryptionParams
+
 	// Compression to use for local copies of snapshot files.
 	Compression br.FileCompression
 
 	// ServerGzipCompressionLevel controls the server-side gzip compression level.
 	// Valid values: "default", "full", "speedy", "none".
 	ServerGzipCompressionLevel string
+
+	// Path to the directory of an earlier backup. Shards which can't have changed since
+	// they were captured by that backup are not downloaded again.
+	IncrementalFrom string
+
+	// Parallelism is the number of shards downloaded concurrently.
+	Parallelism int
+
+	// Start and Stop limit the backup to the shard groups overlapping [Start, Stop). They're
+	// either RFC3339 times, negative durations relative to now, or "now". If not set, the range
+	// is unbounded on that side.
+	Start string
+	Stop  string
 }
 
 func (p *Params) matches(bkt api.BucketMetadataManifest) bool {
@@ -68,11 +97,45 @@ func (p *Params) matches(bkt api.BucketMetadataManifest) bool {
 const backupFilenamePattern = "20060102T150405Z"
 
 func (c *Client) Backup(ctx context.Context, params *Params) error {
-	if err := os.MkdirAll(params.Path, 0777); err != nil {
+	if params.Parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+	storage, err := br.NewStorage(params.Path, params.S3)
+	if err != nil {
 		return err
 	}
-	c.baseName = time.Now().UTC().Format(backupFilenamePattern)
-	c.manifest.Version = br.ManifestVersion
+	if local, ok := storage.(br.LocalStorage); ok {
+		if err := os.MkdirAll(local.Dir, 0777); err != nil {
+			return err
+		}
+	}
+	c.storage = storage
+	if c.secret, err = br.NewEncryptionSecret(params.Encryption); err != nil {
+		return err
+	}
+	if c.secret != nil {
+		encryption, err := c.secret.NewEncryption()
+		if err != nil {
+			return fmt.Errorf("failed to set up encryption: %w", err)
+		}
+		c.encryption = &encryption
+	}
+	now := time.Now().UTC()
+	c.baseName = now.Format(backupFilenamePattern)
+	if c.manifest.Start, err = parseRangeTime(params.Start, now); err != nil {
+		return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano or a negative duration: %w", params.Start, err)
+	}
+	if c.manifest.Stop, err = parseRangeTime(params.Stop, now); err != nil {
+		return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano or a negative duration: %w", params.Stop, err)
+	}
+	if c.manifest.Start != nil && c.manifest.Stop != nil && !c.manifest.Start.Before(*c.manifest.Stop) {
+		return fmt.Errorf("start time %q must be before stop time %q", params.Start, params.Stop)
+	}
+	if params.IncrementalFrom != "" {
+		if err := c.loadPreviousShards(ctx, params); err != nil {
+			return fmt.Errorf("failed to read backup to increment from: %w", err)
+		}
+	}
 
 	// The APIs we use to back up metadata depends on the server's version.
 	legacyServer, err := br.ServerIsLegacy(ctx, c.HealthApi)
@@ -91,7 +154,7 @@ func (c *Client) Backup(ctx context.Context, params *Params) error {
 	if err := c.downloadBucketData(ctx, params); err != nil {
 		return fmt.Errorf("failed to backup bucket data: %w", err)
 	}
-	if err := c.writeManifest(params); err != nil {
+	if err := c.writeManifest(ctx); err != nil {
 		return fmt.Errorf("failed to write backup manifest: %w", err)
 	}
 	return nil
@@ -126,42 +189,14 @@ func (c *Client) downloadMetadata(ctx context.Context, params *Params) error {
 	defer body.Close()
 
 	writeFile := func(from io.Reader, to string) (br.ManifestFileEntry, error) {
-		toPath := filepath.Join(params.Path, to)
 		if params.Compression == br.GzipCompression {
-			toPath = toPath + ".gz"
+			to = to + ".gz"
 		}
-
-		// Closure here so we can clean up file resources via `defer` without
-		// returning from the whole function.
-		if err := func() error {
-			out, err := os.OpenFile(toPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-			if err != nil {
-				return err
-			}
-			defer out.Close()
-
-			var outW io.Writer = out
-			if params.Compression == br.GzipCompression {
-				gw := gzip.NewWriter(out)
-				defer gw.Close()
-				outW = gw
-			}
-
-			_, err = io.Copy(outW, from)
-			return err
-		}(); err != nil {
-			return br.ManifestFileEntry{}, err
-		}
-
-		fi, err := os.Stat(toPath)
+		fi, err := c.writeFile(ctx, to, from, params.Compression, params.Compression == br.GzipCompression)
 		if err != nil {
 			return br.ManifestFileEntry{}, err
 		}
-		return br.ManifestFileEntry{
-			FileName:    fi.Name(),
-			Size:        fi.Size(),
-			Compression: params.Compression,
-		}, nil
+		return *fi, nil
 	}
 
 	mr := multipart.NewReader(body, contentParams["boundary"])
@@ -215,83 +250,92 @@ func (c *Client) downloadMetadataLegacy(ctx context.Context, params *Params) err
 	}
 	defer rawResp.Body.Close()
 
-	kvName := filepath.Join(params.Path, fmt.Sprintf("%s.bolt", c.baseName))
-	tmpKv := fmt.Sprintf("%s.tmp", kvName)
-	defer os.RemoveAll(tmpKv)
+	kvName := fmt.Sprintf("%s.bolt", c.baseName)
+	tmpKv, err := os.CreateTemp("", "influx-backup-*.bolt")
+	if err != nil {
+		return fmt.Errorf("failed to save downloaded KV snapshot: %w", err)
+	}
+	defer os.RemoveAll(tmpKv.Name())
 
 	// Since we need to read the bolt DB to extract a manifest, always save it uncompressed locally.
-	if err := func() error {
-		f, err := os.Create(tmpKv)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-
-		_, err = io.Copy(f, rawResp.Body)
-		return err
-	}(); err != nil {
+	_, err = io.Copy(tmpKv, rawResp.Body)
+	if closeErr := tmpKv.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		return fmt.Errorf("failed to save downloaded KV snapshot: %w", err)
 	}
 
 	// Extract the metadata we need from the downloaded KV store, and convert it to a new-style manifest.
 	log.Println("INFO: Extracting bucket manifest from legacy KV snapshot")
-	c.bucketMetadata, err = br.ExtractBucketMetadata(tmpKv)
+	c.bucketMetadata, err = br.ExtractBucketMetadata(tmpKv.Name())
 	if err != nil {
 		return fmt.Errorf("failed to extract bucket metadata from downloaded KV snapshot: %w", err)
 	}
 
-	// Move/compress the bolt DB into its final location.
-	if err := func() error {
-		if params.Compression == br.NoCompression {
-			return os.Rename(tmpKv, kvName)
-		}
-
-		tmpIn, err := os.Open(tmpKv)
-		if err != nil {
-			return err
-		}
-		defer tmpIn.Close()
-
-		kvName = kvName + ".gz"
-		out, err := os.Create(kvName)
-		if err != nil {
-			return err
-		}
-		defer out.Close()
-
-		gzw := gzip.NewWriter(out)
-		defer gzw.Close()
-
-		_, err = io.Copy(gzw, tmpIn)
-		return err
-	}(); err != nil {
-		return fmt.Errorf("failed to rename downloaded KV snapshot: %w", err)
-	}
-
-	fi, err := os.Stat(kvName)
+	// Copy/compress the bolt DB into its final location.
+	tmpIn, err := os.Open(tmpKv.Name())
 	if err != nil {
 		return fmt.Errorf("failed to inspect local KV snapshot: %w", err)
 	}
-	c.manifest.KV = br.ManifestFileEntry{
-		FileName:    fi.Name(),
-		Size:        fi.Size(),
-		Compression: params.Compression,
+	defer tmpIn.Close()
+	if params.Compression == br.GzipCompression {
+		kvName = kvName + ".gz"
+	}
+	fi, err := c.writeFile(ctx, kvName, tmpIn, params.Compression, params.Compression == br.GzipCompression)
+	if err != nil {
+		return fmt.Errorf("failed to save local copy of KV backup to %q: %w", kvName, err)
 	}
+	c.manifest.KV = *fi
 	return nil
 }
 
 // downloadBucketData downloads TSM snapshots for each shard in the buckets matching
-// the filter parameters provided over the CLI. Snapshots are written to local files.
+// the filter parameters provided over the CLI. Snapshots are written to local files,
+// using up to params.Parallelism concurrent downloads.
 //
 // Bucket metadata must be pre-seeded via downloadMetadata before this method is called.
 func (c *Client) downloadBucketData(ctx context.Context, params *Params) error {
-	c.manifest.Buckets = make([]br.ManifestBucketEntry, 0, len(c.bucketMetadata))
+	var buckets []api.BucketMetadataManifest
+	var toDownload []int64
+	reused := map[int64]br.ManifestFileEntry{}
 	for _, b := range c.bucketMetadata {
 		if !params.matches(b) {
 			continue
 		}
+		if c.manifest.IsPartialRange() {
+			b = c.shardGroupsInRange(b)
+		}
+		buckets = append(buckets, b)
+		reusable := c.reusableShards(b)
+		for _, rp := range b.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				for _, sh := range sg.Shards {
+					if file, ok := reusable[sh.Id]; ok {
+						log.Printf("INFO: Shard %d is unchanged since it was backed up to %q, skipping", sh.Id, file.Path(params.Path))
+						reused[sh.Id] = file
+						continue
+					}
+					toDownload = append(toDownload, sh.Id)
+				}
+			}
+		}
+	}
+
+	downloaded, err := c.downloadShards(ctx, params, toDownload)
+	if err != nil {
+		return err
+	}
+
+	// Build the manifest once all downloads are done, so it follows the order of the metadata.
+	c.manifest.Buckets = make([]br.ManifestBucketEntry, 0, len(buckets))
+	for _, b := range buckets {
 		bktManifest, err := br.ConvertBucketManifest(b, func(shardId int64) (*br.ManifestFileEntry, error) {
-			return c.downloadShardData(ctx, params, shardId)
+			if file, ok := reused[shardId]; ok {
+				return &file, nil
+			}
+			// Shards removed during the backup have no file.
+			return downloaded[shardId], nil
 		})
 		if err != nil {
 			return err
@@ -301,6 +345,60 @@ func (c *Client) downloadBucketData(ctx context.Context, params *Params) error {
 	return nil
 }
 
+// shardGroupsInRange returns a copy of the metadata of a bucket, only holding the shard groups
+// overlapping the time range of the backup.
+func (c Client) shardGroupsInRange(b api.BucketMetadataManifest) api.BucketMetadataManifest {
+	rps := make([]api.RetentionPolicyManifest, len(b.RetentionPolicies))
+	for i, rp := range b.RetentionPolicies {
+		shardGroups := make([]api.ShardGroupManifest, 0, len(rp.ShardGroups))
+		for _, sg := range rp.ShardGroups {
+			if c.manifest.InRange(sg.StartTime, sg.EndTime) {
+				shardGroups = append(shardGroups, sg)
+			}
+		}
+		rp.ShardGroups = shardGroups
+		rps[i] = rp
+	}
+	b.RetentionPolicies = rps
+	return b
+}
+
+// downloadShards downloads the TSM snapshots of the shards with the given IDs, using up to
+// params.Parallelism concurrent downloads, and returns their files by shard ID.
+func (c Client) downloadShards(ctx context.Context, params *Params, shardIds []int64) (map[int64]*br.ManifestFileEntry, error) {
+	files := make([]*br.ManifestFileEntry, len(shardIds))
+	var (
+		mu   sync.Mutex
+		done int
+		size int64
+	)
+	err := br.RunParallel(ctx, params.Parallelism, len(shardIds), func(ctx context.Context, i int) error {
+		file, err := c.downloadShardData(ctx, params, shardIds[i])
+		if err != nil {
+			return fmt.Errorf("failed to download snapshot of shard %d: %w", shardIds[i], err)
+		}
+		files[i] = file
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if file != nil {
+			size += file.Size
+		}
+		log.Printf("INFO: Backed up %d/%d shards (%d bytes)", done, len(shardIds), size)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int64]*br.ManifestFileEntry, len(shardIds))
+	for i, id := range shardIds {
+		byId[id] = files[i]
+	}
+	return byId, nil
+}
+
 // downloadShardData downloads the TSM snapshot for a single shard. The snapshot is written
 // to a local file, and its metadata is returned for aggregation.
 func (c Client) downloadShardData(ctx context.Context, params *Params, shardId int64) (*br.ManifestFileEntry, error) {
@@ -325,63 +423,110 @@ func (c Client) downloadShardData(ctx context.Context, params *Params, shardId i
 	if params.Compression == br.GzipCompression {
 		fileName = fileName + ".gz"
 	}
-	path := filepath.Join(params.Path, fileName)
 
-	// Closure here so we can clean up file resources via `defer` without
-	// returning from the whole function.
-	if err := func() error {
-		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	// Make sure the locally-written data is compressed according to the user's request.
+	var inR io.Reader = res.Body
+	compress := params.Compression == br.GzipCompression && res.Header.Get("Content-Encoding") != "gzip"
+	if params.Compression == br.NoCompression && res.Header.Get("Content-Encoding") == "gzip" {
+		gzr, err := gzip.NewReader(inR)
 		if err != nil {
-			return err
-		}
-		defer f.Close()
-
-		var inR io.Reader = res.Body
-		var outW io.Writer = f
-
-		// Make sure the locally-written data is compressed according to the user's request.
-		if params.Compression == br.GzipCompression && res.Header.Get("Content-Encoding") != "gzip" {
-			gzw := gzip.NewWriter(outW)
-			defer gzw.Close()
-			outW = gzw
-		}
-		if params.Compression == br.NoCompression && res.Header.Get("Content-Encoding") == "gzip" {
-			gzr, err := gzip.NewReader(inR)
-			if err != nil {
-				return err
-			}
-			defer gzr.Close()
-			inR = gzr
+			return nil, err
 		}
+		defer gzr.Close()
+		inR = gzr
+	}
+	return c.writeFile(ctx, fileName, inR, params.Compression, compress)
+}
 
-		_, err = io.Copy(outW, inR)
-		return err
-	}(); err != nil {
+// writeFile copies the contents of r to a new file in the backup's storage, compressing them
+// first if compress is set and then encrypting them if the backup is encrypted, and returns
+// the file's entry in the manifest.
+func (c Client) writeFile(ctx context.Context, name string, r io.Reader, compression br.FileCompression, compress bool) (*br.ManifestFileEntry, error) {
+	out, err := c.storage.Create(ctx, name)
+	if err != nil {
 		return nil, err
 	}
-
-	fi, err := os.Stat(path)
+	h := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(out, h)}
+
+	var w io.Writer = counter
+	var encw io.WriteCloser
+	if c.encryption != nil {
+		if encw, err = c.secret.Encrypt(w, *c.encryption); err != nil {
+			out.Abort()
+			return nil, err
+		}
+		w = encw
+	}
+	var gzw *gzip.Writer
+	if compress {
+		gzw = gzip.NewWriter(w)
+		w = gzw
+	}
+	_, err = io.Copy(w, r)
+	if err == nil && gzw != nil {
+		err = gzw.Close()
+	}
+	if err == nil && encw != nil {
+		err = encw.Close()
+	}
 	if err != nil {
+		out.Abort()
 		return nil, err
 	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
 	return &br.ManifestFileEntry{
-		FileName:    fi.Name(),
-		Size:        fi.Size(),
-		Compression: params.Compression,
+		FileName:    name,
+		Size:        counter.n,
+		Compression: compression,
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+		Encryption:  c.encryption,
 	}, nil
 }
 
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
 // writeManifest writes a description of all files downloaded as part of the backup process
-// to the backup folder, encoded as JSON.
-func (c Client) writeManifest(params *Params) error {
-	manifestPath := filepath.Join(params.Path, fmt.Sprintf("%s.%s", c.baseName, br.ManifestExtension))
-	f, err := os.OpenFile(manifestPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+// to the backup's storage, encoded as JSON.
+func (c Client) writeManifest(ctx context.Context) error {
+	out, err := c.storage.Create(ctx, fmt.Sprintf("%s.%s", c.baseName, br.ManifestExtension))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	enc := json.NewEncoder(f)
+	manifest := c.manifest
+	manifest.Version = manifest.MinVersion()
+	enc := json.NewEncoder(out)
 	enc.SetIndent("", "  ")
-	return enc.Encode(c.manifest)
+	if err := enc.Encode(manifest); err != nil {
+		out.Abort()
+		return err
+	}
+	return out.Close()
+}
+
+// parseRangeTime parses s as an RFC3339 time, a negative duration relative to now like -7d, or
+// "now", returning nil if s is empty.
+func parseRangeTime(s string, now time.Time) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := duration.ParseTime(s, now)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
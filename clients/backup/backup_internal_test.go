@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,12 +15,14 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/influxdata/influx-cli/v2/api"
 	"github.com/influxdata/influx-cli/v2/clients"
 	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
 	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/influxdata/influx-cli/v2/internal/testutils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -159,6 +163,7 @@ func TestBackup_DownloadMetadata(t *testing.T) {
 			out, err := os.MkdirTemp("", "")
 			require.NoError(t, err)
 			defer os.RemoveAll(out)
+			cli.storage = br.LocalStorage{Dir: out}
 
 			params := Params{
 				Path:        out,
@@ -197,6 +202,12 @@ func TestBackup_DownloadMetadata(t *testing.T) {
 			sqlBytes, err := io.ReadAll(sqlReader)
 			require.NoError(t, err)
 			require.Equal(t, fakeSQL, string(sqlBytes))
+
+			for _, file := range []br.ManifestFileEntry{cli.manifest.KV, *cli.manifest.SQL} {
+				checksum, err := fileSHA256(filepath.Join(out, file.FileName))
+				require.NoError(t, err)
+				require.Equal(t, checksum, file.SHA256)
+			}
 		})
 	}
 }
@@ -271,6 +282,7 @@ func TestBackup_DownloadShardData(t *testing.T) {
 			out, err := os.MkdirTemp("", "")
 			require.NoError(t, err)
 			defer os.RemoveAll(out)
+			cli.storage = br.LocalStorage{Dir: out}
 
 			params := Params{
 				Path:        out,
@@ -294,6 +306,10 @@ func TestBackup_DownloadShardData(t *testing.T) {
 			shardBytes, err := io.ReadAll(shardReader)
 			require.NoError(t, err)
 			require.Equal(t, fakeTsm, string(shardBytes))
+
+			checksum, err := fileSHA256(filepath.Join(out, metadata.FileName))
+			require.NoError(t, err)
+			require.Equal(t, checksum, metadata.SHA256)
 		})
 	}
 
@@ -316,6 +332,7 @@ func TestBackup_DownloadShardData(t *testing.T) {
 		out, err := os.MkdirTemp("", "")
 		require.NoError(t, err)
 		defer os.RemoveAll(out)
+		cli.storage = br.LocalStorage{Dir: out}
 
 		params := Params{
 			Path: out,
@@ -336,3 +353,325 @@ func (e *notFoundErr) Error() string {
 func (e *notFoundErr) ErrorCode() api.ErrorCode {
 	return api.ERRORCODE_NOT_FOUND
 }
+
+func TestBackup_Incremental(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	prevDir := filepath.Join(root, "prev")
+	require.NoError(t, os.MkdirAll(prevDir, 0777))
+
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	shardGroup := func(id int64, start, end time.Time) api.ShardGroupManifest {
+		return api.ShardGroupManifest{Id: id, StartTime: start, EndTime: end, Shards: []api.ShardManifest{{Id: id * 10}}}
+	}
+	bucketMetadata := []api.BucketMetadataManifest{{
+		OrganizationID:   "123",
+		OrganizationName: "org",
+		BucketID:         "456",
+		BucketName:       "bucket",
+		RetentionPolicies: []api.RetentionPolicyManifest{{
+			Name: "autogen",
+			ShardGroups: []api.ShardGroupManifest{
+				// Ended before the previous backup.
+				shardGroup(1, day(1), day(2)),
+				// Still written to when the previous backup was taken.
+				shardGroup(2, day(2), day(3)),
+				// Created since the previous backup.
+				shardGroup(3, day(3), day(4)),
+			},
+		}},
+	}}
+
+	// The previous backup captured shard groups 1 and 2 on day 2.
+	prevManifest := br.Manifest{Version: br.ManifestVersion}
+	prevBucket, err := br.ConvertBucketManifest(api.BucketMetadataManifest{
+		BucketID:          "456",
+		RetentionPolicies: []api.RetentionPolicyManifest{{ShardGroups: bucketMetadata[0].RetentionPolicies[0].ShardGroups[:2]}},
+	}, func(shardId int64) (*br.ManifestFileEntry, error) {
+		return &br.ManifestFileEntry{FileName: fmt.Sprintf("20210102T120000Z.%d.tar.gz", shardId), Compression: br.GzipCompression}, nil
+	})
+	require.NoError(t, err)
+	prevManifest.Buckets = []br.ManifestBucketEntry{prevBucket}
+	manifestBytes, err := json.Marshal(prevManifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(prevDir, "20210102T120000Z.manifest"), manifestBytes, 0600))
+
+	ctrl := gomock.NewController(t)
+	backupApi := mock.NewMockBackupApi(ctrl)
+	var downloaded []int64
+	backupApi.EXPECT().GetBackupShardId(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id int64) api.ApiGetBackupShardIdRequest {
+			downloaded = append(downloaded, id)
+			return api.ApiGetBackupShardIdRequest{ApiService: backupApi}.ShardID(id)
+		}).Times(2)
+	backupApi.EXPECT().GetBackupShardIdExecute(gomock.Any()).
+		DoAndReturn(func(api.ApiGetBackupShardIdRequest) (*http.Response, error) {
+			return &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("tsm"))}, nil
+		}).Times(2)
+
+	cli := Client{
+		CLI:            clients.CLI{},
+		BackupApi:      backupApi,
+		baseName:       "20210103T120000Z",
+		bucketMetadata: bucketMetadata,
+	}
+	params := Params{
+		Path:            filepath.Join(root, "next"),
+		Compression:     br.GzipCompression,
+		IncrementalFrom: prevDir,
+	}
+	require.NoError(t, os.MkdirAll(params.Path, 0777))
+	cli.storage = br.LocalStorage{Dir: params.Path}
+	require.NoError(t, cli.loadPreviousShards(context.Background(), &params))
+	require.NoError(t, cli.downloadBucketData(context.Background(), &params))
+
+	require.Equal(t, []int64{20, 30}, downloaded)
+	require.Equal(t, "../prev", cli.manifest.IncrementalFrom)
+	shardGroups := cli.manifest.Buckets[0].RetentionPolicies[0].ShardGroups
+	reused := shardGroups[0].Shards[0]
+	require.Equal(t, "20210102T120000Z.10.tar.gz", reused.FileName)
+	require.Equal(t, "../prev", reused.Dir)
+	require.Equal(t, filepath.Join(prevDir, reused.FileName), reused.Path(params.Path))
+	require.Empty(t, shardGroups[1].Shards[0].Dir)
+	require.Equal(t, "20210103T120000Z.20.tar.gz", shardGroups[1].Shards[0].FileName)
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 checksum of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func TestBackup_S3(t *testing.T) {
+	t.Parallel()
+
+	fake := testutils.NewFakeS3(t)
+	fake.MinPartSize = 16
+	storage, err := br.NewS3Storage(br.S3Config{Endpoint: fake.URL}, "backups", "influx")
+	require.NoError(t, err)
+	storage.PartSize = 16
+
+	fakeTsm := strings.Repeat("tsm", 20)
+	ctrl := gomock.NewController(t)
+	backupApi := mock.NewMockBackupApi(ctrl)
+	backupApi.EXPECT().GetBackupShardId(gomock.Any(), gomock.Any()).
+		Return(api.ApiGetBackupShardIdRequest{ApiService: backupApi}.ShardID(1))
+	backupApi.EXPECT().GetBackupShardIdExecute(gomock.Any()).
+		Return(&http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(fakeTsm))}, nil)
+
+	cli := Client{
+		CLI:       clients.CLI{},
+		BackupApi: backupApi,
+		baseName:  "test",
+		storage:   storage,
+	}
+	params := Params{Path: "s3://backups/influx", Compression: br.NoCompression}
+	file, err := cli.downloadShardData(context.Background(), &params, 1)
+	require.NoError(t, err)
+	cli.manifest.KV = *file
+	require.NoError(t, cli.writeManifest(context.Background()))
+
+	objects := fake.Objects()
+	require.Equal(t, fakeTsm, string(objects["backups/influx/test.1.tar"]))
+	require.Greater(t, fake.UploadedParts(), 1)
+	require.Zero(t, fake.PendingUploads())
+	sum := sha256.Sum256([]byte(fakeTsm))
+	require.Equal(t, br.ManifestFileEntry{
+		FileName:    "test.1.tar",
+		Size:        int64(len(fakeTsm)),
+		Compression: br.NoCompression,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}, *file)
+
+	var manifest br.Manifest
+	require.NoError(t, json.Unmarshal(objects["backups/influx/test.manifest"], &manifest))
+	require.Equal(t, *file, manifest.KV)
+}
+
+func TestBackup_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	out := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "backup.key")
+	require.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, 32), 0600))
+	secret, err := br.NewEncryptionSecret(br.EncryptionParams{KeyFile: keyFile})
+	require.NoError(t, err)
+	encryption, err := secret.NewEncryption()
+	require.NoError(t, err)
+
+	fakeTsm := strings.Repeat("tsm", 100)
+	ctrl := gomock.NewController(t)
+	backupApi := mock.NewMockBackupApi(ctrl)
+	backupApi.EXPECT().GetBackupShardId(gomock.Any(), gomock.Any()).
+		Return(api.ApiGetBackupShardIdRequest{ApiService: backupApi}.ShardID(1))
+	backupApi.EXPECT().GetBackupShardIdExecute(gomock.Any()).
+		Return(&http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(fakeTsm))}, nil)
+
+	cli := Client{
+		CLI:        clients.CLI{},
+		BackupApi:  backupApi,
+		baseName:   "test",
+		storage:    br.LocalStorage{Dir: out},
+		secret:     secret,
+		encryption: &encryption,
+	}
+	params := Params{Path: out, Compression: br.GzipCompression}
+	file, err := cli.downloadShardData(context.Background(), &params, 1)
+	require.NoError(t, err)
+	require.Equal(t, &encryption, file.Encryption)
+
+	stored, err := os.ReadFile(filepath.Join(out, "test.1.tar.gz"))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(stored)), file.Size)
+	_, err = gzip.NewReader(bytes.NewReader(stored))
+	require.Error(t, err, "stored file should be encrypted")
+
+	r, err := br.OpenFile(context.Background(), cli.storage, *file, secret)
+	require.NoError(t, err)
+	defer r.Close()
+	gzr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	contents, err := io.ReadAll(gzr)
+	require.NoError(t, err)
+	require.Equal(t, fakeTsm, string(contents))
+
+	_, err = br.OpenFile(context.Background(), cli.storage, *file, nil)
+	require.Error(t, err)
+
+	// Incremental backups only refer to files they can be restored from.
+	require.True(t, cli.canDecrypt(*file))
+	require.False(t, cli.canDecrypt(br.ManifestFileEntry{FileName: "unencrypted.tar.gz"}))
+	require.False(t, Client{}.canDecrypt(*file))
+}
+
+func TestBackup_Parallel(t *testing.T) {
+	t.Parallel()
+
+	bucket := func(id string, shardIds ...int64) api.BucketMetadataManifest {
+		shardGroups := make([]api.ShardGroupManifest, len(shardIds))
+		for i, shardId := range shardIds {
+			shardGroups[i] = api.ShardGroupManifest{Id: shardId, Shards: []api.ShardManifest{{Id: shardId}}}
+		}
+		return api.BucketMetadataManifest{
+			BucketID:          id,
+			RetentionPolicies: []api.RetentionPolicyManifest{{Name: "autogen", ShardGroups: shardGroups}},
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	backupApi := mock.NewMockBackupApi(ctrl)
+	backupApi.EXPECT().GetBackupShardId(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id int64) api.ApiGetBackupShardIdRequest {
+			return api.ApiGetBackupShardIdRequest{ApiService: backupApi}.ShardID(id)
+		}).Times(6)
+	backupApi.EXPECT().GetBackupShardIdExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiGetBackupShardIdRequest) (*http.Response, error) {
+			if req.GetShardID() == 4 {
+				return nil, &notFoundErr{}
+			}
+			body := strings.Repeat(fmt.Sprintf("tsm%d", req.GetShardID()), int(req.GetShardID()))
+			return &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}).Times(6)
+
+	out := t.TempDir()
+	cli := Client{
+		CLI:            clients.CLI{},
+		BackupApi:      backupApi,
+		baseName:       "test",
+		bucketMetadata: []api.BucketMetadataManifest{bucket("1", 5, 3, 1), bucket("2", 2, 6, 4)},
+		storage:        br.LocalStorage{Dir: out},
+	}
+	params := Params{Path: out, Compression: br.NoCompression, Parallelism: 3}
+	require.NoError(t, cli.downloadBucketData(context.Background(), &params))
+
+	// The manifest follows the order of the bucket metadata, whatever order shards finish in.
+	var shardIds []int64
+	for _, b := range cli.manifest.Buckets {
+		for _, sg := range b.RetentionPolicies[0].ShardGroups {
+			for _, sh := range sg.Shards {
+				shardIds = append(shardIds, sh.ID)
+				require.Equal(t, fmt.Sprintf("test.%d.tar", sh.ID), sh.FileName)
+				require.Equal(t, int64(4*sh.ID), sh.Size)
+			}
+		}
+	}
+	require.Equal(t, []int64{5, 3, 1, 2, 6}, shardIds)
+}
+
+func TestBackup_TimeRange(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	shardGroup := func(id int64, start, end time.Time) api.ShardGroupManifest {
+		return api.ShardGroupManifest{Id: id, StartTime: start, EndTime: end, Shards: []api.ShardManifest{{Id: id * 10}}}
+	}
+
+	ctrl := gomock.NewController(t)
+	backupApi := mock.NewMockBackupApi(ctrl)
+	var downloaded []int64
+	backupApi.EXPECT().GetBackupShardId(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id int64) api.ApiGetBackupShardIdRequest {
+			downloaded = append(downloaded, id)
+			return api.ApiGetBackupShardIdRequest{ApiService: backupApi}.ShardID(id)
+		}).Times(1)
+	backupApi.EXPECT().GetBackupShardIdExecute(gomock.Any()).
+		DoAndReturn(func(api.ApiGetBackupShardIdRequest) (*http.Response, error) {
+			return &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("tsm"))}, nil
+		}).Times(1)
+
+	out := t.TempDir()
+	cli := Client{
+		CLI:       clients.CLI{},
+		BackupApi: backupApi,
+		baseName:  "test",
+		storage:   br.LocalStorage{Dir: out},
+		bucketMetadata: []api.BucketMetadataManifest{{
+			BucketID: "456",
+			RetentionPolicies: []api.RetentionPolicyManifest{{
+				Name: "autogen",
+				ShardGroups: []api.ShardGroupManifest{
+					shardGroup(1, day(1), day(2)),
+					shardGroup(2, day(2), day(3)),
+					shardGroup(3, day(3), day(4)),
+					shardGroup(4, day(4), day(5)),
+				},
+			}},
+		}},
+	}
+	now := day(10)
+	var err error
+	cli.manifest.Start, err = parseRangeTime("2021-01-02T12:00:00Z", now)
+	require.NoError(t, err)
+	cli.manifest.Stop, err = parseRangeTime("-7d", now)
+	require.NoError(t, err)
+	require.Equal(t, day(3), *cli.manifest.Stop)
+
+	params := Params{Path: out, Compression: br.NoCompression}
+	require.NoError(t, cli.downloadBucketData(context.Background(), &params))
+	require.Equal(t, []int64{20}, downloaded)
+
+	var shardGroupIds []int64
+	for _, sg := range cli.manifest.Buckets[0].RetentionPolicies[0].ShardGroups {
+		shardGroupIds = append(shardGroupIds, sg.ID)
+	}
+	require.Equal(t, []int64{2}, shardGroupIds)
+
+	// Older CLIs would restore the backup as if it held every shard group of the bucket.
+	require.NoError(t, cli.writeManifest(context.Background()))
+	manifestBytes, err := os.ReadFile(filepath.Join(out, "test.manifest"))
+	require.NoError(t, err)
+	var manifest br.Manifest
+	require.NoError(t, json.Unmarshal(manifestBytes, &manifest))
+	require.Equal(t, br.ManifestVersion, manifest.Version)
+	require.Equal(t, day(3), *manifest.Stop)
+}
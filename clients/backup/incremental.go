@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients/restore"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+)
+
+// previousShard is the snapshot of a shard captured by an earlier backup.
+type previousShard struct {
+	bucketID string
+	group    br.ManifestShardGroup
+	// file is relative to the directory of the new backup.
+	file br.ManifestFileEntry
+	// takenAt is when the earlier backup started.
+	takenAt time.Time
+}
+
+// loadPreviousShards indexes the shard snapshots captured by the backups in params.IncrementalFrom.
+func (c *Client) loadPreviousShards(ctx context.Context, params *Params) error {
+	if _, ok := c.storage.(br.LocalStorage); !ok {
+		return errors.New("incremental backups can only be written to a local directory")
+	}
+	prevStorage := br.LocalStorage{Dir: params.IncrementalFrom}
+	manifests, err := restore.FindManifests(ctx, prevStorage)
+	if err != nil {
+		return err
+	}
+	relDir, err := relativeDir(params.Path, params.IncrementalFrom)
+	if err != nil {
+		return err
+	}
+	c.manifest.IncrementalFrom = relDir
+	if relDir == "" {
+		c.manifest.IncrementalFrom = "."
+	}
+
+	c.previousShards = map[int64]previousShard{}
+	for _, manifestFile := range manifests {
+		takenAt, err := time.Parse(backupFilenamePattern, strings.TrimSuffix(manifestFile, "."+br.ManifestExtension))
+		if err != nil {
+			log.Printf("WARN: Can't tell when the backup with manifest %q was taken, ignoring it", manifestFile)
+			continue
+		}
+		manifest, err := restore.ReadManifest(ctx, prevStorage, manifestFile)
+		if err != nil {
+			return err
+		}
+		for _, b := range manifest.Buckets {
+			for _, rp := range b.RetentionPolicies {
+				for _, sg := range rp.ShardGroups {
+					for _, sh := range sg.Shards {
+						file := sh.ManifestFileEntry
+						if file.Dir, err = relativeDir(params.Path, filepath.Join(params.IncrementalFrom, filepath.FromSlash(file.Dir))); err != nil {
+							return err
+						}
+						// Manifests are sorted in ascending time, so later snapshots replace earlier ones.
+						c.previousShards[sh.ID] = previousShard{bucketID: b.BucketID, group: sg, file: file, takenAt: takenAt}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// reusableShards returns the snapshots captured by earlier backups of the shards of bkt that
+// can't have changed since, because their shard group had ended before the snapshot was taken.
+func (c Client) reusableShards(bkt api.BucketMetadataManifest) map[int64]br.ManifestFileEntry {
+	reusable := map[int64]br.ManifestFileEntry{}
+	for _, rp := range bkt.RetentionPolicies {
+		for _, sg := range rp.ShardGroups {
+			for _, sh := range sg.Shards {
+				prev, ok := c.previousShards[sh.Id]
+				if !ok || prev.bucketID != bkt.BucketID || prev.group.ID != sg.Id ||
+					!prev.group.StartTime.Equal(sg.StartTime) || !prev.group.EndTime.Equal(sg.EndTime) ||
+					!sameTime(prev.group.TruncatedAt, sg.TruncatedAt) || sg.DeletedAt != nil ||
+					sg.EndTime.After(prev.takenAt) || !c.canDecrypt(prev.file) {
+					continue
+				}
+				reusable[sh.Id] = prev.file
+			}
+		}
+	}
+	return reusable
+}
+
+// canDecrypt returns whether a file captured by an earlier backup can be decrypted with the
+// secret of the new backup, so a backup never refers to files it can't be restored from.
+func (c Client) canDecrypt(file br.ManifestFileEntry) bool {
+	if file.Encryption == nil || c.secret == nil {
+		return file.Encryption == nil && c.secret == nil
+	}
+	return c.secret.Matches(*file.Encryption)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// relativeDir returns the path of dir relative to base, using forward slashes, or "" if
+// they're the same directory.
+func relativeDir(base, dir string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absBase, absDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to find %q relative to %q: %w", dir, base, err)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
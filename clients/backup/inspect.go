@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/clients/restore"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+)
+
+type InspectParams struct {
+	// Path to the directory of the backup to inspect, or an s3:// URL of a backup in
+	// S3-compatible object storage.
+	Path string
+
+	// S3 configures the connection to object storage, for S3 paths.
+	S3 br.S3Config
+}
+
+// InspectedManifest is the content of a backup manifest, converted to the latest version of
+// the manifest if it was written by an older version of the CLI.
+type InspectedManifest struct {
+	Name string `json:"name"`
+	br.Manifest
+}
+
+// Inspect prints the contents of every backup manifest at a location, without reading the
+// files of the backups.
+func (c Client) Inspect(ctx context.Context, params *InspectParams) error {
+	storage, err := br.NewStorage(params.Path, params.S3)
+	if err != nil {
+		return err
+	}
+	names, err := restore.FindManifests(ctx, storage)
+	if err != nil {
+		return err
+	}
+	manifests := make([]InspectedManifest, len(names))
+	for i, name := range names {
+		manifest, err := restore.ReadManifest(ctx, storage, name)
+		if err != nil {
+			return err
+		}
+		manifests[i] = InspectedManifest{Name: name, Manifest: manifest}
+	}
+
+	if c.PrintAsJSON {
+		return c.PrintJSON(manifests)
+	}
+
+	var rows []map[string]interface{}
+	for _, m := range manifests {
+		for _, b := range m.Buckets {
+			// Buckets and retention policies without shard groups are still listed.
+			row := map[string]interface{}{
+				"Manifest":         m.Name,
+				"Organization":     b.OrganizationName,
+				"Bucket":           b.BucketName,
+				"Retention Policy": "",
+				"Shard Group":      "",
+				"Start":            "",
+				"End":              "",
+				"Shards":           0,
+				"Size":             0,
+				"Compression":      "",
+			}
+			if len(b.RetentionPolicies) == 0 {
+				rows = append(rows, row)
+			}
+			for _, rp := range b.RetentionPolicies {
+				if len(rp.ShardGroups) == 0 {
+					rows = append(rows, withFields(row, map[string]interface{}{"Retention Policy": rp.Name}))
+				}
+				for _, sg := range rp.ShardGroups {
+					var size int64
+					compressions := map[string]bool{}
+					var compression []string
+					for _, sh := range sg.Shards {
+						size += sh.Size
+						desc := sh.Compression.String()
+						if sh.Encryption != nil {
+							desc += ", encrypted"
+						}
+						if !compressions[desc] {
+							compressions[desc] = true
+							compression = append(compression, desc)
+						}
+					}
+					rows = append(rows, withFields(row, map[string]interface{}{
+						"Retention Policy": rp.Name,
+						"Shard Group":      sg.ID,
+						"Start":            sg.StartTime.Format(time.RFC3339),
+						"End":              sg.EndTime.Format(time.RFC3339),
+						"Shards":           len(sg.Shards),
+						"Size":             size,
+						"Compression":      strings.Join(compression, "; "),
+					}))
+				}
+			}
+		}
+	}
+	return c.PrintTable([]string{
+		"Manifest", "Organization", "Bucket", "Retention Policy", "Shard Group", "Start", "End", "Shards", "Size", "Compression",
+	}, rows...)
+}
+
+// withFields returns a copy of row with the given fields added.
+func withFields(row, fields map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(row)+len(fields))
+	for k, v := range row {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,127 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/backup"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Inspect(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+	manifest := br.Manifest{
+		Version: br.ManifestVersion,
+		KV:      br.ManifestFileEntry{FileName: "20210110T000000Z.bolt.gz", Size: 100, Compression: br.GzipCompression},
+		Buckets: []br.ManifestBucketEntry{{
+			OrganizationName: "org",
+			BucketName:       "bucket",
+			RetentionPolicies: []br.ManifestRetentionPolicy{{
+				Name: "autogen",
+				ShardGroups: []br.ManifestShardGroup{{
+					ID:        1,
+					StartTime: start,
+					EndTime:   start.Add(7 * 24 * time.Hour),
+					Shards: []br.ManifestShardEntry{
+						{ID: 1, ManifestFileEntry: br.ManifestFileEntry{FileName: "1.tar.gz", Size: 10, Compression: br.GzipCompression}},
+						{ID: 2, ManifestFileEntry: br.ManifestFileEntry{FileName: "2.tar.gz", Size: 20, Compression: br.GzipCompression}},
+					},
+				}},
+			}},
+		}, {
+			OrganizationName:  "org",
+			BucketName:        "empty",
+			RetentionPolicies: []br.ManifestRetentionPolicy{{Name: "autogen"}},
+		}},
+	}
+
+	writeManifest := func(t *testing.T) string {
+		dir := t.TempDir()
+		manifestBytes, err := json.Marshal(manifest)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "20210110T000000Z.manifest"), manifestBytes, 0600))
+		return dir
+	}
+	newClient := func(t *testing.T, stdout *bytes.Buffer, asJSON bool) backup.Client {
+		ctrl := gomock.NewController(t)
+		stdio := mock.NewMockStdIO(ctrl)
+		stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+		return backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: asJSON}}
+	}
+
+	t.Run("table", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, false)
+		require.NoError(t, client.Inspect(context.Background(), &backup.InspectParams{Path: writeManifest(t)}))
+
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		require.Len(t, lines, 3)
+		require.Equal(t, []string{
+			"20210110T000000Z.manifest", "org", "bucket", "autogen", "1",
+			"2021-01-04T00:00:00Z", "2021-01-11T00:00:00Z", "2", "30", "gzip",
+		}, strings.Fields(lines[1]))
+		require.Equal(t, []string{"20210110T000000Z.manifest", "org", "empty", "autogen", "0", "0"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, true)
+		require.NoError(t, client.Inspect(context.Background(), &backup.InspectParams{Path: writeManifest(t)}))
+
+		var inspected []backup.InspectedManifest
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &inspected))
+		require.Len(t, inspected, 1)
+		require.Equal(t, "20210110T000000Z.manifest", inspected[0].Name)
+		require.Equal(t, manifest.Buckets, inspected[0].Buckets)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("skipping test on Windows: https://github.com/etcd-io/bbolt/issues/252")
+		}
+
+		// Manifests written by the 2.0.x CLI refer to an uncompressed KV snapshot.
+		dir := t.TempDir()
+		in, err := os.Open(testKV)
+		require.NoError(t, err)
+		defer in.Close()
+		gzr, err := gzip.NewReader(in)
+		require.NoError(t, err)
+		kv, err := io.ReadAll(gzr)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "20201215T000000Z.bolt"), kv, 0600))
+		legacy := `{"kv":{"fileName":"20201215T000000Z.bolt","size":1},"files":[]}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "20201215T000000Z.manifest"), []byte(legacy), 0600))
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, true)
+		require.NoError(t, client.Inspect(context.Background(), &backup.InspectParams{Path: dir}))
+
+		var inspected []backup.InspectedManifest
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &inspected))
+		require.Len(t, inspected, 1)
+		require.Equal(t, br.ManifestVersion, inspected[0].Version)
+		require.NotEmpty(t, inspected[0].Buckets)
+	})
+}
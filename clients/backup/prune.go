@@ -0,0 +1,339 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/clients/restore"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+)
+
+type PruneParams struct {
+	// Path to the directory holding backups, either as manifests directly within it or
+	// within its immediate subdirectories.
+	Path string
+
+	// Number of days, weeks, and months for which the latest backup is kept.
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+
+	// If true, report which backups would be deleted without deleting them.
+	DryRun bool
+}
+
+// PruneResult describes whether a backup was kept by Prune, and why.
+type PruneResult struct {
+	Manifest string    `json:"manifest"`
+	Time     time.Time `json:"time"`
+	Keep     bool      `json:"keep"`
+	Reasons  []string  `json:"reasons"`
+}
+
+// prunableBackup is a backup found by Prune.
+type prunableBackup struct {
+	PruneResult
+	// dir is the directory holding the backup's manifest.
+	dir      string
+	manifest br.Manifest
+}
+
+// files returns the absolute paths of the files of the backup.
+func (b prunableBackup) files() []string {
+	entries := []br.ManifestFileEntry{b.manifest.KV}
+	if b.manifest.SQL != nil {
+		entries = append(entries, *b.manifest.SQL)
+	}
+	for _, bkt := range b.manifest.Buckets {
+		for _, sh := range bucketShards(bkt) {
+			entries = append(entries, sh.ManifestFileEntry)
+		}
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, filepath.Clean(e.Path(b.dir)))
+	}
+	return files
+}
+
+// Prune deletes the backups in a directory which aren't kept by any of the retention rules in
+// params. Each rule keeps the latest backup in each of the given number of most recent days,
+// weeks, or months which have backups, based on the UTC timestamps in the names of backup manifests.
+//
+// Backups holding files that kept incremental backups refer to, and the earlier backups that kept
+// backups limited to a time range are merged with when restoring, are always kept, so every kept
+// backup can still be restored.
+func (c Client) Prune(ctx context.Context, params *PruneParams) error {
+	if params.KeepDaily < 0 || params.KeepWeekly < 0 || params.KeepMonthly < 0 {
+		return errors.New("the number of backups to keep can't be negative")
+	}
+	if params.KeepDaily == 0 && params.KeepWeekly == 0 && params.KeepMonthly == 0 {
+		return errors.New("at least one of --keep-daily, --keep-weekly, or --keep-monthly must be set")
+	}
+
+	backups, err := findPrunableBackups(ctx, params.Path)
+	if err != nil {
+		return err
+	}
+	applyRetention(backups, params)
+	if err := keepIncrementalChains(backups); err != nil {
+		return err
+	}
+
+	if !params.DryRun {
+		if err := deletePrunedBackups(backups); err != nil {
+			return err
+		}
+	}
+
+	results := make([]PruneResult, len(backups))
+	for i, b := range backups {
+		results[i] = b.PruneResult
+	}
+	if c.PrintAsJSON {
+		return c.PrintJSON(results)
+	}
+	rows := make([]map[string]interface{}, len(results))
+	for i, r := range results {
+		action := "delete"
+		if r.Keep {
+			action = "keep"
+		} else if !params.DryRun {
+			action = "deleted"
+		}
+		rows[i] = map[string]interface{}{
+			"Manifest": r.Manifest,
+			"Time":     r.Time.Format(time.RFC3339),
+			"Action":   action,
+			"Reasons":  strings.Join(r.Reasons, ", "),
+		}
+	}
+	return c.PrintTable([]string{"Manifest", "Time", "Action", "Reasons"}, rows...)
+}
+
+// findPrunableBackups finds the backup manifests directly within root and within its immediate
+// subdirectories, sorted from newest to oldest.
+func findPrunableBackups(ctx context.Context, root string) ([]*prunableBackup, error) {
+	dirs := []string{root}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, filepath.Join(root, e.Name()))
+		}
+	}
+
+	var backups []*prunableBackup
+	for _, dir := range dirs {
+		storage := br.LocalStorage{Dir: dir}
+		names, err := storage.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !strings.HasSuffix(name, "."+br.ManifestExtension) {
+				continue
+			}
+			rel, err := filepath.Rel(root, filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			takenAt, err := time.Parse(backupFilenamePattern, strings.TrimSuffix(name, "."+br.ManifestExtension))
+			if err != nil {
+				log.Printf("WARN: Can't tell when the backup with manifest %q was taken, ignoring it", rel)
+				continue
+			}
+			manifest, err := restore.ReadManifest(ctx, storage, name)
+			if err != nil {
+				return nil, err
+			}
+			backups = append(backups, &prunableBackup{
+				PruneResult: PruneResult{Manifest: filepath.ToSlash(rel), Time: takenAt, Reasons: []string{}},
+				dir:         dir,
+				manifest:    manifest,
+			})
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// applyRetention marks the backups kept by the retention rules in params, given backups sorted
+// from newest to oldest.
+func applyRetention(backups []*prunableBackup, params *PruneParams) {
+	rules := []struct {
+		name   string
+		keep   int
+		period func(time.Time) string
+	}{
+		{name: "daily", keep: params.KeepDaily, period: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{name: "weekly", keep: params.KeepWeekly, period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{name: "monthly", keep: params.KeepMonthly, period: func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, rule := range rules {
+		seen := map[string]bool{}
+		for _, b := range backups {
+			if len(seen) == rule.keep {
+				break
+			}
+			period := rule.period(b.Time.UTC())
+			if seen[period] {
+				continue
+			}
+			seen[period] = true
+			b.Keep = true
+			b.Reasons = append(b.Reasons, rule.name)
+		}
+	}
+}
+
+// keepIncrementalChains marks the backups that kept backups depend on as kept, and checks that
+// every file of a kept backup exists. Backups depend on the backups holding the files they refer
+// to and, if they're limited to a time range, on the earlier backups they're merged with when restoring.
+func keepIncrementalChains(backups []*prunableBackup) error {
+	owners := map[string]*prunableBackup{}
+	for _, b := range backups {
+		for _, f := range b.files() {
+			if filepath.Dir(f) == filepath.Clean(b.dir) {
+				owners[f] = b
+			}
+		}
+	}
+
+	// Follow references from kept backups until no more backups need to be kept.
+	todo := make([]*prunableBackup, 0, len(backups))
+	for _, b := range backups {
+		if b.Keep {
+			todo = append(todo, b)
+		}
+	}
+	for len(todo) > 0 {
+		b := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		need := func(dep *prunableBackup) {
+			reason := "needed by " + b.Manifest
+			if !dep.Keep {
+				dep.Keep = true
+				todo = append(todo, dep)
+			}
+			if !contains(dep.Reasons, reason) {
+				dep.Reasons = append(dep.Reasons, reason)
+			}
+		}
+		for _, f := range b.files() {
+			if _, err := os.Stat(f); err != nil {
+				return fmt.Errorf("backup %q refers to %q, which can't be read, so it can't be kept intact: %w", b.Manifest, f, err)
+			}
+			if owner, ok := owners[f]; ok && owner != b {
+				need(owner)
+			}
+		}
+		if b.manifest.IsPartialRange() {
+			for _, dep := range mergedBackups(backups, b) {
+				need(dep)
+			}
+		}
+	}
+	return nil
+}
+
+// mergedBackups returns the earlier backups which a backup limited to a time range is merged with
+// when restoring: for each of its buckets, the latest earlier backup of the bucket in the same directory.
+// backups must be sorted from newest to oldest.
+func mergedBackups(backups []*prunableBackup, partial *prunableBackup) []*prunableBackup {
+	var merged []*prunableBackup
+	for _, bkt := range partial.manifest.Buckets {
+		for _, earlier := range backups {
+			if earlier.dir != partial.dir || !earlier.Time.Before(partial.Time) {
+				continue
+			}
+			prev, ok := findBucket(earlier.manifest, bkt.OrganizationName, bkt.BucketName)
+			if !ok {
+				continue
+			}
+			// Restores only merge backups of the same bucket, not of a re-created one with the same name.
+			if prev.BucketID == bkt.BucketID {
+				merged = append(merged, earlier)
+			}
+			break
+		}
+	}
+	return merged
+}
+
+// findBucket returns the entry of a bucket in a manifest.
+func findBucket(m br.Manifest, orgName, bucketName string) (br.ManifestBucketEntry, bool) {
+	for _, bkt := range m.Buckets {
+		if bkt.OrganizationName == orgName && bkt.BucketName == bucketName {
+			return bkt, true
+		}
+	}
+	return br.ManifestBucketEntry{}, false
+}
+
+// deletePrunedBackups deletes the manifests and files of backups which aren't kept, along with
+// any subdirectories of the root left empty. Files kept backups refer to are never deleted.
+func deletePrunedBackups(backups []*prunableBackup) error {
+	needed := map[string]bool{}
+	for _, b := range backups {
+		if b.Keep {
+			for _, f := range b.files() {
+				needed[f] = true
+			}
+		}
+	}
+	for _, b := range backups {
+		if b.Keep {
+			continue
+		}
+		for _, f := range b.files() {
+			if needed[f] || filepath.Dir(f) != filepath.Clean(b.dir) {
+				continue
+			}
+			if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete file of backup %q: %w", b.Manifest, err)
+			}
+		}
+		manifestPath := filepath.Join(b.dir, filepath.Base(filepath.FromSlash(b.Manifest)))
+		if err := os.Remove(manifestPath); err != nil {
+			return fmt.Errorf("failed to delete manifest of backup %q: %w", b.Manifest, err)
+		}
+		log.Printf("INFO: Deleted backup %q", b.Manifest)
+	}
+
+	// Clean up subdirectories which only held pruned backups.
+	for _, b := range backups {
+		if b.Keep || !strings.Contains(b.Manifest, "/") {
+			continue
+		}
+		if entries, err := os.ReadDir(b.dir); err == nil && len(entries) == 0 {
+			if err := os.Remove(b.dir); err != nil {
+				return fmt.Errorf("failed to delete empty backup directory %q: %w", b.dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/backup"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Prune(t *testing.T) {
+	t.Parallel()
+
+	// writeBackup writes a backup with a KV snapshot and a single shard to a directory of root,
+	// optionally referring to the shard captured by an earlier backup.
+	writeBackup := func(t *testing.T, root, dir, baseName, shardDir string) {
+		path := filepath.Join(root, dir)
+		require.NoError(t, os.MkdirAll(path, 0777))
+		shard := br.ManifestFileEntry{FileName: baseName + ".1.tar.gz", Dir: shardDir}
+		if shardDir == "" {
+			require.NoError(t, os.WriteFile(filepath.Join(path, shard.FileName), []byte("tsm"), 0600))
+		} else {
+			shard.FileName = filepath.Base(shardDir) + ".1.tar.gz"
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(path, baseName+".bolt.gz"), []byte("kv"), 0600))
+		manifest := br.Manifest{
+			Version: br.ManifestVersion,
+			KV:      br.ManifestFileEntry{FileName: baseName + ".bolt.gz"},
+			Buckets: []br.ManifestBucketEntry{{
+				BucketName: "bucket",
+				RetentionPolicies: []br.ManifestRetentionPolicy{{
+					ShardGroups: []br.ManifestShardGroup{{ID: 1, Shards: []br.ManifestShardEntry{{ID: 1, ManifestFileEntry: shard}}}},
+				}},
+			}},
+		}
+		manifestBytes, err := json.Marshal(manifest)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(path, baseName+".manifest"), manifestBytes, 0600))
+	}
+
+	testCases := []struct {
+		name     string
+		params   backup.PruneParams
+		expected map[string][]string
+	}{
+		{
+			name:   "daily",
+			params: backup.PruneParams{KeepDaily: 2},
+			expected: map[string][]string{
+				"20210110T000000Z/20210110T000000Z.manifest": {"daily"},
+				"20210109T120000Z/20210109T120000Z.manifest": {"daily"},
+				"20210101T000000Z/20210101T000000Z.manifest": {"needed by 20210110T000000Z/20210110T000000Z.manifest"},
+			},
+		},
+		{
+			name:   "monthly",
+			params: backup.PruneParams{KeepDaily: 1, KeepMonthly: 2},
+			expected: map[string][]string{
+				"20210110T000000Z/20210110T000000Z.manifest": {"daily", "monthly"},
+				"20201215T000000Z.manifest":                  {"monthly"},
+				"20210101T000000Z/20210101T000000Z.manifest": {"needed by 20210110T000000Z/20210110T000000Z.manifest"},
+			},
+		},
+		{
+			name:   "dry run",
+			params: backup.PruneParams{KeepWeekly: 1, DryRun: true},
+			expected: map[string][]string{
+				"20210110T000000Z/20210110T000000Z.manifest": {"weekly"},
+				"20210101T000000Z/20210101T000000Z.manifest": {"needed by 20210110T000000Z/20210110T000000Z.manifest"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			writeBackup(t, root, ".", "20201215T000000Z", "")
+			writeBackup(t, root, "20210101T000000Z", "20210101T000000Z", "")
+			writeBackup(t, root, "20210105T000000Z", "20210105T000000Z", "")
+			writeBackup(t, root, "20210109T000000Z", "20210109T000000Z", "")
+			writeBackup(t, root, "20210109T120000Z", "20210109T120000Z", "")
+			writeBackup(t, root, "20210110T000000Z", "20210110T000000Z", "../20210101T000000Z")
+
+			ctrl := gomock.NewController(t)
+			stdout := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+			client := backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: true}}
+
+			params := tc.params
+			params.Path = root
+			require.NoError(t, client.Prune(context.Background(), &params))
+
+			var results []backup.PruneResult
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+			require.Len(t, results, 6)
+			for i, r := range results {
+				if i > 0 {
+					require.True(t, r.Time.Before(results[i-1].Time), "results should be sorted from newest to oldest")
+				}
+				reasons, keep := tc.expected[r.Manifest]
+				require.Equal(t, keep, r.Keep, r.Manifest)
+				if keep {
+					require.Equal(t, reasons, r.Reasons, r.Manifest)
+				}
+
+				_, err := os.Stat(filepath.Join(root, r.Manifest))
+				if keep || params.DryRun {
+					require.NoError(t, err, r.Manifest)
+				} else {
+					require.True(t, os.IsNotExist(err), r.Manifest)
+					// Pruned backups in their own directories are removed entirely.
+					if dir := filepath.Dir(r.Manifest); dir != "." {
+						_, err := os.Stat(filepath.Join(root, dir))
+						require.True(t, os.IsNotExist(err), dir)
+					}
+				}
+			}
+
+			// The incremental backup can still be restored.
+			_, err := os.Stat(filepath.Join(root, "20210101T000000Z", "20210101T000000Z.1.tar.gz"))
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("no rules", func(t *testing.T) {
+		t.Parallel()
+		client := backup.Client{}
+		require.Error(t, client.Prune(context.Background(), &backup.PruneParams{Path: t.TempDir()}))
+	})
+
+	t.Run("partial range", func(t *testing.T) {
+		t.Parallel()
+
+		root := t.TempDir()
+		// writePartial writes a backup of buckets with the given IDs and names, which is limited
+		// to a time range if start is set.
+		writePartial := func(baseName string, start *time.Time, buckets map[string]string) {
+			manifest := br.Manifest{
+				Version: br.ManifestVersion,
+				KV:      br.ManifestFileEntry{FileName: baseName + ".bolt.gz"},
+				Start:   start,
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(root, manifest.KV.FileName), []byte("kv"), 0600))
+			for id, name := range buckets {
+				manifest.Buckets = append(manifest.Buckets, br.ManifestBucketEntry{OrganizationName: "org", BucketID: id, BucketName: name})
+			}
+			manifestBytes, err := json.Marshal(manifest)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(root, baseName+".manifest"), manifestBytes, 0600))
+		}
+		start := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+		writePartial("20201201T000000Z", nil, map[string]string{"1": "bucket", "2": "other"})
+		writePartial("20210101T000000Z", nil, map[string]string{"1": "bucket"})
+		writePartial("20210105T000000Z", nil, map[string]string{"3": "other"})
+		writePartial("20210110T000000Z", &start, map[string]string{"1": "bucket", "2": "other"})
+
+		ctrl := gomock.NewController(t)
+		stdout := bytes.Buffer{}
+		stdio := mock.NewMockStdIO(ctrl)
+		stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+		client := backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: true}}
+		require.NoError(t, client.Prune(context.Background(), &backup.PruneParams{Path: root, KeepDaily: 1}))
+
+		var results []backup.PruneResult
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+		reasons := map[string][]string{}
+		for _, r := range results {
+			if r.Keep {
+				reasons[r.Manifest] = r.Reasons
+			}
+		}
+		// Restoring the latest backup merges its shard groups with those of the latest earlier
+		// backup of "bucket". The latest earlier backup of "other" is of a re-created bucket,
+		// which isn't merged.
+		require.Equal(t, map[string][]string{
+			"20210110T000000Z.manifest": {"daily"},
+			"20210101T000000Z.manifest": {"needed by 20210110T000000Z.manifest"},
+		}, reasons)
+	})
+}
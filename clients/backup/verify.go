@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients/restore"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+)
+
+type VerifyParams struct {
+	// Path to the directory of the backup to verify, or an s3:// URL of a backup in
+	// S3-compatible object storage.
+	Path string
+
+	// S3 configures the connection to object storage, for S3 paths.
+	S3 br.S3Config
+
+	// Encryption configures the passphrase or key used to decrypt encrypted backup files.
+	// If not set, only the size and checksum of encrypted files are checked.
+	Encryption br.EncryptionParams
+}
+
+// VerifyReport describes the outcome of verifying a backup.
+type VerifyReport struct {
+	Manifests int             `json:"manifests"`
+	Files     int             `json:"files"`
+	Problems  []VerifyProblem `json:"problems"`
+}
+
+// VerifyProblem is a problem found with a file in a backup.
+type VerifyProblem struct {
+	Manifest string `json:"manifest"`
+	File     string `json:"file"`
+	Problem  string `json:"problem"`
+}
+
+// Verify checks that the files of every backup in a location are intact: that they have the
+// size and checksum recorded in their manifest, that compressed files are valid gzip streams,
+// that the KV snapshot can be parsed, and that it knows about every shard in the manifest.
+func (c Client) Verify(ctx context.Context, params *VerifyParams) error {
+	secret, err := br.NewEncryptionSecret(params.Encryption)
+	if err != nil {
+		return err
+	}
+	storage, err := br.NewStorage(params.Path, params.S3)
+	if err != nil {
+		return err
+	}
+	manifests, err := restore.FindManifests(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	report := VerifyReport{Manifests: len(manifests), Problems: []VerifyProblem{}}
+	// Incremental backups share files, which only need to be checked once.
+	checked := map[string]bool{}
+	for _, manifestName := range manifests {
+		addProblem := func(file, format string, args ...interface{}) {
+			report.Problems = append(report.Problems, VerifyProblem{
+				Manifest: manifestName,
+				File:     file,
+				Problem:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		manifest, err := restore.ReadManifest(ctx, storage, manifestName)
+		if err != nil {
+			addProblem(manifestName, "%v", err)
+			continue
+		}
+
+		files := []br.ManifestFileEntry{manifest.KV}
+		if manifest.SQL != nil {
+			files = append(files, *manifest.SQL)
+		}
+		for _, b := range manifest.Buckets {
+			for _, sh := range bucketShards(b) {
+				files = append(files, sh.ManifestFileEntry)
+			}
+		}
+		kvIntact := true
+		encrypted := false
+		for i, file := range files {
+			encrypted = encrypted || file.Encryption != nil
+			if checked[file.Name()] {
+				continue
+			}
+			checked[file.Name()] = true
+			report.Files++
+			if err := verifyFile(ctx, storage, file, secret); err != nil {
+				addProblem(file.FileName, "%v", err)
+				if i == 0 {
+					kvIntact = false
+				}
+			}
+		}
+		if encrypted && secret == nil {
+			log.Printf("WARN: Files of backup %q are encrypted, only their sizes and checksums were checked", manifestName)
+			if manifest.KV.Encryption != nil {
+				continue
+			}
+		}
+		if !kvIntact {
+			continue
+		}
+
+		for _, problem := range checkShardsInKV(ctx, storage, manifest, secret) {
+			addProblem(problem.File, "%s", problem.Problem)
+		}
+	}
+
+	if c.PrintAsJSON {
+		if err := c.PrintJSON(report); err != nil {
+			return err
+		}
+	} else if len(report.Problems) == 0 {
+		if _, err := c.StdIO.Write([]byte(fmt.Sprintf("Verified %d files in %d manifests, no problems found\n", report.Files, report.Manifests))); err != nil {
+			return err
+		}
+	} else {
+		rows := make([]map[string]interface{}, len(report.Problems))
+		for i, p := range report.Problems {
+			rows[i] = map[string]interface{}{
+				"Manifest": p.Manifest,
+				"File":     p.File,
+				"Problem":  p.Problem,
+			}
+		}
+		if err := c.PrintTable([]string{"Manifest", "File", "Problem"}, rows...); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("found %d problems in backup at %q", len(report.Problems), storage.Location(""))
+	}
+	return nil
+}
+
+// verifyFile checks that a file in storage matches the size, checksum, and compression
+// recorded for it in a manifest. Encrypted files are decrypted if a secret is given, and
+// otherwise only checked for size and checksum.
+func verifyFile(ctx context.Context, storage br.Storage, file br.ManifestFileEntry, secret *br.EncryptionSecret) error {
+	f, err := storage.Open(ctx, file.Name())
+	if err != nil {
+		return fmt.Errorf("can't be read: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	var size byteCounter
+	r := io.TeeReader(f, io.MultiWriter(h, &size))
+	contentsErr := verifyContents(r, file, secret)
+	// Read whatever wasn't consumed while decrypting and decompressing, so the whole file is measured
+	// and checksummed.
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("can't be read: %w", err)
+	}
+	// A file of the wrong size is also likely to be corrupt, but its size is the better explanation.
+	if int64(size) != file.Size {
+		return fmt.Errorf("size is %d bytes, but the manifest records %d", size, file.Size)
+	}
+	if contentsErr != nil {
+		return contentsErr
+	}
+	if file.SHA256 != "" {
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != file.SHA256 {
+			return fmt.Errorf("SHA-256 checksum is %s, but the manifest records %s", sum, file.SHA256)
+		}
+	}
+	return nil
+}
+
+// verifyContents checks that the contents of a file can be decrypted and decompressed.
+func verifyContents(r io.Reader, file br.ManifestFileEntry, secret *br.EncryptionSecret) error {
+	contents := r
+	switch {
+	case file.Encryption != nil && secret == nil:
+		// The contents can't be checked without decrypting them.
+		return nil
+	case file.Encryption != nil:
+		var err error
+		if contents, err = secret.Decrypt(r, *file.Encryption); err != nil {
+			return fmt.Errorf("can't be decrypted: %w", err)
+		}
+		if file.Compression == br.NoCompression {
+			if _, err := io.Copy(io.Discard, contents); err != nil {
+				return fmt.Errorf("can't be decrypted: %w", err)
+			}
+		}
+	}
+	if file.Compression == br.GzipCompression {
+		gzr, err := gzip.NewReader(contents)
+		if err != nil {
+			return fmt.Errorf("isn't a valid gzip stream: %w", err)
+		}
+		if _, err := io.Copy(io.Discard, gzr); err != nil {
+			return fmt.Errorf("gzip stream is corrupt: %w", err)
+		}
+	}
+	return nil
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// checkShardsInKV parses the KV snapshot of a backup, and checks that every bucket and shard
+// in the manifest is known to it.
+func checkShardsInKV(ctx context.Context, storage br.Storage, manifest br.Manifest, secret *br.EncryptionSecret) []VerifyProblem {
+	kvName := manifest.KV.FileName
+	metadata, err := readKVMetadata(ctx, storage, manifest.KV, secret)
+	if err != nil {
+		return []VerifyProblem{{File: kvName, Problem: fmt.Sprintf("KV snapshot can't be parsed: %v", err)}}
+	}
+
+	shardsByBucket := make(map[string]map[int64]bool, len(metadata))
+	for _, b := range metadata {
+		shards := map[int64]bool{}
+		for _, rp := range b.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				for _, sh := range sg.Shards {
+					shards[sh.Id] = true
+				}
+			}
+		}
+		shardsByBucket[b.BucketID] = shards
+	}
+
+	var problems []VerifyProblem
+	for _, b := range manifest.Buckets {
+		// System buckets aren't included in the metadata extracted from KV.
+		if strings.HasPrefix(b.BucketName, "_") {
+			continue
+		}
+		shards, ok := shardsByBucket[b.BucketID]
+		if !ok {
+			problems = append(problems, VerifyProblem{
+				File:    kvName,
+				Problem: fmt.Sprintf("bucket %q (%s) in the manifest isn't in the KV snapshot", b.BucketName, b.BucketID),
+			})
+			continue
+		}
+		for _, sh := range bucketShards(b) {
+			if !shards[sh.ID] {
+				problems = append(problems, VerifyProblem{
+					File:    sh.FileName,
+					Problem: fmt.Sprintf("shard %d of bucket %q isn't in the KV snapshot", sh.ID, b.BucketName),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// readKVMetadata extracts bucket metadata from a KV snapshot in storage, decrypting and
+// decompressing it to a temporary file first if needed.
+func readKVMetadata(ctx context.Context, storage br.Storage, file br.ManifestFileEntry, secret *br.EncryptionSecret) ([]api.BucketMetadataManifest, error) {
+	if local, ok := storage.(br.LocalStorage); ok && file.Compression == br.NoCompression && file.Encryption == nil {
+		return br.ExtractBucketMetadata(local.Path(file.Name()))
+	}
+
+	in, err := br.OpenFile(ctx, storage, file, secret)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	var r io.Reader = in
+	if file.Compression == br.GzipCompression {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+	tmp, err := os.CreateTemp("", "influx-verify-*.bolt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KV snapshot: %w", err)
+	}
+	return br.ExtractBucketMetadata(tmp.Name())
+}
+
+// bucketShards returns the shards of all shard groups in a bucket.
+func bucketShards(b br.ManifestBucketEntry) []br.ManifestShardEntry {
+	var shards []br.ManifestShardEntry
+	for _, rp := range b.RetentionPolicies {
+		for _, sg := range rp.ShardGroups {
+			shards = append(shards, sg.Shards...)
+		}
+	}
+	return shards
+}
@@ -0,0 +1,346 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/backup"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/influxdata/influx-cli/v2/internal/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// testKV holds the metadata of buckets without any shards.
+const testKV = "../../internal/backup_restore/testdata/test.bolt.gz"
+
+func TestClient_Verify(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test on Windows: https://github.com/etcd-io/bbolt/issues/252")
+	}
+
+	kv, err := os.ReadFile(testKV)
+	require.NoError(t, err)
+	var gzipped bytes.Buffer
+	gzw := gzip.NewWriter(&gzipped)
+	_, err = gzw.Write([]byte("tsm"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	fileEntry := func(name string, contents []byte) br.ManifestFileEntry {
+		sum := sha256.Sum256(contents)
+		return br.ManifestFileEntry{
+			FileName:    name,
+			Size:        int64(len(contents)),
+			Compression: br.GzipCompression,
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+	}
+	uncompressed := func(file br.ManifestFileEntry) br.ManifestFileEntry {
+		file.Compression = br.NoCompression
+		return file
+	}
+	shard := func(id int64, file br.ManifestFileEntry) br.ManifestShardEntry {
+		return br.ManifestShardEntry{ID: id, ManifestFileEntry: file}
+	}
+	bucket := func(shards ...br.ManifestShardEntry) br.ManifestBucketEntry {
+		return br.ManifestBucketEntry{
+			OrganizationName: "test",
+			BucketID:         "d66c5360b5aa91b4",
+			BucketName:       "test",
+			RetentionPolicies: []br.ManifestRetentionPolicy{{
+				Name:        "autogen",
+				ShardGroups: []br.ManifestShardGroup{{ID: 1, Shards: shards}},
+			}},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		files            map[string][]byte
+		buckets          []br.ManifestBucketEntry
+		expectedProblems []backup.VerifyProblem
+	}{
+		{
+			name:    "intact",
+			buckets: []br.ManifestBucketEntry{bucket()},
+		},
+		{
+			name: "corrupt",
+			files: map[string][]byte{
+				"20210101T000000Z.1.tar.gz": gzipped.Bytes(),
+				"20210101T000000Z.2.tar.gz": append([]byte("x"), gzipped.Bytes()[1:]...),
+				"20210101T000000Z.3.tar.gz": gzipped.Bytes()[:10],
+				"20210101T000000Z.4.tar":    []byte("TSM"),
+			},
+			buckets: []br.ManifestBucketEntry{bucket(
+				shard(1, fileEntry("20210101T000000Z.1.tar.gz", gzipped.Bytes())),
+				shard(2, fileEntry("20210101T000000Z.2.tar.gz", gzipped.Bytes())),
+				shard(3, fileEntry("20210101T000000Z.3.tar.gz", gzipped.Bytes()[:10])),
+				shard(4, uncompressed(fileEntry("20210101T000000Z.4.tar", []byte("tsm")))),
+			)},
+			expectedProblems: []backup.VerifyProblem{
+				{File: "20210101T000000Z.2.tar.gz", Problem: "isn't a valid gzip stream: gzip: invalid header"},
+				{File: "20210101T000000Z.3.tar.gz", Problem: "gzip stream is corrupt: unexpected EOF"},
+				{
+					File:    "20210101T000000Z.4.tar",
+					Problem: "SHA-256 checksum is " + sha256Hex("TSM") + ", but the manifest records " + sha256Hex("tsm"),
+				},
+				{File: "20210101T000000Z.1.tar.gz", Problem: `shard 1 of bucket "test" isn't in the KV snapshot`},
+				{File: "20210101T000000Z.2.tar.gz", Problem: `shard 2 of bucket "test" isn't in the KV snapshot`},
+				{File: "20210101T000000Z.3.tar.gz", Problem: `shard 3 of bucket "test" isn't in the KV snapshot`},
+				{File: "20210101T000000Z.4.tar", Problem: `shard 4 of bucket "test" isn't in the KV snapshot`},
+			},
+		},
+		{
+			name: "corrupt KV",
+			files: map[string][]byte{
+				"20210101T000000Z.bolt.gz": append(kv[:len(kv)-1:len(kv)-1], kv[len(kv)-1]^0xff),
+			},
+			buckets: []br.ManifestBucketEntry{bucket()},
+			expectedProblems: []backup.VerifyProblem{{
+				File:    "20210101T000000Z.bolt.gz",
+				Problem: "gzip stream is corrupt: gzip: invalid checksum",
+			}},
+		},
+		{
+			name: "unknown bucket",
+			buckets: []br.ManifestBucketEntry{{
+				BucketID:   "0000000000000001",
+				BucketName: "missing",
+			}},
+			expectedProblems: []backup.VerifyProblem{{
+				File:    "20210101T000000Z.bolt.gz",
+				Problem: `bucket "missing" (0000000000000001) in the manifest isn't in the KV snapshot`,
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			files := map[string][]byte{"20210101T000000Z.bolt.gz": kv}
+			for name, contents := range tc.files {
+				files[name] = contents
+			}
+			for name, contents := range files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), contents, 0600))
+			}
+			manifest := br.Manifest{
+				Version: br.ManifestVersion,
+				KV:      fileEntry("20210101T000000Z.bolt.gz", kv),
+				Buckets: tc.buckets,
+			}
+			manifestBytes, err := json.Marshal(manifest)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "20210101T000000Z.manifest"), manifestBytes, 0600))
+
+			ctrl := gomock.NewController(t)
+			stdout := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+			client := backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: true}}
+
+			err = client.Verify(context.Background(), &backup.VerifyParams{Path: dir})
+			var report backup.VerifyReport
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+			require.Equal(t, 1, report.Manifests)
+			require.Equal(t, len(files), report.Files)
+			if len(tc.expectedProblems) == 0 {
+				require.NoError(t, err)
+				require.Empty(t, report.Problems)
+				return
+			}
+			require.Error(t, err)
+			for i := range tc.expectedProblems {
+				tc.expectedProblems[i].Manifest = "20210101T000000Z.manifest"
+			}
+			require.Equal(t, tc.expectedProblems, report.Problems)
+		})
+	}
+}
+
+func TestClient_Verify_S3(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test on Windows: https://github.com/etcd-io/bbolt/issues/252")
+	}
+
+	kv, err := os.ReadFile(testKV)
+	require.NoError(t, err)
+	var gzipped bytes.Buffer
+	gzw := gzip.NewWriter(&gzipped)
+	_, err = gzw.Write([]byte("tsm"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	truncated := gzipped.Bytes()[:10]
+
+	manifestBytes, err := json.Marshal(br.Manifest{
+		Version: br.ManifestVersion,
+		KV: br.ManifestFileEntry{
+			FileName:    "20210101T000000Z.bolt.gz",
+			Size:        int64(len(kv)),
+			Compression: br.GzipCompression,
+			SHA256:      sha256Hex(string(kv)),
+		},
+		Buckets: []br.ManifestBucketEntry{{
+			OrganizationName: "test",
+			BucketID:         "d66c5360b5aa91b4",
+			BucketName:       "test",
+			RetentionPolicies: []br.ManifestRetentionPolicy{{
+				Name: "autogen",
+				ShardGroups: []br.ManifestShardGroup{{ID: 1, Shards: []br.ManifestShardEntry{{
+					ID: 1,
+					ManifestFileEntry: br.ManifestFileEntry{
+						FileName:    "20210101T000000Z.1.tar.gz",
+						Size:        int64(gzipped.Len()),
+						Compression: br.GzipCompression,
+						SHA256:      sha256Hex(gzipped.String()),
+					},
+				}}}},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	fake := testutils.NewFakeS3(t)
+	fake.PutObject("backups", "influx/20210101T000000Z.manifest", manifestBytes)
+	fake.PutObject("backups", "influx/20210101T000000Z.bolt.gz", kv)
+	fake.PutObject("backups", "influx/20210101T000000Z.1.tar.gz", truncated)
+
+	ctrl := gomock.NewController(t)
+	stdout := bytes.Buffer{}
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+	client := backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: true}}
+
+	err = client.Verify(context.Background(), &backup.VerifyParams{
+		Path: "s3://backups/influx",
+		S3:   br.S3Config{Endpoint: fake.URL},
+	})
+	require.EqualError(t, err, `found 2 problems in backup at "s3://backups/influx"`)
+	var report backup.VerifyReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	require.Equal(t, backup.VerifyReport{
+		Manifests: 1,
+		Files:     2,
+		Problems: []backup.VerifyProblem{
+			{
+				Manifest: "20210101T000000Z.manifest",
+				File:     "20210101T000000Z.1.tar.gz",
+				Problem:  fmt.Sprintf("size is 10 bytes, but the manifest records %d", gzipped.Len()),
+			},
+			{
+				Manifest: "20210101T000000Z.manifest",
+				File:     "20210101T000000Z.1.tar.gz",
+				Problem:  `shard 1 of bucket "test" isn't in the KV snapshot`,
+			},
+		},
+	}, report)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestClient_Verify_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test on Windows: https://github.com/etcd-io/bbolt/issues/252")
+	}
+
+	keyFile := func(b byte) string {
+		path := filepath.Join(t.TempDir(), "backup.key")
+		require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{b}, 32), 0600))
+		return path
+	}
+	params := br.EncryptionParams{KeyFile: keyFile(1)}
+	secret, err := br.NewEncryptionSecret(params)
+	require.NoError(t, err)
+	encryption, err := secret.NewEncryption()
+	require.NoError(t, err)
+
+	kv, err := os.ReadFile(testKV)
+	require.NoError(t, err)
+	var encrypted bytes.Buffer
+	w, err := secret.Encrypt(&encrypted, encryption)
+	require.NoError(t, err)
+	_, err = w.Write(kv)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20210101T000000Z.bolt.gz"), encrypted.Bytes(), 0600))
+	manifestBytes, err := json.Marshal(br.Manifest{
+		Version: br.ManifestVersion,
+		KV: br.ManifestFileEntry{
+			FileName:    "20210101T000000Z.bolt.gz",
+			Size:        int64(encrypted.Len()),
+			Compression: br.GzipCompression,
+			SHA256:      sha256Hex(encrypted.String()),
+			Encryption:  &encryption,
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20210101T000000Z.manifest"), manifestBytes, 0600))
+
+	testCases := []struct {
+		name            string
+		params          br.EncryptionParams
+		expectedProblem string
+	}{
+		{name: "with key", params: params},
+		{name: "without key"},
+		{
+			name:            "wrong key",
+			params:          br.EncryptionParams{KeyFile: keyFile(2)},
+			expectedProblem: "can't be decrypted: " + br.ErrWrongEncryptionKey.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			stdout := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+			client := backup.Client{CLI: clients.CLI{StdIO: stdio, PrintAsJSON: true}}
+
+			err := client.Verify(context.Background(), &backup.VerifyParams{Path: dir, Encryption: tc.params})
+			var report backup.VerifyReport
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+			if tc.expectedProblem == "" {
+				require.NoError(t, err)
+				require.Empty(t, report.Problems)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, []backup.VerifyProblem{{
+				Manifest: "20210101T000000Z.manifest",
+				File:     "20210101T000000Z.bolt.gz",
+				Problem:  tc.expectedProblem,
+			}}, report.Problems)
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+// DefaultCardinalityWindows is the number of windows reported when no start time is given.
+const DefaultCardinalityWindows = 7
+
+// DefaultMaxCardinalityWindows is the default limit on the number of windows reported. Each window
+// is counted by a separate query.
+const DefaultMaxCardinalityWindows = 1000
+
+// CardinalityClient reports the series cardinality of a bucket.
+type CardinalityClient struct {
+	clients.CLI
+	api.QueryApi
+	// BucketsApi looks up the names of buckets given by ID, which the schema package needs.
+	api.BucketsApi
+}
+
+type CardinalityParams struct {
+	clients.OrgBucketParams
+	Start string
+	Stop  string
+	// Window is the length of the time windows cardinality growth is reported for.
+	Window time.Duration
+	// TopTagKeys is the number of tag keys with the most distinct values to report.
+	TopTagKeys int
+	// MaxWindows is the largest number of windows the time range may be split into.
+	MaxWindows int
+}
+
+type CardinalityReport struct {
+	Start        time.Time                `json:"start"`
+	Stop         time.Time                `json:"stop"`
+	Series       int64                    `json:"series"`
+	Measurements []MeasurementCardinality `json:"measurements"`
+	TagKeys      []TagKeyCardinality      `json:"tagKeys"`
+	Windows      []WindowCardinality      `json:"windows"`
+}
+
+type MeasurementCardinality struct {
+	Measurement string `json:"measurement"`
+	Series      int64  `json:"series"`
+}
+
+type TagKeyCardinality struct {
+	TagKey string `json:"tagKey"`
+	Values int64  `json:"values"`
+}
+
+type WindowCardinality struct {
+	Start  time.Time `json:"start"`
+	Stop   time.Time `json:"stop"`
+	Series int64     `json:"series"`
+}
+
+// Cardinality reports the series count of a bucket and of each of its measurements, the
+// tag keys with the most distinct values, and the series count of each window in the
+// time range, to show how cardinality grows.
+func (c CardinalityClient) Cardinality(ctx context.Context, params *CardinalityParams) error {
+	if params.OrgID == "" && params.OrgName == "" && c.ActiveConfig.Org == "" {
+		return clients.ErrMustSpecifyOrg
+	}
+	if params.BucketID == "" && params.BucketName == "" {
+		return clients.ErrMustSpecifyBucket
+	}
+	if params.Window <= 0 {
+		return errors.New("window must be positive")
+	}
+	if params.TopTagKeys < 0 {
+		return errors.New("the number of top tag keys must not be negative")
+	}
+	if params.MaxWindows <= 0 {
+		return errors.New("the maximum number of windows must be positive")
+	}
+	stop := time.Now().UTC()
+	var err error
+	if params.Stop != "" {
+		if stop, err = time.Parse(time.RFC3339Nano, params.Stop); err != nil {
+			return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano: %w", params.Stop, err)
+		}
+	}
+	start := stop.Add(-DefaultCardinalityWindows * params.Window)
+	if params.Start != "" {
+		if start, err = time.Parse(time.RFC3339Nano, params.Start); err != nil {
+			return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano: %w", params.Start, err)
+		}
+	}
+	if !start.Before(stop) {
+		return fmt.Errorf("start time %s must be before stop time %s", start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano))
+	}
+	// Check before querying anything, as each window is counted by its own query.
+	if windows := (stop.Sub(start) + params.Window - 1) / params.Window; windows > time.Duration(params.MaxWindows) {
+		return fmt.Errorf("the time range would be split into %d windows of %s, more than the maximum of %d, use a longer window or a shorter time range",
+			windows, params.Window, params.MaxWindows)
+	}
+
+	bucketName, err := c.bucketName(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	report := CardinalityReport{Start: start, Stop: stop}
+	if report.Series, err = c.seriesCardinality(ctx, params, start, stop, ""); err != nil {
+		return err
+	}
+
+	measurements, err := c.schemaValues(ctx, params, schemaCall("schema.measurements", bucketName, start, stop, ""))
+	if err != nil {
+		return fmt.Errorf("failed to list measurements: %w", err)
+	}
+	for _, m := range measurements {
+		n, err := c.seriesCardinality(ctx, params, start, stop, m)
+		if err != nil {
+			return err
+		}
+		report.Measurements = append(report.Measurements, MeasurementCardinality{Measurement: m, Series: n})
+	}
+	sort.SliceStable(report.Measurements, func(i, j int) bool {
+		return report.Measurements[i].Series > report.Measurements[j].Series
+	})
+
+	if report.TagKeys, err = c.topTagKeys(ctx, params, bucketName, start, stop); err != nil {
+		return err
+	}
+
+	// Windows are aligned to the stop time, so the most recent window is always complete.
+	for windowStop := stop; windowStop.After(start); windowStop = windowStop.Add(-params.Window) {
+		windowStart := windowStop.Add(-params.Window)
+		if windowStart.Before(start) {
+			windowStart = start
+		}
+		n, err := c.seriesCardinality(ctx, params, windowStart, windowStop, "")
+		if err != nil {
+			return err
+		}
+		report.Windows = append(report.Windows, WindowCardinality{Start: windowStart, Stop: windowStop, Series: n})
+	}
+	sort.Slice(report.Windows, func(i, j int) bool { return report.Windows[i].Start.Before(report.Windows[j].Start) })
+
+	return c.printCardinality(report)
+}
+
+// bucketName returns the name of the bucket in params, looking it up if it's given by ID.
+func (c CardinalityClient) bucketName(ctx context.Context, params *CardinalityParams) (string, error) {
+	if params.BucketName != "" {
+		return params.BucketName, nil
+	}
+	resp, err := c.GetBuckets(ctx).Id(params.BucketID).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to find bucket %q: %w", params.BucketID, err)
+	}
+	buckets := resp.GetBuckets()
+	if len(buckets) == 0 {
+		return "", fmt.Errorf("bucket %q not found", params.BucketID)
+	}
+	return buckets[0].GetName(), nil
+}
+
+// topTagKeys returns the params.TopTagKeys tag keys with the most distinct values.
+func (c CardinalityClient) topTagKeys(ctx context.Context, params *CardinalityParams, bucketName string, start, stop time.Time) ([]TagKeyCardinality, error) {
+	if params.TopTagKeys == 0 {
+		return nil, nil
+	}
+	keys, err := c.schemaValues(ctx, params, schemaCall("schema.tagKeys", bucketName, start, stop, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag keys: %w", err)
+	}
+	var tagKeys []TagKeyCardinality
+	for _, key := range keys {
+		if nonTagKeys[key] {
+			continue
+		}
+		// Values are counted by the server, so they aren't all sent for high-cardinality tags.
+		flux := schemaCall("schema.tagValues", bucketName, start, stop, "tag: "+query.StringLiteral(key)) + " |> count()"
+		records, err := c.query(ctx, params, flux)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count values of tag %q: %w", key, err)
+		}
+		var n int64
+		for _, r := range records {
+			if v, ok := r.Value().(int64); ok {
+				n += v
+			}
+		}
+		tagKeys = append(tagKeys, TagKeyCardinality{TagKey: key, Values: n})
+	}
+	sort.SliceStable(tagKeys, func(i, j int) bool { return tagKeys[i].Values > tagKeys[j].Values })
+	if len(tagKeys) > params.TopTagKeys {
+		tagKeys = tagKeys[:params.TopTagKeys]
+	}
+	return tagKeys, nil
+}
+
+// nonTagKeys are returned by schema.tagKeys(), but aren't tags.
+var nonTagKeys = map[string]bool{
+	"_start":       true,
+	"_stop":        true,
+	"_field":       true,
+	"_measurement": true,
+}
+
+// schemaCall returns a query calling fn from the schema package on a bucket between start and
+// stop, with extra args. Schema functions read the index of the bucket instead of its points.
+func schemaCall(fn, bucketName string, start, stop time.Time, args string) string {
+	if args != "" {
+		args = ", " + args
+	}
+	return fmt.Sprintf("import \"influxdata/influxdb/schema\"\n%s(bucket: %s%s, start: %s, stop: %s)",
+		fn, query.StringLiteral(bucketName), args, start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano))
+}
+
+// seriesCardinality returns the number of series between start and stop, limited to
+// measurement if it's not empty.
+func (c CardinalityClient) seriesCardinality(ctx context.Context, params *CardinalityParams, start, stop time.Time, measurement string) (int64, error) {
+	predicate := ""
+	if measurement != "" {
+		predicate = fmt.Sprintf(", predicate: (r) => r._measurement == %s", query.StringLiteral(measurement))
+	}
+	flux := fmt.Sprintf("import \"influxdata/influxdb\"\ninfluxdb.cardinality(%s, start: %s, stop: %s%s)",
+		fluxBucket(params.BucketParams), start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano), predicate)
+	records, err := c.query(ctx, params, flux)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute series cardinality: %w", err)
+	}
+	var n int64
+	for _, r := range records {
+		if v, ok := r.Value().(int64); ok {
+			n += v
+		}
+	}
+	return n, nil
+}
+
+// schemaValues returns the string values returned by a query of the schema package, sorted.
+func (c CardinalityClient) schemaValues(ctx context.Context, params *CardinalityParams, flux string) ([]string, error) {
+	records, err := c.query(ctx, params, flux)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(records))
+	for _, r := range records {
+		if v, ok := r.Value().(string); ok {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+func (c CardinalityClient) query(ctx context.Context, params *CardinalityParams, flux string) ([]*fluxcsv.FluxRecord, error) {
+	body, err := postFluxQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, flux)
+	if err != nil {
+		return nil, err
+	}
+	result := fluxcsv.NewQueryTableResult(body)
+	defer result.Close()
+
+	var records []*fluxcsv.FluxRecord
+	for result.Next() {
+		records = append(records, result.Record())
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (c CardinalityClient) printCardinality(report CardinalityReport) error {
+	if c.PrintAsJSON {
+		return c.PrintJSON(report)
+	}
+
+	measurementRows := make([]map[string]interface{}, len(report.Measurements))
+	for i, m := range report.Measurements {
+		measurementRows[i] = map[string]interface{}{
+			"Measurement": m.Measurement,
+			"Series":      m.Series,
+		}
+	}
+	if err := c.PrintTable([]string{"Measurement", "Series"}, measurementRows...); err != nil {
+		return err
+	}
+
+	tagKeyRows := make([]map[string]interface{}, len(report.TagKeys))
+	for i, t := range report.TagKeys {
+		tagKeyRows[i] = map[string]interface{}{
+			"Tag Key":         t.TagKey,
+			"Distinct Values": t.Values,
+		}
+	}
+	if _, err := c.StdIO.Write([]byte("\n")); err != nil {
+		return err
+	}
+	if err := c.PrintTable([]string{"Tag Key", "Distinct Values"}, tagKeyRows...); err != nil {
+		return err
+	}
+
+	windowRows := make([]map[string]interface{}, len(report.Windows))
+	var prev int64
+	for i, w := range report.Windows {
+		growth := ""
+		if i > 0 {
+			growth = fmt.Sprintf("%+d", w.Series-prev)
+		}
+		prev = w.Series
+		windowRows[i] = map[string]interface{}{
+			"Start":  w.Start.Format(time.RFC3339),
+			"Stop":   w.Stop.Format(time.RFC3339),
+			"Series": w.Series,
+			"Growth": growth,
+		}
+	}
+	if _, err := c.StdIO.Write([]byte("\n")); err != nil {
+		return err
+	}
+	if err := c.PrintTable([]string{"Start", "Stop", "Series", "Growth"}, windowRows...); err != nil {
+		return err
+	}
+
+	_, err := c.StdIO.Write([]byte(fmt.Sprintf("\nTotal series: %d\n", report.Series)))
+	return err
+}
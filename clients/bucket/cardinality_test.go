@@ -0,0 +1,165 @@
+package bucket_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func valuesCsv(datatype string, values ...string) string {
+	csv := "#group,false,false,false\n#datatype,string,long," + datatype + "\n#default,_result,,\n,result,table,_value\n"
+	for _, v := range values {
+		csv += ",,0," + v + "\n"
+	}
+	return csv
+}
+
+func TestBucketsCardinality(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	queryApi := mock.NewMockQueryApi(ctrl)
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).AnyTimes()
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+			require.Equal(t, "123", *req.GetOrgID())
+			q := req.GetQuery().Query
+			var body string
+			switch {
+			case strings.Contains(q, `influxdb.cardinality(bucketID: "456", start: 2021-01-01T00:00:00Z, stop: 2021-01-03T00:00:00Z)`):
+				body = valuesCsv("long", "30")
+			case strings.Contains(q, `r._measurement == "cpu"`):
+				body = valuesCsv("long", "10")
+			case strings.Contains(q, `r._measurement == "mem"`):
+				body = valuesCsv("long", "20")
+			case strings.Contains(q, `start: 2021-01-01T00:00:00Z, stop: 2021-01-02T00:00:00Z)`):
+				body = valuesCsv("long", "12")
+			case strings.Contains(q, `start: 2021-01-02T00:00:00Z, stop: 2021-01-03T00:00:00Z)`):
+				body = valuesCsv("long", "25")
+			case strings.Contains(q, `schema.measurements(bucket: "my-bucket", start: 2021-01-01T00:00:00Z, stop: 2021-01-03T00:00:00Z)`):
+				body = valuesCsv("string", "cpu", "mem")
+			case strings.Contains(q, `schema.tagKeys(bucket: "my-bucket", start: 2021-01-01T00:00:00Z, stop: 2021-01-03T00:00:00Z)`):
+				body = valuesCsv("string", "_field", "_measurement", "_start", "_stop", "host", "region")
+			case strings.Contains(q, `schema.tagValues(bucket: "my-bucket", tag: "host", start: 2021-01-01T00:00:00Z, stop: 2021-01-03T00:00:00Z) |> count()`):
+				body = valuesCsv("long", "3")
+			case strings.Contains(q, `schema.tagValues(bucket: "my-bucket", tag: "region", start: 2021-01-01T00:00:00Z, stop: 2021-01-03T00:00:00Z) |> count()`):
+				body = valuesCsv("long", "1")
+			default:
+				t.Fatalf("unexpected query: %s", q)
+			}
+			return &http.Response{Body: io.NopCloser(strings.NewReader(body))}, nil
+		}).AnyTimes()
+	// The schema package needs the name of the bucket.
+	bucketsApi := mock.NewMockBucketsApi(ctrl)
+	bucketsApi.EXPECT().GetBuckets(gomock.Any()).Return(api.ApiGetBucketsRequest{ApiService: bucketsApi})
+	bucketsApi.EXPECT().GetBucketsExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiGetBucketsRequest) (api.Buckets, error) {
+			require.Equal(t, "456", *req.GetId())
+			return api.Buckets{Buckets: &[]api.Bucket{{Name: "my-bucket"}}}, nil
+		})
+
+	stdout := bytes.Buffer{}
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+
+	client := bucket.CardinalityClient{
+		CLI:        clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-org"}, PrintAsJSON: true},
+		QueryApi:   queryApi,
+		BucketsApi: bucketsApi,
+	}
+	params := bucket.CardinalityParams{
+		OrgBucketParams: clients.OrgBucketParams{
+			OrgParams:    clients.OrgParams{OrgID: "123"},
+			BucketParams: clients.BucketParams{BucketID: "456"},
+		},
+		Start:      "2021-01-01T00:00:00Z",
+		Stop:       "2021-01-03T00:00:00Z",
+		Window:     24 * time.Hour,
+		TopTagKeys: 1,
+		MaxWindows: 2,
+	}
+	require.NoError(t, client.Cardinality(context.Background(), &params))
+
+	var report bucket.CardinalityReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	require.Equal(t, int64(30), report.Series)
+	require.Equal(t, []bucket.MeasurementCardinality{
+		{Measurement: "mem", Series: 20},
+		{Measurement: "cpu", Series: 10},
+	}, report.Measurements)
+	require.Equal(t, []bucket.TagKeyCardinality{{TagKey: "host", Values: 3}}, report.TagKeys)
+	require.Len(t, report.Windows, 2)
+	require.Equal(t, int64(12), report.Windows[0].Series)
+	require.Equal(t, int64(25), report.Windows[1].Series)
+	require.Equal(t, "2021-01-02T00:00:00Z", report.Windows[1].Start.Format(time.RFC3339))
+}
+
+func TestBucketsCardinality_InvalidParams(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		params        bucket.CardinalityParams
+		expectedError string
+	}{
+		{
+			name:          "negative top",
+			params:        bucket.CardinalityParams{Window: time.Hour, TopTagKeys: -1, MaxWindows: 10},
+			expectedError: "the number of top tag keys must not be negative",
+		},
+		{
+			name:          "no windows",
+			params:        bucket.CardinalityParams{Window: time.Hour},
+			expectedError: "the maximum number of windows must be positive",
+		},
+		{
+			name: "too many windows",
+			params: bucket.CardinalityParams{
+				Start:      "2011-01-01T00:00:00Z",
+				Stop:       "2021-01-01T00:30:00Z",
+				Window:     time.Minute,
+				MaxWindows: bucket.DefaultMaxCardinalityWindows,
+			},
+			expectedError: "the time range would be split into 5260350 windows of 1m0s, more than the maximum of 1000, use a longer window or a shorter time range",
+		},
+		{
+			name: "partial window over the limit",
+			params: bucket.CardinalityParams{
+				Start:      "2021-01-01T00:00:00Z",
+				Stop:       "2021-01-03T00:00:01Z",
+				Window:     24 * time.Hour,
+				MaxWindows: 2,
+			},
+			expectedError: "the time range would be split into 3 windows of 24h0m0s, more than the maximum of 2, use a longer window or a shorter time range",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// No queries are expected.
+			client := bucket.CardinalityClient{QueryApi: mock.NewMockQueryApi(gomock.NewController(t))}
+			params := tc.params
+			params.OrgBucketParams = clients.OrgBucketParams{
+				OrgParams:    clients.OrgParams{OrgID: "123"},
+				BucketParams: clients.BucketParams{BucketName: "my-bucket"},
+			}
+			require.EqualError(t, client.Cardinality(context.Background(), &params), tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/write"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/pkg/csv2lp"
+)
+
+// CopyClient copies points from a source bucket into a destination bucket, which may be
+// on a different server. The embedded CLI and APIs belong to the destination server.
+type CopyClient struct {
+	clients.CLI
+	api.WriteApi
+	write.RateLimiter
+	write.BatchWriter
+
+	// SrcQueryApi queries the source server.
+	SrcQueryApi api.QueryApi
+	// SrcConfig is the config used to connect to the source server.
+	SrcConfig config.Config
+}
+
+type CopyParams struct {
+	Src   clients.OrgBucketParams
+	Dst   clients.OrgBucketParams
+	Start string
+	Stop  string
+	// Window is the length of the time ranges the copy is split into.
+	Window time.Duration
+	// ResumeFile records the windows that have been copied, so an interrupted copy can be resumed.
+	ResumeFile string
+}
+
+// copyProgress is stored in the resume file after each copied window.
+type copyProgress struct {
+	Src       string    `json:"src"`
+	Dst       string    `json:"dst"`
+	Start     time.Time `json:"start"`
+	Stop      time.Time `json:"stop"`
+	Completed time.Time `json:"completed"`
+}
+
+func (c CopyClient) Copy(ctx context.Context, params *CopyParams) error {
+	if params.Src.OrgID == "" && params.Src.OrgName == "" && c.SrcConfig.Org == "" {
+		return fmt.Errorf("%s for the source bucket", clients.ErrMustSpecifyOrg)
+	}
+	if params.Dst.OrgID == "" && params.Dst.OrgName == "" && c.ActiveConfig.Org == "" {
+		return fmt.Errorf("%s for the destination bucket", clients.ErrMustSpecifyOrg)
+	}
+	if params.Src.BucketID == "" && params.Src.BucketName == "" {
+		return fmt.Errorf("%s to copy from", clients.ErrMustSpecifyBucket)
+	}
+	if params.Dst.BucketID == "" && params.Dst.BucketName == "" {
+		return fmt.Errorf("%s to copy to", clients.ErrMustSpecifyBucket)
+	}
+	if params.Window <= 0 {
+		return errors.New("window must be positive")
+	}
+	start, err := time.Parse(time.RFC3339Nano, params.Start)
+	if err != nil {
+		return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano: %w", params.Start, err)
+	}
+	stop := time.Now().UTC()
+	if params.Stop != "" {
+		if stop, err = time.Parse(time.RFC3339Nano, params.Stop); err != nil {
+			return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano: %w", params.Stop, err)
+		}
+	}
+	if !start.Before(stop) {
+		return fmt.Errorf("start time %s must be before stop time %s", start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano))
+	}
+
+	progress := copyProgress{
+		Src:   describeBucket(c.SrcConfig, params.Src),
+		Dst:   describeBucket(c.ActiveConfig, params.Dst),
+		Start: start,
+		Stop:  stop,
+	}
+	windowStart := start
+	if params.ResumeFile != "" {
+		resumed, err := readCopyProgress(params.ResumeFile)
+		if err != nil {
+			return err
+		}
+		if resumed != nil {
+			// An open-ended copy keeps the stop time of the interrupted run.
+			if params.Stop == "" {
+				progress.Stop, stop = resumed.Stop, resumed.Stop
+			}
+			if resumed.Src != progress.Src || resumed.Dst != progress.Dst ||
+				!resumed.Start.Equal(progress.Start) || !resumed.Stop.Equal(progress.Stop) {
+				return fmt.Errorf("resume file %q was written by a different copy, from %s to %s", params.ResumeFile, resumed.Src, resumed.Dst)
+			}
+			windowStart = resumed.Completed
+			_, _ = c.StdIO.WriteErr([]byte(fmt.Sprintf("Resuming copy from %s\n", windowStart.Format(time.RFC3339Nano))))
+		}
+	}
+
+	var windows, points int64
+	for windowStart.Before(stop) {
+		windowStop := windowStart.Add(params.Window)
+		if windowStop.After(stop) {
+			windowStop = stop
+		}
+		n, err := c.copyWindow(ctx, params, windowStart, windowStop)
+		if err != nil {
+			return fmt.Errorf("failed to copy window [%s, %s): %w",
+				windowStart.Format(time.RFC3339Nano), windowStop.Format(time.RFC3339Nano), err)
+		}
+		windows++
+		points += n
+		_, _ = c.StdIO.WriteErr([]byte(fmt.Sprintf("Copied %d points from [%s, %s)\n",
+			n, windowStart.Format(time.RFC3339Nano), windowStop.Format(time.RFC3339Nano))))
+
+		if params.ResumeFile != "" {
+			progress.Completed = windowStop
+			if err := writeCopyProgress(params.ResumeFile, progress); err != nil {
+				return err
+			}
+		}
+		windowStart = windowStop
+	}
+	if params.ResumeFile != "" {
+		if err := os.Remove(params.ResumeFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove resume file %q: %w", params.ResumeFile, err)
+		}
+	}
+
+	if c.PrintAsJSON {
+		return c.PrintJSON(map[string]interface{}{
+			"source":      progress.Src,
+			"destination": progress.Dst,
+			"windows":     windows,
+			"points":      points,
+		})
+	}
+	return c.PrintTable([]string{"Source", "Destination", "Windows", "Points"}, map[string]interface{}{
+		"Source":      progress.Src,
+		"Destination": progress.Dst,
+		"Windows":     windows,
+		"Points":      points,
+	})
+}
+
+// copyWindow copies the points in [start, stop) and returns how many were copied.
+func (c CopyClient) copyWindow(ctx context.Context, params *CopyParams, start, stop time.Time) (int64, error) {
+	reader := &windowLineReader{
+		client: c,
+		src:    params.Src,
+		start:  start,
+		stop:   stop,
+	}
+	writeClient := write.Client{
+		CLI:         c.CLI,
+		WriteApi:    c.WriteApi,
+		LineReader:  reader,
+		RateLimiter: c.RateLimiter,
+		BatchWriter: c.BatchWriter,
+	}
+	if err := writeClient.Write(ctx, &write.Params{OrgBucketParams: params.Dst, Precision: api.WRITEPRECISION_NS}); err != nil {
+		return 0, err
+	}
+	return reader.lines, nil
+}
+
+// windowLineReader queries one window of the source bucket, and converts the results to line protocol.
+type windowLineReader struct {
+	client      CopyClient
+	src         clients.OrgBucketParams
+	start, stop time.Time
+	lines       int64
+}
+
+func (r *windowLineReader) Open(ctx context.Context) (io.Reader, io.Closer, error) {
+	flux := fmt.Sprintf("%s |> range(start: %s, stop: %s)",
+		fluxFrom(r.src.BucketParams), r.start.Format(time.RFC3339Nano), r.stop.Format(time.RFC3339Nano))
+
+	body, err := postFluxQuery(ctx, r.client.SrcQueryApi, r.src.OrgParams, r.client.SrcConfig.Org, flux)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query source bucket: %w", err)
+	}
+	return &lineCounter{r: csv2lp.FluxCsvToLineProtocol(body), lines: &r.lines}, body, nil
+}
+
+// lineCounter counts the lines read from r.
+type lineCounter struct {
+	r     io.Reader
+	lines *int64
+}
+
+func (l *lineCounter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	*l.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	return n, err
+}
+
+// describeBucket identifies a bucket on the server of cfg.
+func describeBucket(cfg config.Config, params clients.OrgBucketParams) string {
+	bucket := params.BucketName
+	if params.BucketID != "" {
+		bucket = "id:" + params.BucketID
+	}
+	org := params.OrgName
+	if params.OrgID != "" {
+		org = "id:" + params.OrgID
+	} else if org == "" {
+		org = cfg.Org
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(cfg.Host, "/"), org, bucket)
+}
+
+func readCopyProgress(path string) (*copyProgress, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read resume file %q: %w", path, err)
+	}
+	var progress copyProgress
+	if err := json.Unmarshal(contents, &progress); err != nil {
+		return nil, fmt.Errorf("failed to parse resume file %q: %w", path, err)
+	}
+	return &progress, nil
+}
+
+func writeCopyProgress(path string, progress copyProgress) error {
+	contents, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so an interruption never leaves a partial resume file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write resume file %q: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write resume file %q: %w", path, err)
+	}
+	return nil
+}
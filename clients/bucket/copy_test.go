@@ -0,0 +1,191 @@
+package bucket_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/clients/write"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type noopThrottler struct{}
+
+func (noopThrottler) Throttle(_ context.Context, in io.Reader) io.Reader {
+	return in
+}
+
+func windowCsv(ts string) string {
+	return `#group,false,false,true,true,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement
+,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,` + ts + `,1,f,m
+`
+}
+
+func TestBucketsCopy(t *testing.T) {
+	t.Parallel()
+
+	params := bucket.CopyParams{
+		Src:    clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketName: "src"}},
+		Dst:    clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketName: "dst"}},
+		Start:  "2021-01-01T00:00:00Z",
+		Stop:   "2021-01-01T02:30:00Z",
+		Window: time.Hour,
+	}
+
+	testCases := []struct {
+		name          string
+		failAt        int
+		expectedLines []string
+		expectedErr   string
+	}{
+		{
+			name: "all windows",
+			expectedLines: []string{
+				"m f=1 1609459200000000000",
+				"m f=1 1609462800000000000",
+				"m f=1 1609466400000000000",
+			},
+		},
+		{
+			name:   "failed window",
+			failAt: 2,
+			expectedLines: []string{
+				"m f=1 1609459200000000000",
+			},
+			expectedErr: "failed to copy window [2021-01-01T01:00:00Z, 2021-01-01T02:00:00Z): failed to query source bucket: failed to execute query: boom",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			var queries []string
+			queryApi := mock.NewMockQueryApi(ctrl)
+			queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).AnyTimes()
+			queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+				DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+					require.Equal(t, "src-org", *req.GetOrg())
+					queries = append(queries, req.GetQuery().Query)
+					if len(queries) == tc.failAt {
+						return nil, errors.New("boom")
+					}
+					times := []string{"2021-01-01T00:00:00Z", "2021-01-01T01:00:00Z", "2021-01-01T02:00:00Z"}
+					return &http.Response{Body: io.NopCloser(strings.NewReader(windowCsv(times[len(queries)-1])))}, nil
+				}).AnyTimes()
+
+			var written []string
+			writeApi := mock.NewMockWriteApi(ctrl)
+			writeApi.EXPECT().PostWrite(gomock.Any()).Return(api.ApiPostWriteRequest{ApiService: writeApi}).AnyTimes()
+			writeApi.EXPECT().PostWriteExecute(gomock.Any()).DoAndReturn(func(req api.ApiPostWriteRequest) error {
+				require.Equal(t, "dst-org", *req.GetOrg())
+				require.Equal(t, "dst", *req.GetBucket())
+				gzr, err := gzip.NewReader(bytes.NewReader(req.GetBody()))
+				require.NoError(t, err)
+				body, err := io.ReadAll(gzr)
+				require.NoError(t, err)
+				written = append(written, strings.TrimSpace(string(body)))
+				return nil
+			}).AnyTimes()
+
+			stdout := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+			stdio.EXPECT().WriteErr(gomock.Any()).AnyTimes()
+
+			client := bucket.CopyClient{
+				CLI:         clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Host: "http://dst:8086", Org: "dst-org"}},
+				WriteApi:    writeApi,
+				RateLimiter: noopThrottler{},
+				BatchWriter: &write.BufferBatcher{MaxFlushBytes: write.DefaultMaxBytes, MaxFlushInterval: write.DefaultInterval},
+				SrcQueryApi: queryApi,
+				SrcConfig:   config.Config{Host: "http://src:8086", Org: "src-org"},
+			}
+			params := params
+			err := client.Copy(context.Background(), &params)
+			require.Equal(t, tc.expectedLines, written)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, `from(bucket: "src") |> range(start: 2021-01-01T02:00:00Z, stop: 2021-01-01T02:30:00Z)`, queries[2])
+			require.Regexp(t, `http://src:8086/src-org/src\s+http://dst:8086/dst-org/dst\s+3\s+3`, stdout.String())
+		})
+	}
+}
+
+func TestBucketsCopy_Resume(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	resumeFile := filepath.Join(t.TempDir(), "copy.json")
+	params := bucket.CopyParams{
+		Src:        clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketID: "123"}},
+		Dst:        clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketName: "dst"}},
+		Start:      "2021-01-01T00:00:00Z",
+		Stop:       "2021-01-01T02:00:00Z",
+		Window:     time.Hour,
+		ResumeFile: resumeFile,
+	}
+
+	var queries []string
+	queryApi := mock.NewMockQueryApi(ctrl)
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).AnyTimes()
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+			queries = append(queries, req.GetQuery().Query)
+			if len(queries) == 2 {
+				return nil, errors.New("boom")
+			}
+			return &http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil
+		}).AnyTimes()
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).AnyTimes()
+	stdio.EXPECT().WriteErr(gomock.Any()).AnyTimes()
+
+	client := bucket.CopyClient{
+		CLI:         clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "org"}},
+		WriteApi:    mock.NewMockWriteApi(ctrl),
+		RateLimiter: noopThrottler{},
+		BatchWriter: &write.BufferBatcher{MaxFlushBytes: write.DefaultMaxBytes, MaxFlushInterval: write.DefaultInterval},
+		SrcQueryApi: queryApi,
+		SrcConfig:   config.Config{Org: "org"},
+	}
+
+	require.Error(t, client.Copy(context.Background(), &params))
+	require.FileExists(t, resumeFile)
+
+	// A different copy can't use the resume file.
+	otherParams := params
+	otherParams.Dst.BucketName = "other"
+	require.ErrorContains(t, client.Copy(context.Background(), &otherParams), "was written by a different copy")
+
+	require.NoError(t, client.Copy(context.Background(), &params))
+	require.Equal(t, []string{
+		`from(bucketID: "123") |> range(start: 2021-01-01T00:00:00Z, stop: 2021-01-01T01:00:00Z)`,
+		`from(bucketID: "123") |> range(start: 2021-01-01T01:00:00Z, stop: 2021-01-01T02:00:00Z)`,
+		`from(bucketID: "123") |> range(start: 2021-01-01T01:00:00Z, stop: 2021-01-01T02:00:00Z)`,
+	}, queries)
+	_, err := os.Stat(resumeFile)
+	require.True(t, os.IsNotExist(err))
+}
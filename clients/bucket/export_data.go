@@ -0,0 +1,376 @@
+package bucket
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/pkg/csv2lp"
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+type ExportFormat int
+
+const (
+	ExportFormatLP ExportFormat = iota
+	ExportFormatCSV
+	ExportFormatParquet
+)
+
+func (f *ExportFormat) Set(v string) error {
+	switch v {
+	case "lp":
+		*f = ExportFormatLP
+	case "csv":
+		*f = ExportFormatCSV
+	case "parquet":
+		*f = ExportFormatParquet
+	default:
+		return fmt.Errorf("unsupported format: %q", v)
+	}
+	return nil
+}
+
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportFormatLP:
+		return "lp"
+	case ExportFormatCSV:
+		return "csv"
+	case ExportFormatParquet:
+		return "parquet"
+	default:
+		return ""
+	}
+}
+
+// fileName returns the name of the files written in the format. Parquet files are
+// compressed internally, other formats are gzipped.
+func (f ExportFormat) fileName() string {
+	if f == ExportFormatParquet {
+		return "part.parquet"
+	}
+	return fmt.Sprintf("part.%s.gz", f)
+}
+
+func (f ExportFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *ExportFormat) UnmarshalText(text []byte) error {
+	return f.Set(string(text))
+}
+
+// ExportManifestName is the name of the manifest written next to exported files.
+const ExportManifestName = "manifest.json"
+
+// ExportManifest describes the files written by an export.
+type ExportManifest struct {
+	Org    string               `json:"org"`
+	Bucket string               `json:"bucket"`
+	Start  time.Time            `json:"start"`
+	Stop   time.Time            `json:"stop"`
+	Format ExportFormat         `json:"format"`
+	Files  []ExportManifestFile `json:"files"`
+}
+
+type ExportManifestFile struct {
+	// Path is relative to the export directory, and always uses forward slashes.
+	Path        string `json:"path"`
+	Measurement string `json:"measurement"`
+	Date        string `json:"date"`
+	Rows        int64  `json:"rows"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// ExportDataClient exports the points of a bucket to local files.
+type ExportDataClient struct {
+	clients.CLI
+	api.QueryApi
+}
+
+type ExportDataParams struct {
+	clients.OrgBucketParams
+	Start string
+	Stop  string
+	// Path to the directory exported files are written to.
+	Path   string
+	Format ExportFormat
+	// Parallelism is the number of partitions exported concurrently.
+	Parallelism int
+}
+
+// exportPartition is the data of one measurement on one day.
+type exportPartition struct {
+	measurement string
+	start, stop time.Time
+}
+
+// ExportData writes the points of a bucket to files partitioned by measurement and day, in
+// the form <measurement>/date=YYYY-MM-DD/part.<format>.gz, or part.parquet for Parquet.
+// Annotated CSV files can be written back with 'influx write --format flux-csv'. Parquet
+// files have a row per series and timestamp, with a column per tag and field.
+func (c ExportDataClient) ExportData(ctx context.Context, params *ExportDataParams) error {
+	if params.OrgID == "" && params.OrgName == "" && c.ActiveConfig.Org == "" {
+		return clients.ErrMustSpecifyOrg
+	}
+	if params.BucketID == "" && params.BucketName == "" {
+		return clients.ErrMustSpecifyBucket
+	}
+	if params.Parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+	start, err := time.Parse(time.RFC3339Nano, params.Start)
+	if err != nil {
+		return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano: %w", params.Start, err)
+	}
+	stop := time.Now().UTC()
+	if params.Stop != "" {
+		if stop, err = time.Parse(time.RFC3339Nano, params.Stop); err != nil {
+			return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano: %w", params.Stop, err)
+		}
+	}
+	if !start.Before(stop) {
+		return fmt.Errorf("start time %s must be before stop time %s", start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano))
+	}
+	if err := os.MkdirAll(params.Path, 0777); err != nil {
+		return err
+	}
+
+	measurements, err := c.listMeasurements(ctx, params, start, stop)
+	if err != nil {
+		return fmt.Errorf("failed to list measurements: %w", err)
+	}
+	var partitions []exportPartition
+	for _, m := range measurements {
+		for dayStart := start; dayStart.Before(stop); {
+			dayStop := dayStart.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if dayStop.After(stop) {
+				dayStop = stop
+			}
+			partitions = append(partitions, exportPartition{measurement: m, start: dayStart, stop: dayStop})
+			dayStart = dayStop
+		}
+	}
+
+	files, err := c.exportPartitions(ctx, params, partitions)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	manifest := ExportManifest{
+		Org:    params.OrgName,
+		Bucket: params.BucketName,
+		Start:  start,
+		Stop:   stop,
+		Format: params.Format,
+		Files:  files,
+	}
+	if params.OrgID != "" {
+		manifest.Org = params.OrgID
+	} else if manifest.Org == "" {
+		manifest.Org = c.ActiveConfig.Org
+	}
+	if params.BucketID != "" {
+		manifest.Bucket = params.BucketID
+	}
+	if err := writeExportManifest(filepath.Join(params.Path, ExportManifestName), manifest); err != nil {
+		return fmt.Errorf("failed to write export manifest: %w", err)
+	}
+
+	if c.PrintAsJSON {
+		return c.PrintJSON(manifest)
+	}
+	headers := []string{"Path", "Rows", "Size", "SHA256"}
+	rows := make([]map[string]interface{}, len(files))
+	for i, f := range files {
+		rows[i] = map[string]interface{}{
+			"Path":   f.Path,
+			"Rows":   f.Rows,
+			"Size":   f.Size,
+			"SHA256": f.SHA256,
+		}
+	}
+	return c.PrintTable(headers, rows...)
+}
+
+// exportPartitions exports partitions using params.Parallelism workers, and returns the
+// written files. Empty partitions don't produce files.
+func (c ExportDataClient) exportPartitions(ctx context.Context, params *ExportDataParams, partitions []exportPartition) ([]ExportManifestFile, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	todo := make(chan exportPartition)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		files    []ExportManifestFile
+		firstErr error
+	)
+	for i := 0; i < params.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range todo {
+				file, err := c.exportPartition(ctx, params, p)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to export %q on %s: %w", p.measurement, p.start.UTC().Format("2006-01-02"), err)
+					cancel()
+				} else if file != nil {
+					files = append(files, *file)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range partitions {
+		select {
+		case todo <- p:
+		case <-ctx.Done():
+		}
+	}
+	close(todo)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return files, ctx.Err()
+}
+
+func (c ExportDataClient) exportPartition(ctx context.Context, params *ExportDataParams, p exportPartition) (*ExportManifestFile, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	flux := fmt.Sprintf("%s |> range(start: %s, stop: %s) |> filter(fn: (r) => r._measurement == %s)",
+		fluxFrom(params.BucketParams), p.start.Format(time.RFC3339Nano), p.stop.Format(time.RFC3339Nano), query.StringLiteral(p.measurement))
+	if params.Format == ExportFormatParquet {
+		flux += " " + parquetPivot
+	}
+	body, err := postFluxQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, flux)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	date := p.start.UTC().Format("2006-01-02")
+	relPath := path.Join(url.PathEscape(p.measurement), "date="+date, params.Format.fileName())
+	filePath := filepath.Join(params.Path, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+		return nil, err
+	}
+	tmpPath := filePath + ".tmp"
+	defer os.Remove(tmpPath)
+
+	hash := sha256.New()
+	var rows int64
+	// Closure here so we can clean up file resources via `defer` without
+	// returning from the whole function.
+	if err := func() error {
+		f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if params.Format == ExportFormatParquet {
+			if rows, err = writeParquet(io.MultiWriter(f, hash), body); err != nil {
+				return err
+			}
+			return f.Close()
+		}
+
+		gzw := gzip.NewWriter(io.MultiWriter(f, hash))
+		switch params.Format {
+		case ExportFormatCSV:
+			result := fluxcsv.NewQueryTableResult(io.NopCloser(io.TeeReader(body, gzw)))
+			for result.Next() {
+				rows++
+			}
+			if err := result.Err(); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.Copy(gzw, &lineCounter{r: csv2lp.FluxCsvToLineProtocol(body), lines: &rows}); err != nil {
+				return err
+			}
+		}
+		if err := gzw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	}(); err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, nil
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportManifestFile{
+		Path:        relPath,
+		Measurement: p.measurement,
+		Date:        date,
+		Rows:        rows,
+		Size:        fi.Size(),
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// listMeasurements returns the measurements with points between start and stop.
+func (c ExportDataClient) listMeasurements(ctx context.Context, params *ExportDataParams, start, stop time.Time) ([]string, error) {
+	flux := fmt.Sprintf(`%s |> range(start: %s, stop: %s) |> keep(columns: ["_measurement"]) |> group() |> distinct(column: "_measurement")`,
+		fluxFrom(params.BucketParams), start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano))
+	body, err := postFluxQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, flux)
+	if err != nil {
+		return nil, err
+	}
+	result := fluxcsv.NewQueryTableResult(body)
+	defer result.Close()
+
+	var measurements []string
+	for result.Next() {
+		if m, ok := result.Record().Value().(string); ok {
+			measurements = append(measurements, m)
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(measurements)
+	return measurements, nil
+}
+
+func writeExportManifest(path string, manifest ExportManifest) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
@@ -0,0 +1,245 @@
+package bucket_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+const measurementsCsv = `#group,false,false,false
+#datatype,string,long,string
+#default,_result,,
+,result,table,_value
+,,0,cpu
+,,0,disk/io
+`
+
+func pointCsv(measurement, ts string) string {
+	return `#group,false,false,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,double,string,string
+#default,_result,,,,,
+,result,table,_time,_value,_field,_measurement
+,,0,` + ts + `,1,f,` + measurement + `
+`
+}
+
+func TestBucketsExportData(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		format        bucket.ExportFormat
+		expectedFiles map[string]string
+	}{
+		{
+			name:   "line protocol",
+			format: bucket.ExportFormatLP,
+			expectedFiles: map[string]string{
+				"cpu/date=2021-01-01/part.lp.gz":       "cpu f=1 1609502400000000000\n",
+				"cpu/date=2021-01-02/part.lp.gz":       "cpu f=1 1609588800000000000\n",
+				"disk%2Fio/date=2021-01-01/part.lp.gz": "disk/io f=1 1609502400000000000\n",
+			},
+		},
+		{
+			name:   "annotated csv",
+			format: bucket.ExportFormatCSV,
+			expectedFiles: map[string]string{
+				"cpu/date=2021-01-01/part.csv.gz":       pointCsv("cpu", "2021-01-01T12:00:00Z"),
+				"cpu/date=2021-01-02/part.csv.gz":       pointCsv("cpu", "2021-01-02T12:00:00Z"),
+				"disk%2Fio/date=2021-01-01/part.csv.gz": pointCsv("disk/io", "2021-01-01T12:00:00Z"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			queryApi := mock.NewMockQueryApi(ctrl)
+			queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).AnyTimes()
+			queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+				DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+					require.Equal(t, "my-org", *req.GetOrg())
+					q := req.GetQuery().Query
+					var body string
+					switch {
+					case strings.Contains(q, "distinct"):
+						require.Equal(t, `from(bucket: "my-bucket") |> range(start: 2021-01-01T06:00:00Z, stop: 2021-01-02T18:00:00Z) |> keep(columns: ["_measurement"]) |> group() |> distinct(column: "_measurement")`, q)
+						body = measurementsCsv
+					case strings.Contains(q, `"cpu"`) && strings.Contains(q, "start: 2021-01-01T06:00:00Z, stop: 2021-01-02T00:00:00Z"):
+						body = pointCsv("cpu", "2021-01-01T12:00:00Z")
+					case strings.Contains(q, `"cpu"`) && strings.Contains(q, "start: 2021-01-02T00:00:00Z, stop: 2021-01-02T18:00:00Z"):
+						body = pointCsv("cpu", "2021-01-02T12:00:00Z")
+					case strings.Contains(q, `"disk/io"`) && strings.Contains(q, "start: 2021-01-01T06:00:00Z"):
+						body = pointCsv("disk/io", "2021-01-01T12:00:00Z")
+					}
+					return &http.Response{Body: io.NopCloser(strings.NewReader(body))}, nil
+				}).Times(5)
+
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).AnyTimes()
+
+			out := t.TempDir()
+			client := bucket.ExportDataClient{
+				CLI:      clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-org"}},
+				QueryApi: queryApi,
+			}
+			params := bucket.ExportDataParams{
+				OrgBucketParams: clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketName: "my-bucket"}},
+				Start:           "2021-01-01T06:00:00Z",
+				Stop:            "2021-01-02T18:00:00Z",
+				Path:            out,
+				Format:          tc.format,
+				Parallelism:     2,
+			}
+			require.NoError(t, client.ExportData(context.Background(), &params))
+
+			manifestBytes, err := os.ReadFile(filepath.Join(out, bucket.ExportManifestName))
+			require.NoError(t, err)
+			var manifest bucket.ExportManifest
+			require.NoError(t, json.Unmarshal(manifestBytes, &manifest))
+			require.Equal(t, "my-org", manifest.Org)
+			require.Equal(t, "my-bucket", manifest.Bucket)
+			require.Len(t, manifest.Files, len(tc.expectedFiles))
+
+			for _, f := range manifest.Files {
+				expected, ok := tc.expectedFiles[f.Path]
+				require.True(t, ok, f.Path)
+				require.Equal(t, int64(1), f.Rows)
+
+				compressed, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(f.Path)))
+				require.NoError(t, err)
+				sum := sha256.Sum256(compressed)
+				require.Equal(t, hex.EncodeToString(sum[:]), f.SHA256)
+				require.Equal(t, int64(len(compressed)), f.Size)
+
+				gzr, err := gzip.NewReader(bytes.NewReader(compressed))
+				require.NoError(t, err)
+				contents, err := io.ReadAll(gzr)
+				require.NoError(t, err)
+				require.Equal(t, expected, string(contents))
+			}
+		})
+	}
+}
+
+const pivotedCsv = `#group,false,false,false,false,false,false,false,false
+#datatype,string,long,dateTime:RFC3339,string,string,double,long,string
+#default,_result,,,,,,,
+,result,table,_time,_measurement,host,usage,count,state
+,,0,2021-01-01T12:00:00Z,cpu,a,1.5,3,ok
+,,0,2021-01-01T12:00:10Z,cpu,b,2,,
+`
+
+type parquetPoint struct {
+	Time        *int64   `parquet:"_time,optional"`
+	Measurement *string  `parquet:"_measurement,optional"`
+	Host        *string  `parquet:"host,optional"`
+	Usage       *float64 `parquet:"usage,optional"`
+	Count       *int64   `parquet:"count,optional"`
+	State       *string  `parquet:"state,optional"`
+}
+
+func TestBucketsExportData_Parquet(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	queryApi := mock.NewMockQueryApi(ctrl)
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).AnyTimes()
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+			q := req.GetQuery().Query
+			var body string
+			switch {
+			case strings.Contains(q, "distinct"):
+				body = measurementsCsv
+			case strings.Contains(q, `"cpu"`):
+				require.Equal(t, `from(bucket: "my-bucket") |> range(start: 2021-01-01T00:00:00Z, stop: 2021-01-02T00:00:00Z) |> filter(fn: (r) => r._measurement == "cpu") `+
+					`|> drop(columns: ["_start", "_stop"]) |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value") |> group()`, q)
+				body = pivotedCsv
+			}
+			return &http.Response{Body: io.NopCloser(strings.NewReader(body))}, nil
+		}).Times(3)
+
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).AnyTimes()
+
+	out := t.TempDir()
+	client := bucket.ExportDataClient{
+		CLI:      clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-org"}},
+		QueryApi: queryApi,
+	}
+	params := bucket.ExportDataParams{
+		OrgBucketParams: clients.OrgBucketParams{BucketParams: clients.BucketParams{BucketName: "my-bucket"}},
+		Start:           "2021-01-01T00:00:00Z",
+		Stop:            "2021-01-02T00:00:00Z",
+		Path:            out,
+		Format:          bucket.ExportFormatParquet,
+		Parallelism:     1,
+	}
+	require.NoError(t, client.ExportData(context.Background(), &params))
+
+	manifestBytes, err := os.ReadFile(filepath.Join(out, bucket.ExportManifestName))
+	require.NoError(t, err)
+	var manifest bucket.ExportManifest
+	require.NoError(t, json.Unmarshal(manifestBytes, &manifest))
+	require.Equal(t, bucket.ExportFormatParquet, manifest.Format)
+	// disk/io has no points, so only cpu is exported.
+	require.Len(t, manifest.Files, 1)
+	f := manifest.Files[0]
+	require.Equal(t, "cpu/date=2021-01-01/part.parquet", f.Path)
+	require.Equal(t, int64(2), f.Rows)
+
+	contents, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(f.Path)))
+	require.NoError(t, err)
+	sum := sha256.Sum256(contents)
+	require.Equal(t, hex.EncodeToString(sum[:]), f.SHA256)
+	require.Equal(t, int64(len(contents)), f.Size)
+
+	points, err := parquet.Read[parquetPoint](bytes.NewReader(contents), int64(len(contents)))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	require.Equal(t, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC).UnixNano(), *points[0].Time)
+	require.Equal(t, "cpu", *points[0].Measurement)
+	require.Equal(t, "a", *points[0].Host)
+	require.Equal(t, 1.5, *points[0].Usage)
+	require.Equal(t, int64(3), *points[0].Count)
+	require.Equal(t, "ok", *points[0].State)
+	require.Equal(t, time.Date(2021, 1, 1, 12, 0, 10, 0, time.UTC).UnixNano(), *points[1].Time)
+	require.Equal(t, "b", *points[1].Host)
+	require.Equal(t, 2.0, *points[1].Usage)
+	require.Nil(t, points[1].Count)
+	require.Nil(t, points[1].State)
+}
+
+func TestExportFormat_Set(t *testing.T) {
+	t.Parallel()
+
+	var f bucket.ExportFormat
+	require.NoError(t, f.Set("csv"))
+	require.Equal(t, bucket.ExportFormatCSV, f)
+	require.NoError(t, f.Set("parquet"))
+	require.Equal(t, bucket.ExportFormatParquet, f)
+	require.Error(t, f.Set("xml"))
+}
@@ -0,0 +1,143 @@
+package bucket
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetPivot turns the points of a measurement into a single table with a row per series
+// and timestamp, and a column per tag and field, so that each Parquet file has one schema.
+const parquetPivot = `|> drop(columns: ["_start", "_stop"]) |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value") |> group()`
+
+// writeParquet writes the records of a pivoted query response to w as a Snappy-compressed
+// Parquet file, and returns the number of rows written. Nothing is written if the response
+// has no records.
+func writeParquet(w io.Writer, body io.ReadCloser) (int64, error) {
+	result := fluxcsv.NewQueryTableResult(body)
+	defer result.Close()
+
+	var (
+		pw      *parquet.Writer
+		columns []fluxcsv.FluxColumn
+		rows    int64
+	)
+	for result.Next() {
+		if result.AnnotationsChanged() {
+			schema, tableColumns, err := parquetSchema(result.Metadata())
+			if err != nil {
+				return 0, err
+			}
+			if pw == nil {
+				pw = parquet.NewWriter(w, schema, parquet.Compression(&parquet.Snappy))
+				columns = tableColumns
+			} else if !sameColumns(columns, tableColumns) {
+				return 0, fmt.Errorf("columns changed from %s to %s within the result", pw.Schema(), schema)
+			}
+		}
+
+		values := result.Record().Values()
+		row := make(parquet.Row, len(columns))
+		for i, col := range columns {
+			v, err := parquetValue(values[col.Name()])
+			if err != nil {
+				return 0, fmt.Errorf("column %q: %w", col.Name(), err)
+			}
+			if v.IsNull() {
+				row[i] = v.Level(0, 0, i)
+			} else {
+				row[i] = v.Level(0, 1, i)
+			}
+		}
+		if _, err := pw.WriteRows([]parquet.Row{row}); err != nil {
+			return 0, err
+		}
+		rows++
+	}
+	if err := result.Err(); err != nil {
+		return 0, err
+	}
+	if pw == nil {
+		return 0, nil
+	}
+	return rows, pw.Close()
+}
+
+// parquetSchema returns the Parquet schema of a table, and its columns in the order of the
+// schema's leaf columns. All columns are optional, as pivoted fields may be missing.
+func parquetSchema(metadata *fluxcsv.FluxTableMetadata) (*parquet.Schema, []fluxcsv.FluxColumn, error) {
+	group := parquet.Group{}
+	byName := map[string]fluxcsv.FluxColumn{}
+	for _, col := range metadata.Columns() {
+		var node parquet.Node
+		switch col.DataType() {
+		case fluxcsv.StringDatatype:
+			node = parquet.String()
+		case fluxcsv.DoubleDatatype:
+			node = parquet.Leaf(parquet.DoubleType)
+		case fluxcsv.BoolDatatype:
+			node = parquet.Leaf(parquet.BooleanType)
+		case fluxcsv.LongDatatype, fluxcsv.DurationDatatype:
+			node = parquet.Int(64)
+		case fluxcsv.ULongDatatype:
+			node = parquet.Uint(64)
+		case fluxcsv.Base64BinaryDataType:
+			node = parquet.Leaf(parquet.ByteArrayType)
+		case fluxcsv.TimeDatatypeRFC, fluxcsv.TimeDatatypeRFCNano:
+			node = parquet.Timestamp(parquet.Nanosecond)
+		default:
+			return nil, nil, fmt.Errorf("column %q has unsupported data type %v", col.Name(), col.DataType())
+		}
+		group[col.Name()] = parquet.Optional(node)
+		byName[col.Name()] = col
+	}
+	schema := parquet.NewSchema("influxdb", group)
+
+	columns := make([]fluxcsv.FluxColumn, 0, len(byName))
+	for _, path := range schema.Columns() {
+		columns = append(columns, byName[path[0]])
+	}
+	return schema, columns, nil
+}
+
+func sameColumns(a, b []fluxcsv.FluxColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name() != b[i].Name() || a[i].DataType() != b[i].DataType() {
+			return false
+		}
+	}
+	return true
+}
+
+// parquetValue converts a value parsed by fluxcsv to a Parquet value.
+func parquetValue(v interface{}) (parquet.Value, error) {
+	if v == nil {
+		return parquet.NullValue(), nil
+	}
+	switch v := v.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(v)), nil
+	case float64:
+		return parquet.DoubleValue(v), nil
+	case bool:
+		return parquet.BooleanValue(v), nil
+	case int64:
+		return parquet.Int64Value(v), nil
+	case uint64:
+		return parquet.Int64Value(int64(v)), nil
+	case time.Duration:
+		return parquet.Int64Value(int64(v)), nil
+	case []byte:
+		return parquet.ByteArrayValue(v), nil
+	case time.Time:
+		return parquet.Int64Value(v.UnixNano()), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("unexpected value of type %T", v)
+	}
+}
@@ -0,0 +1,30 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+)
+
+// fluxBucket returns the Flux argument selecting the bucket in params.
+func fluxBucket(params clients.BucketParams) string {
+	if params.BucketID != "" {
+		return "bucketID: " + query.StringLiteral(params.BucketID)
+	}
+	return "bucket: " + query.StringLiteral(params.BucketName)
+}
+
+// fluxFrom returns a Flux from() call reading the bucket in params.
+func fluxFrom(params clients.BucketParams) string {
+	return fmt.Sprintf("from(%s)", fluxBucket(params))
+}
+
+// postFluxQuery executes flux in the org selected by params, or in defaultOrg if no org
+// is selected, and returns the annotated CSV response.
+func postFluxQuery(ctx context.Context, queryApi api.QueryApi, params clients.OrgParams, defaultOrg string, flux string) (io.ReadCloser, error) {
+	return query.PostQuery(ctx, queryApi, params, defaultOrg, query.BuildDefaultAST(flux))
+}
@@ -0,0 +1,183 @@
+package delete
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/duration"
+)
+
+// deleteProgress is stored in the resume file after each deleted chunk.
+type deleteProgress struct {
+	Bucket    string    `json:"bucket"`
+	Predicate string    `json:"predicate"`
+	Start     time.Time `json:"start"`
+	Stop      time.Time `json:"stop"`
+	// Completed is the time up to which every chunk has been deleted. Chunks deleted in
+	// parallel after it are deleted again on resume, which is harmless.
+	Completed time.Time `json:"completed"`
+}
+
+type chunk struct {
+	index       int
+	start, stop time.Time
+}
+
+// deleteChunks deletes the data between start and stop in windows of chunkSize, using
+// params.Parallelism concurrent requests.
+func (c Client) deleteChunks(ctx context.Context, params *Params, predicate string, start, stop time.Time, chunkSize time.Duration) error {
+	if params.Parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+	progress := deleteProgress{
+		Bucket:    params.BucketName,
+		Predicate: predicate,
+		Start:     start,
+		Stop:      stop,
+	}
+	if params.BucketID != "" {
+		progress.Bucket = params.BucketID
+	}
+	if params.ResumeFile != "" {
+		resumed, err := readDeleteProgress(params.ResumeFile)
+		if err != nil {
+			return err
+		}
+		if resumed != nil {
+			// Relative times move between runs, so the times of the interrupted run are kept.
+			if duration.IsRelativeTime(params.Start) {
+				progress.Start = resumed.Start
+			}
+			if duration.IsRelativeTime(params.Stop) {
+				progress.Stop = resumed.Stop
+			}
+			if resumed.Bucket != progress.Bucket || resumed.Predicate != progress.Predicate ||
+				!resumed.Start.Equal(progress.Start) || !resumed.Stop.Equal(progress.Stop) {
+				return fmt.Errorf("resume file %q was written by a different delete, from bucket %q with predicate %q",
+					params.ResumeFile, resumed.Bucket, resumed.Predicate)
+			}
+			progress.Completed = resumed.Completed
+			start, stop = resumed.Completed, progress.Stop
+			_, _ = c.StdIO.WriteErr([]byte(fmt.Sprintf("Resuming delete from %s\n", start.Format(time.RFC3339Nano))))
+		}
+	}
+
+	var chunks []chunk
+	for chunkStart := start; chunkStart.Before(stop); chunkStart = chunkStart.Add(chunkSize) {
+		chunkStop := chunkStart.Add(chunkSize)
+		if chunkStop.After(stop) {
+			chunkStop = stop
+		}
+		chunks = append(chunks, chunk{index: len(chunks), start: chunkStart, stop: chunkStop})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	todo := make(chan chunk)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		done      = make([]bool, len(chunks))
+		completed int
+		deleted   int
+		firstErr  error
+	)
+	for i := 0; i < params.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range todo {
+				// Chunks handed out after a failure are left for a resumed delete.
+				if ctx.Err() != nil {
+					continue
+				}
+				err := c.deleteWithRetry(ctx, params, predicate, ch.start, ch.stop)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to delete chunk [%s, %s): %w",
+							ch.start.Format(time.RFC3339Nano), ch.stop.Format(time.RFC3339Nano), err)
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				deleted++
+				_, _ = c.StdIO.WriteErr([]byte(fmt.Sprintf("Deleted chunk %d/%d [%s, %s)\n",
+					deleted, len(chunks), ch.start.Format(time.RFC3339Nano), ch.stop.Format(time.RFC3339Nano))))
+				done[ch.index] = true
+				advanced := false
+				for completed < len(chunks) && done[completed] {
+					progress.Completed = chunks[completed].stop
+					completed++
+					advanced = true
+				}
+				if advanced && params.ResumeFile != "" && firstErr == nil {
+					if err := writeDeleteProgress(params.ResumeFile, progress); err != nil {
+						firstErr = err
+						cancel()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, ch := range chunks {
+		select {
+		case todo <- ch:
+		case <-ctx.Done():
+		}
+	}
+	close(todo)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if params.ResumeFile != "" {
+		if err := os.Remove(params.ResumeFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove resume file %q: %w", params.ResumeFile, err)
+		}
+	}
+	return nil
+}
+
+func readDeleteProgress(path string) (*deleteProgress, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read resume file %q: %w", path, err)
+	}
+	var progress deleteProgress
+	if err := json.Unmarshal(contents, &progress); err != nil {
+		return nil, fmt.Errorf("failed to parse resume file %q: %w", path, err)
+	}
+	return &progress, nil
+}
+
+func writeDeleteProgress(path string, progress deleteProgress) error {
+	contents, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so an interruption never leaves a partial resume file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write resume file %q: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write resume file %q: %w", path, err)
+	}
+	return nil
+}
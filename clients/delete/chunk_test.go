@@ -0,0 +1,206 @@
+package delete_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/delete"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_DeleteChunks(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	chunkParams := func(resumeFile string) delete.Params {
+		return delete.Params{
+			OrgBucketParams: clients.OrgBucketParams{
+				BucketParams: clients.BucketParams{BucketName: "my-bucket"},
+			},
+			Start:       day(1).Format(time.RFC3339),
+			Stop:        day(4).Add(12 * time.Hour).Format(time.RFC3339),
+			Predicate:   `host="a"`,
+			Chunk:       "1d",
+			Parallelism: 1,
+			Retries:     1,
+			RetryDelay:  time.Millisecond,
+			ResumeFile:  resumeFile,
+		}
+	}
+
+	testCases := []struct {
+		name string
+		// fail returns the status code and error to fail the attempt'th request for the chunk
+		// starting at start with. A zero status code means the request got no response.
+		fail           func(start time.Time, attempt int) (int, error)
+		parallelism    int
+		resumeFrom     time.Time
+		expectedStarts []time.Time
+		expectedErr    string
+		// expectedResume is the completed time left in the resume file, if it's kept.
+		expectedResume time.Time
+	}{
+		{
+			name:           "sequential",
+			expectedStarts: []time.Time{day(1), day(2), day(3), day(4)},
+		},
+		{
+			name:           "parallel",
+			parallelism:    3,
+			expectedStarts: []time.Time{day(1), day(2), day(3), day(4)},
+		},
+		{
+			name: "retried",
+			fail: func(start time.Time, attempt int) (int, error) {
+				if start.Equal(day(2)) && attempt == 0 {
+					return 0, errors.New("timeout")
+				}
+				return 0, nil
+			},
+			expectedStarts: []time.Time{day(1), day(2), day(2), day(3), day(4)},
+		},
+		{
+			name: "server error retried",
+			fail: func(start time.Time, attempt int) (int, error) {
+				if start.Equal(day(2)) && attempt == 0 {
+					return http.StatusServiceUnavailable, errors.New("503 Service Unavailable")
+				}
+				return 0, nil
+			},
+			expectedStarts: []time.Time{day(1), day(2), day(2), day(3), day(4)},
+		},
+		{
+			name: "failed",
+			fail: func(start time.Time, attempt int) (int, error) {
+				if start.Equal(day(3)) {
+					return 0, errors.New("timeout")
+				}
+				return 0, nil
+			},
+			expectedStarts: []time.Time{day(1), day(2), day(3), day(3)},
+			expectedErr:    "failed to delete chunk [2021-01-03T00:00:00Z, 2021-01-04T00:00:00Z): failed to delete data: timeout",
+			expectedResume: day(3),
+		},
+		{
+			name: "invalid request",
+			fail: func(start time.Time, attempt int) (int, error) {
+				if start.Equal(day(2)) {
+					return http.StatusBadRequest, &api.Error{Code: api.ERRORCODE_INVALID}
+				}
+				return 0, nil
+			},
+			expectedStarts: []time.Time{day(1), day(2)},
+			expectedErr:    "failed to delete chunk [2021-01-02T00:00:00Z, 2021-01-03T00:00:00Z): failed to delete data: <invalid>",
+			expectedResume: day(2),
+		},
+		{
+			// A client error whose body couldn't be decoded, for example an HTML page from a proxy.
+			name: "undecodable client error",
+			fail: func(start time.Time, attempt int) (int, error) {
+				if start.Equal(day(2)) {
+					return http.StatusRequestEntityTooLarge, errors.New("413 Request Entity Too Large")
+				}
+				return 0, nil
+			},
+			expectedStarts: []time.Time{day(1), day(2)},
+			expectedErr:    "failed to delete chunk [2021-01-02T00:00:00Z, 2021-01-03T00:00:00Z): failed to delete data: 413 Request Entity Too Large",
+			expectedResume: day(2),
+		},
+		{
+			name:           "resumed",
+			resumeFrom:     day(3),
+			expectedStarts: []time.Time{day(3), day(4)},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			resumeFile := filepath.Join(t.TempDir(), "delete.progress")
+			params := chunkParams(resumeFile)
+			if tc.parallelism > 0 {
+				params.Parallelism = tc.parallelism
+			}
+			if !tc.resumeFrom.IsZero() {
+				progress := `{"bucket":"my-bucket","predicate":"host=\"a\"","start":"2021-01-01T00:00:00Z","stop":"2021-01-04T12:00:00Z","completed":"` +
+					tc.resumeFrom.Format(time.RFC3339) + `"}`
+				require.NoError(t, os.WriteFile(resumeFile, []byte(progress), 0600))
+			}
+
+			var mu sync.Mutex
+			var starts []time.Time
+			attempts := map[time.Time]int{}
+			delApi := mock.NewMockDeleteApi(ctrl)
+			delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi}).AnyTimes()
+			delApi.EXPECT().PostDeleteExecuteWithHttpInfo(gomock.Any()).DoAndReturn(func(req api.ApiPostDeleteRequest) (*http.Response, error) {
+				body := req.GetDeletePredicateRequest()
+				require.Equal(t, `host="a"`, body.GetPredicate())
+				require.True(t, body.GetStop().Sub(body.GetStart()) <= 24*time.Hour)
+				mu.Lock()
+				defer mu.Unlock()
+				starts = append(starts, body.GetStart())
+				attempt := attempts[body.GetStart()]
+				attempts[body.GetStart()]++
+				if tc.fail != nil {
+					if statusCode, err := tc.fail(body.GetStart(), attempt); err != nil {
+						var resp *http.Response
+						if statusCode != 0 {
+							resp = &http.Response{StatusCode: statusCode}
+						}
+						return resp, err
+					}
+				}
+				return &http.Response{StatusCode: http.StatusNoContent}, nil
+			}).AnyTimes()
+
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().WriteErr(gomock.Any()).AnyTimes()
+			client := delete.Client{
+				CLI:       clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-org"}},
+				DeleteApi: delApi,
+			}
+			err := client.Delete(context.Background(), &params)
+
+			sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+			require.Equal(t, tc.expectedStarts, starts)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				contents, err := os.ReadFile(resumeFile)
+				require.NoError(t, err)
+				require.Contains(t, string(contents), `"completed":"`+tc.expectedResume.Format(time.RFC3339)+`"`)
+				return
+			}
+			require.NoError(t, err)
+			require.NoFileExists(t, resumeFile)
+		})
+	}
+
+	for _, chunk := range []string{"-1h", "0s"} {
+		chunk := chunk
+		t.Run("chunk "+chunk, func(t *testing.T) {
+			t.Parallel()
+			params := chunkParams("")
+			params.Chunk = chunk
+			// No requests are expected.
+			client := delete.Client{
+				CLI:       clients.CLI{ActiveConfig: config.Config{Org: "my-org"}},
+				DeleteApi: mock.NewMockDeleteApi(gomock.NewController(t)),
+			}
+			require.EqualError(t, client.Delete(context.Background(), &params), `chunk "`+chunk+`" must be a positive duration`)
+		})
+	}
+}
@@ -2,23 +2,66 @@ package delete
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influx-cli/v2/api"
 	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/pkg/duration"
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
 )
 
 type Client struct {
 	clients.CLI
 	api.DeleteApi
+	// QueryApi is only used by dry runs.
+	api.QueryApi
 }
 
 type Params struct {
 	clients.OrgBucketParams
 	Predicate string
-	Start     string
-	Stop      string
+	// Predicates and the lines of PredicateFile are deleted along with Predicate, each by its
+	// own request. Blank lines and lines starting with # are ignored.
+	Predicates    []string
+	PredicateFile string
+	// Start and Stop are either RFC3339 times, negative durations relative to now, or "now".
+	Start string
+	Stop  string
+	// DryRun reports the series and points matching the predicate instead of deleting them.
+	DryRun bool
+	// Chunk splits the time range into windows of this duration (ex: 1d), each deleted by its
+	// own request.
+	Chunk string
+	// Parallelism is the number of chunks deleted concurrently.
+	Parallelism int
+	// Retries is the number of times a request failing with a server or connection error is
+	// retried, first after RetryDelay, which is doubled after each retry.
+	Retries    int
+	RetryDelay time.Duration
+	// ResumeFile records the chunks that have been deleted, so an interrupted delete can be resumed.
+	ResumeFile string
+}
+
+// PredicateResult reports the outcome of deleting one of several predicates.
+type PredicateResult struct {
+	Predicate string `json:"predicate"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DryRunResult is the data a delete would remove.
+type DryRunResult struct {
+	Start     time.Time `json:"start"`
+	Stop      time.Time `json:"stop"`
+	Predicate string    `json:"predicate"`
+	Series    int64     `json:"series"`
+	Points    int64     `json:"points"`
 }
 
 func (c Client) Delete(ctx context.Context, params *Params) error {
@@ -28,18 +71,161 @@ func (c Client) Delete(ctx context.Context, params *Params) error {
 	if params.BucketID == "" && params.BucketName == "" {
 		return clients.ErrMustSpecifyBucket
 	}
-	start, err := time.Parse(time.RFC3339Nano, params.Start)
+	chunkSize, err := duration.RawDurationToTimeDuration(params.Chunk)
 	if err != nil {
-		return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano: %w", params.Start, err)
+		return fmt.Errorf("chunk %q is not a valid duration: %w", params.Chunk, err)
 	}
-	stop, err := time.Parse(time.RFC3339Nano, params.Stop)
+	if params.Chunk != "" && chunkSize <= 0 {
+		return fmt.Errorf("chunk %q must be a positive duration", params.Chunk)
+	}
+	if params.ResumeFile != "" && chunkSize == 0 {
+		return errors.New("a resume file can only be used with chunked deletes")
+	}
+	if params.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+	now := time.Now().UTC()
+	start, err := duration.ParseTime(params.Start, now)
 	if err != nil {
-		return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano: %w", params.Stop, err)
+		return fmt.Errorf("start time %q cannot be parsed as RFC3339Nano or a negative duration: %w", params.Start, err)
+	}
+	stop, err := duration.ParseTime(params.Stop, now)
+	if err != nil {
+		return fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano or a negative duration: %w", params.Stop, err)
+	}
+	predicates, err := readPredicates(params)
+	if err != nil {
+		return err
+	}
+	// Every predicate is checked before anything is deleted.
+	parsed := make([]Predicate, len(predicates))
+	for i, p := range predicates {
+		if parsed[i], err = ParsePredicate(p); err != nil {
+			return err
+		}
+	}
+	if params.ResumeFile != "" && len(predicates) > 1 {
+		return errors.New("a resume file can only be used with a single predicate")
 	}
 
-	reqBody := api.NewDeletePredicateRequest(start, stop)
+	if params.DryRun {
+		return c.dryRun(ctx, params, start, stop, predicates, parsed)
+	}
+	if len(predicates) <= 1 {
+		predicate := ""
+		if len(predicates) == 1 {
+			predicate = predicates[0]
+		}
+		return c.deletePredicate(ctx, params, predicate, start, stop, chunkSize)
+	}
+
+	results := make([]PredicateResult, len(predicates))
+	var failed int
+	for i, p := range predicates {
+		results[i] = PredicateResult{Predicate: p, Success: true}
+		if err := c.deletePredicate(ctx, params, p, start, stop, chunkSize); err != nil {
+			results[i].Success = false
+			results[i].Error = err.Error()
+			failed++
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err := c.printPredicateResults(results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d predicates", failed, len(predicates))
+	}
+	return nil
+}
+
+func (c Client) deletePredicate(ctx context.Context, params *Params, predicate string, start, stop time.Time, chunkSize time.Duration) error {
+	if chunkSize == 0 {
+		return c.deleteWithRetry(ctx, params, predicate, start, stop)
+	}
+	return c.deleteChunks(ctx, params, predicate, start, stop, chunkSize)
+}
+
+// readPredicates returns all predicates given by params.
+func readPredicates(params *Params) ([]string, error) {
+	var predicates []string
 	if params.Predicate != "" {
-		reqBody.SetPredicate(params.Predicate)
+		predicates = append(predicates, params.Predicate)
+	}
+	predicates = append(predicates, params.Predicates...)
+	if params.PredicateFile != "" {
+		contents, err := os.ReadFile(params.PredicateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read predicate file %q: %w", params.PredicateFile, err)
+		}
+		for _, line := range strings.Split(string(contents), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			predicates = append(predicates, line)
+		}
+		if len(predicates) == 0 {
+			return nil, fmt.Errorf("predicate file %q doesn't contain any predicates", params.PredicateFile)
+		}
+	}
+	return predicates, nil
+}
+
+func (c Client) printPredicateResults(results []PredicateResult) error {
+	if c.PrintAsJSON {
+		return c.PrintJSON(results)
+	}
+	rows := make([]map[string]interface{}, len(results))
+	for i, r := range results {
+		status := "deleted"
+		if !r.Success {
+			status = "failed"
+		}
+		rows[i] = map[string]interface{}{
+			"Predicate": r.Predicate,
+			"Status":    status,
+			"Error":     r.Error,
+		}
+	}
+	return c.PrintTable([]string{"Predicate", "Status", "Error"}, rows...)
+}
+
+// deleteWithRetry deletes the data between start and stop, retrying requests failing with
+// retryable errors up to params.Retries times.
+func (c Client) deleteWithRetry(ctx context.Context, params *Params, predicate string, start, stop time.Time) error {
+	delay := params.RetryDelay
+	for attempt := 0; ; attempt++ {
+		statusCode, err := c.deleteRange(ctx, params, predicate, start, stop)
+		if err == nil || attempt >= params.Retries || ctx.Err() != nil || !isRetryable(statusCode) {
+			return err
+		}
+		_, _ = c.StdIO.WriteErr([]byte(fmt.Sprintf("Retrying delete of [%s, %s) in %s: %v\n",
+			start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano), delay, err)))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether a request that failed with the given HTTP status code might
+// succeed if it's sent again. Requests that didn't get a response and server errors are
+// retryable, but errors in the request itself aren't.
+func isRetryable(statusCode int) bool {
+	return statusCode == 0 || statusCode >= http.StatusInternalServerError
+}
+
+// deleteRange deletes the data between start and stop, and returns the status code of the
+// response, or 0 if the request didn't get one.
+func (c Client) deleteRange(ctx context.Context, params *Params, predicate string, start, stop time.Time) (int, error) {
+	reqBody := api.NewDeletePredicateRequest(start, stop)
+	if predicate != "" {
+		reqBody.SetPredicate(predicate)
 	}
 
 	req := c.PostDelete(ctx).DeletePredicateRequest(*reqBody)
@@ -56,8 +242,69 @@ func (c Client) Delete(ctx context.Context, params *Params) error {
 		req = req.Bucket(params.BucketName)
 	}
 
-	if err := req.Execute(); err != nil {
-		return fmt.Errorf("failed to delete data: %w", err)
+	resp, err := req.ExecuteWithHttpInfo()
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
 	}
-	return nil
+	if err != nil {
+		return statusCode, fmt.Errorf("failed to delete data: %w", err)
+	}
+	return statusCode, nil
+}
+
+// dryRun counts the series and points a delete of each predicate would remove.
+func (c Client) dryRun(ctx context.Context, params *Params, start, stop time.Time, predicates []string, parsed []Predicate) error {
+	if len(predicates) == 0 {
+		predicates, parsed = []string{""}, []Predicate{nil}
+	}
+	results := make([]DryRunResult, len(predicates))
+	for i := range predicates {
+		res, err := c.countPredicate(ctx, params, start, stop, parsed[i])
+		if err != nil {
+			return fmt.Errorf("failed to count data to delete for predicate %q: %w", predicates[i], err)
+		}
+		res.Predicate = predicates[i]
+		results[i] = res
+	}
+
+	if c.PrintAsJSON {
+		return c.PrintJSON(results)
+	}
+	rows := make([]map[string]interface{}, len(results))
+	for i, res := range results {
+		rows[i] = map[string]interface{}{
+			"Start":     res.Start.Format(time.RFC3339Nano),
+			"Stop":      res.Stop.Format(time.RFC3339Nano),
+			"Predicate": res.Predicate,
+			"Series":    res.Series,
+			"Points":    res.Points,
+		}
+	}
+	return c.PrintTable([]string{"Start", "Stop", "Predicate", "Series", "Points"}, rows...)
+}
+
+func (c Client) countPredicate(ctx context.Context, params *Params, start, stop time.Time, predicate Predicate) (DryRunResult, error) {
+	bucket := "bucket: " + query.StringLiteral(params.BucketName)
+	if params.BucketID != "" {
+		bucket = "bucketID: " + query.StringLiteral(params.BucketID)
+	}
+	flux := fmt.Sprintf("from(%s) |> range(start: %s, stop: %s) |> filter(fn: %s) |> count()",
+		bucket, start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano), predicate.FluxFilter())
+	res := DryRunResult{Start: start, Stop: stop}
+	body, err := query.PostQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, query.BuildDefaultAST(flux))
+	if err != nil {
+		return res, err
+	}
+	result := fluxcsv.NewQueryTableResult(body)
+	defer result.Close()
+
+	// count() returns one row per series.
+	for result.Next() {
+		if n, ok := result.Record().Value().(int64); ok {
+			res.Series++
+			res.Points += n
+		}
+	}
+	return res, result.Err()
 }
@@ -1,7 +1,15 @@
 package delete_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,7 +57,7 @@ func TestClient_Delete(t *testing.T) {
 			defaultOrgName: "my-default-org",
 			registerExpectations: func(t *testing.T, delApi *mock.MockDeleteApi) {
 				delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi})
-				delApi.EXPECT().PostDeleteExecute(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
+				delApi.EXPECT().PostDeleteExecuteWithHttpInfo(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
 					body := in.GetDeletePredicateRequest()
 					return assert.NotNil(t, body) &&
 						assert.Equal(t, id1, *in.GetOrgID()) &&
@@ -59,7 +67,7 @@ func TestClient_Delete(t *testing.T) {
 						assert.Equal(t, start, body.GetStart()) &&
 						assert.Equal(t, stop, body.GetStop()) &&
 						assert.Nil(t, body.Predicate)
-				})).Return(nil)
+				})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
 			},
 		},
 		{
@@ -79,7 +87,7 @@ func TestClient_Delete(t *testing.T) {
 			defaultOrgName: "my-default-org",
 			registerExpectations: func(t *testing.T, delApi *mock.MockDeleteApi) {
 				delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi})
-				delApi.EXPECT().PostDeleteExecute(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
+				delApi.EXPECT().PostDeleteExecuteWithHttpInfo(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
 					body := in.GetDeletePredicateRequest()
 					return assert.NotNil(t, body) &&
 						assert.Equal(t, id1, *in.GetOrgID()) &&
@@ -89,7 +97,7 @@ func TestClient_Delete(t *testing.T) {
 						assert.Equal(t, start, body.GetStart()) &&
 						assert.Equal(t, stop, body.GetStop()) &&
 						assert.Nil(t, body.Predicate)
-				})).Return(nil)
+				})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
 			},
 		},
 		{
@@ -110,7 +118,7 @@ func TestClient_Delete(t *testing.T) {
 			defaultOrgName: "my-default-org",
 			registerExpectations: func(t *testing.T, delApi *mock.MockDeleteApi) {
 				delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi})
-				delApi.EXPECT().PostDeleteExecute(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
+				delApi.EXPECT().PostDeleteExecuteWithHttpInfo(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
 					body := in.GetDeletePredicateRequest()
 					return assert.NotNil(t, body) &&
 						assert.Equal(t, "my-org", *in.GetOrg()) &&
@@ -120,7 +128,7 @@ func TestClient_Delete(t *testing.T) {
 						assert.Equal(t, start, body.GetStart()) &&
 						assert.Equal(t, stop, body.GetStop()) &&
 						assert.Equal(t, `foo = "bar"`, body.GetPredicate())
-				})).Return(nil)
+				})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
 			},
 		},
 		{
@@ -139,7 +147,7 @@ func TestClient_Delete(t *testing.T) {
 			defaultOrgName: "my-default-org",
 			registerExpectations: func(t *testing.T, delApi *mock.MockDeleteApi) {
 				delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi})
-				delApi.EXPECT().PostDeleteExecute(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
+				delApi.EXPECT().PostDeleteExecuteWithHttpInfo(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
 					body := in.GetDeletePredicateRequest()
 					return assert.NotNil(t, body) &&
 						assert.Equal(t, "my-default-org", *in.GetOrg()) &&
@@ -149,9 +157,46 @@ func TestClient_Delete(t *testing.T) {
 						assert.Equal(t, start, body.GetStart()) &&
 						assert.Equal(t, stop, body.GetStop()) &&
 						assert.Equal(t, `foo = "bar"`, body.GetPredicate())
-				})).Return(nil)
+				})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
 			},
 		},
+		{
+			name: "relative times",
+			params: delete.Params{
+				OrgBucketParams: clients.OrgBucketParams{
+					BucketParams: clients.BucketParams{
+						BucketName: "my-bucket",
+					},
+				},
+				Start: "-7d",
+				Stop:  "now",
+			},
+			defaultOrgName: "my-default-org",
+			registerExpectations: func(t *testing.T, delApi *mock.MockDeleteApi) {
+				delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi})
+				delApi.EXPECT().PostDeleteExecuteWithHttpInfo(tmock.MatchedBy(func(in api.ApiPostDeleteRequest) bool {
+					body := in.GetDeletePredicateRequest()
+					return assert.NotNil(t, body) &&
+						assert.Equal(t, 7*24*time.Hour, body.GetStop().Sub(body.GetStart())) &&
+						assert.WithinDuration(t, time.Now(), body.GetStop(), time.Minute)
+				})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
+			},
+		},
+		{
+			name: "invalid predicate",
+			params: delete.Params{
+				OrgBucketParams: clients.OrgBucketParams{
+					BucketParams: clients.BucketParams{
+						BucketName: "my-bucket",
+					},
+				},
+				Start:     start.Format(time.RFC3339Nano),
+				Stop:      stop.Format(time.RFC3339Nano),
+				Predicate: `foo = "bar" or baz = "qux"`,
+			},
+			defaultOrgName: "my-default-org",
+			expectedErr:    "OR at position 12 is not supported by delete",
+		},
 		{
 			name:        "no org",
 			expectedErr: clients.ErrMustSpecifyOrg.Error(),
@@ -221,3 +266,104 @@ func TestClient_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_DeleteDryRun(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	queryApi := mock.NewMockQueryApi(ctrl)
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi})
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+			require.Equal(t, "my-default-org", *req.GetOrg())
+			require.Equal(t,
+				`from(bucket: "my-bucket") |> range(start: 2020-01-01T00:00:00Z, stop: 2021-01-01T00:00:00Z) |> filter(fn: (r) => r["_measurement"] == "cpu" and r["host"] == "a") |> count()`,
+				req.GetQuery().Query)
+			csv := "#group,false,false,true,true,false\n" +
+				"#datatype,string,long,string,string,long\n" +
+				"#default,_result,,,,\n" +
+				",result,table,_measurement,_field,_value\n" +
+				",,0,cpu,usage_user,10\n" +
+				",,1,cpu,usage_system,15\n"
+			return &http.Response{Body: io.NopCloser(strings.NewReader(csv))}, nil
+		})
+
+	stdout := bytes.Buffer{}
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+
+	client := delete.Client{
+		CLI:       clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-default-org"}, PrintAsJSON: true},
+		DeleteApi: mock.NewMockDeleteApi(ctrl),
+		QueryApi:  queryApi,
+	}
+	params := delete.Params{
+		OrgBucketParams: clients.OrgBucketParams{
+			BucketParams: clients.BucketParams{BucketName: "my-bucket"},
+		},
+		Start:     "2020-01-01T00:00:00Z",
+		Stop:      "2021-01-01T00:00:00Z",
+		Predicate: `_measurement="cpu" AND host="a"`,
+		DryRun:    true,
+	}
+	require.NoError(t, client.Delete(context.Background(), &params))
+
+	var res []delete.DryRunResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Len(t, res, 1)
+	require.Equal(t, int64(2), res[0].Series)
+	require.Equal(t, int64(25), res[0].Points)
+}
+
+func TestClient_DeletePredicates(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	predicateFile := filepath.Join(t.TempDir(), "preds.txt")
+	require.NoError(t, os.WriteFile(predicateFile, []byte("# users to forget\nuser=\"carol\"\n\nuser=\"dave\"\n"), 0600))
+
+	var deleted []string
+	delApi := mock.NewMockDeleteApi(ctrl)
+	delApi.EXPECT().PostDelete(gomock.Any()).Return(api.ApiPostDeleteRequest{ApiService: delApi}).Times(4)
+	delApi.EXPECT().PostDeleteExecuteWithHttpInfo(gomock.Any()).DoAndReturn(func(req api.ApiPostDeleteRequest) (*http.Response, error) {
+		predicate := req.GetDeletePredicateRequest().GetPredicate()
+		deleted = append(deleted, predicate)
+		if predicate == `user="bob"` {
+			return nil, errors.New("timeout")
+		}
+		return &http.Response{StatusCode: http.StatusNoContent}, nil
+	}).Times(4)
+
+	stdout := bytes.Buffer{}
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+
+	client := delete.Client{
+		CLI:       clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-default-org"}, PrintAsJSON: true},
+		DeleteApi: delApi,
+	}
+	params := delete.Params{
+		OrgBucketParams: clients.OrgBucketParams{
+			BucketParams: clients.BucketParams{BucketName: "my-bucket"},
+		},
+		Start:         "2020-01-01T00:00:00Z",
+		Stop:          "2021-01-01T00:00:00Z",
+		Predicates:    []string{`user="alice"`, `user="bob"`},
+		PredicateFile: predicateFile,
+	}
+	require.EqualError(t, client.Delete(context.Background(), &params), "failed to delete 1 of 4 predicates")
+	require.Equal(t, []string{`user="alice"`, `user="bob"`, `user="carol"`, `user="dave"`}, deleted)
+
+	var results []delete.PredicateResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+	require.Equal(t, []delete.PredicateResult{
+		{Predicate: `user="alice"`, Success: true},
+		{Predicate: `user="bob"`, Error: "failed to delete data: timeout"},
+		{Predicate: `user="carol"`, Success: true},
+		{Predicate: `user="dave"`, Success: true},
+	}, results)
+
+	// An invalid predicate stops the delete before anything is deleted.
+	params.Predicates = append(params.Predicates, `user="eve" or user="mallory"`)
+	require.ErrorContains(t, client.Delete(context.Background(), &params), "OR at position 11 is not supported by delete")
+}
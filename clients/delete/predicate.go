@@ -0,0 +1,237 @@
+package delete
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/influxdata/influx-cli/v2/clients/query"
+)
+
+// Predicate is a parsed delete predicate. The delete API only supports comparisons of tags
+// (or _measurement) with values, combined with AND, so a predicate is the list of its
+// comparisons.
+type Predicate []Comparison
+
+type Comparison struct {
+	Key string
+	// Op is either "=" or "!=".
+	Op    string
+	Value string
+}
+
+// ParsePredicate parses a delete predicate like `_measurement="cpu" AND (host="a" AND region!="us")`,
+// returning an error describing the first problem found. An empty predicate matches all series.
+func ParsePredicate(s string) (Predicate, error) {
+	p := predicateParser{input: s}
+	p.next()
+	if p.tok.kind == tokEOF {
+		return nil, nil
+	}
+	pred, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate %q: %w", s, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("invalid predicate %q: %w", s, p.unexpected())
+	}
+	return pred, nil
+}
+
+// FluxFilter returns a Flux predicate function matching the same series as p.
+func (p Predicate) FluxFilter() string {
+	if len(p) == 0 {
+		return "(r) => true"
+	}
+	exprs := make([]string, len(p))
+	for i, c := range p {
+		key, value := query.StringLiteral(c.Key), query.StringLiteral(c.Value)
+		if c.Op == "!=" {
+			// Series without the tag don't equal any value.
+			exprs[i] = fmt.Sprintf("(not exists r[%s] or r[%s] != %s)", key, key, value)
+		} else {
+			exprs[i] = fmt.Sprintf("r[%s] == %s", key, value)
+		}
+	}
+	return "(r) => " + strings.Join(exprs, " and ")
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokAnd
+	tokOr
+	tokLParen
+	tokRParen
+	tokOp
+	tokIllegal
+)
+
+type token struct {
+	kind tokenKind
+	// text is the unquoted text of identifiers and strings, and the source of other tokens.
+	text string
+	pos  int
+}
+
+type predicateParser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+// parseExpr parses comparisons and parenthesized expressions combined with AND.
+func (p *predicateParser) parseExpr() (Predicate, error) {
+	var pred Predicate
+	for {
+		switch p.tok.kind {
+		case tokLParen:
+			p.next()
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokRParen {
+				return nil, fmt.Errorf("expected ')' at position %d", p.tok.pos)
+			}
+			p.next()
+			pred = append(pred, inner...)
+		case tokIdent, tokString:
+			c, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			pred = append(pred, c)
+		default:
+			return nil, p.unexpected()
+		}
+
+		switch p.tok.kind {
+		case tokAnd:
+			p.next()
+		case tokOr:
+			return nil, fmt.Errorf("OR at position %d is not supported by delete, use separate predicates instead", p.tok.pos)
+		default:
+			return pred, nil
+		}
+	}
+}
+
+func (p *predicateParser) parseComparison() (Comparison, error) {
+	c := Comparison{Key: p.tok.text}
+	p.next()
+	if p.tok.kind != tokOp {
+		return c, fmt.Errorf("expected '=' or '!=' after %q at position %d", c.Key, p.tok.pos)
+	}
+	if p.tok.text != "=" && p.tok.text != "!=" {
+		return c, fmt.Errorf("operator %q at position %d is not supported by delete, only '=' and '!=' are", p.tok.text, p.tok.pos)
+	}
+	c.Op = p.tok.text
+	p.next()
+	if p.tok.kind == tokIllegal {
+		return c, p.unexpected()
+	}
+	if p.tok.kind != tokString && p.tok.kind != tokIdent {
+		return c, fmt.Errorf("expected a value for %q at position %d", c.Key, p.tok.pos)
+	}
+	c.Value = p.tok.text
+	p.next()
+	return c, nil
+}
+
+func (p *predicateParser) unexpected() error {
+	switch p.tok.kind {
+	case tokEOF:
+		return fmt.Errorf("unexpected end of predicate at position %d", p.tok.pos)
+	case tokIllegal:
+		return fmt.Errorf("%s at position %d", p.tok.text, p.tok.pos)
+	default:
+		return fmt.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos)
+	}
+}
+
+// next scans the next token into p.tok.
+func (p *predicateParser) next() {
+	for p.pos < len(p.input) {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	switch ch := p.input[p.pos]; {
+	case ch == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case ch == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case ch == '"' || ch == '\'':
+		p.tok = p.scanString(ch)
+	case strings.ContainsRune("=!<>~", rune(ch)):
+		for p.pos < len(p.input) && strings.ContainsRune("=!<>~", rune(p.input[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokOp, text: p.input[start:p.pos], pos: start}
+	default:
+		for p.pos < len(p.input) {
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if !isIdentRune(r) {
+				break
+			}
+			p.pos += size
+		}
+		if p.pos == start {
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			p.pos += size
+			p.tok = token{kind: tokIllegal, text: fmt.Sprintf("unexpected character %q", r), pos: start}
+			return
+		}
+		text := p.input[start:p.pos]
+		switch strings.ToLower(text) {
+		case "and":
+			p.tok = token{kind: tokAnd, text: text, pos: start}
+		case "or":
+			p.tok = token{kind: tokOr, text: text, pos: start}
+		default:
+			p.tok = token{kind: tokIdent, text: text, pos: start}
+		}
+	}
+}
+
+// scanString scans a string delimited by quote, in which quote and backslash can be
+// escaped with a backslash.
+func (p *predicateParser) scanString(quote byte) token {
+	start := p.pos
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		switch {
+		case ch == quote:
+			p.pos++
+			return token{kind: tokString, text: sb.String(), pos: start}
+		case ch == '\\' && p.pos+1 < len(p.input) && (p.input[p.pos+1] == quote || p.input[p.pos+1] == '\\'):
+			sb.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		default:
+			sb.WriteByte(ch)
+			p.pos++
+		}
+	}
+	return token{kind: tokIllegal, text: "unterminated string", pos: start}
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
@@ -0,0 +1,92 @@
+package delete_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influx-cli/v2/clients/delete"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePredicate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		in           string
+		expected     delete.Predicate
+		expectedFlux string
+		expectedErr  string
+	}{
+		{
+			name:         "empty",
+			in:           "  ",
+			expectedFlux: "(r) => true",
+		},
+		{
+			name:         "single comparison",
+			in:           `_measurement="cpu"`,
+			expected:     delete.Predicate{{Key: "_measurement", Op: "=", Value: "cpu"}},
+			expectedFlux: `(r) => r["_measurement"] == "cpu"`,
+		},
+		{
+			name: "nested and",
+			in:   `tag1="v1" and (tag2=123 AND "tag 3" != 'it\'s')`,
+			expected: delete.Predicate{
+				{Key: "tag1", Op: "=", Value: "v1"},
+				{Key: "tag2", Op: "=", Value: "123"},
+				{Key: "tag 3", Op: "!=", Value: "it's"},
+			},
+			expectedFlux: `(r) => r["tag1"] == "v1" and r["tag2"] == "123" and (not exists r["tag 3"] or r["tag 3"] != "it's")`,
+		},
+		{
+			name:        "or",
+			in:          `a="1" OR b="2"`,
+			expectedErr: `invalid predicate "a=\"1\" OR b=\"2\"": OR at position 6 is not supported by delete, use separate predicates instead`,
+		},
+		{
+			name:        "regex",
+			in:          `a=~/x/`,
+			expectedErr: `invalid predicate "a=~/x/": operator "=~" at position 1 is not supported by delete, only '=' and '!=' are`,
+		},
+		{
+			name:        "missing value",
+			in:          `a=`,
+			expectedErr: `invalid predicate "a=": expected a value for "a" at position 2`,
+		},
+		{
+			name:        "unbalanced parens",
+			in:          `(a="1"`,
+			expectedErr: `invalid predicate "(a=\"1\"": expected ')' at position 6`,
+		},
+		{
+			name:        "trailing and",
+			in:          `a="1" and`,
+			expectedErr: `invalid predicate "a=\"1\" and": unexpected end of predicate at position 9`,
+		},
+		{
+			name:        "unterminated string",
+			in:          `a="1`,
+			expectedErr: `invalid predicate "a=\"1": unterminated string at position 2`,
+		},
+		{
+			name:        "trailing tokens",
+			in:          `a="1" b="2"`,
+			expectedErr: `invalid predicate "a=\"1\" b=\"2\"": unexpected "b" at position 6`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pred, err := delete.ParsePredicate(tc.in)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, pred)
+			require.Equal(t, tc.expectedFlux, pred.FluxFilter())
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type annotatedRowKind int
+
+const (
+	annotationRow annotatedRowKind = iota
+	headerRow
+	dataRow
+)
+
+// annotatedCSVReader reads raw rows from an annotated CSV stream, classifying each
+// one as it's read. It's used by printers that need to inspect or rewrite query
+// results without fully parsing them.
+type annotatedCSVReader struct {
+	r *csv.Reader
+
+	inAnnotations bool
+	headerSeen    bool
+}
+
+func newAnnotatedCSVReader(in io.Reader) *annotatedCSVReader {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	return &annotatedCSVReader{r: r}
+}
+
+// Read returns the next non-empty row in the stream. The returned bool is true if
+// the row is the first annotation of a new table.
+func (a *annotatedCSVReader) Read() ([]string, annotatedRowKind, bool, error) {
+	for {
+		row, err := a.r.Read()
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if len(row) <= 1 {
+			continue
+		}
+
+		if strings.HasPrefix(row[0], "#") {
+			newTable := !a.inAnnotations
+			a.inAnnotations, a.headerSeen = true, false
+			return row, annotationRow, newTable, nil
+		}
+		a.inAnnotations = false
+		if !a.headerSeen {
+			a.headerSeen = true
+			return row, headerRow, false, nil
+		}
+		return row, dataRow, false, nil
+	}
+}
@@ -0,0 +1,246 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+)
+
+const cacheFileExt = ".csv"
+
+// ResultCache stores the raw annotated CSV returned by queries on local disk, so repeated
+// identical queries can be answered without contacting the server. It's most useful for
+// queries over closed time ranges, where results never change.
+type ResultCache struct {
+	// Dir is the directory cached results are stored in.
+	Dir string
+	// TTL is how long cached results are served for.
+	TTL time.Duration
+	// Host is the URL of the server results are cached for.
+	Host string
+	// User identifies the credentials results are cached for, such as the API token, so
+	// results are never served to another user of the same server. Only a hash of it ends
+	// up on disk.
+	User string
+}
+
+// DefaultCacheDir computes where cached query results are stored, next to the config
+// store at configPath.
+func DefaultCacheDir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "query-cache")
+}
+
+// cacheKey identifies the results of query in org for the cache's host and user. Queries
+// are normalized first, so formatting changes don't cause cache misses.
+func (rc *ResultCache) cacheKey(org string, query api.Query) (string, error) {
+	keyJSON, err := json.Marshal(map[string]interface{}{
+		"host":   rc.Host,
+		"user":   rc.User,
+		"org":    org,
+		"query":  normalizeQuery(query.Query),
+		"extern": query.Extern,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(keyJSON)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeQuery collapses whitespace that includes a line break into a single newline,
+// and trims the query. Whitespace within a line and within string literals is left as-is,
+// since it can be significant.
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	pendingSpace, pendingNewline := "", false
+	for _, r := range strings.TrimSpace(query) {
+		if !inString && (r == ' ' || r == '\t' || r == '\r' || r == '\n') {
+			if r == '\n' {
+				pendingNewline = true
+			}
+			pendingSpace += string(r)
+			continue
+		}
+		if pendingNewline {
+			b.WriteByte('\n')
+		} else {
+			b.WriteString(pendingSpace)
+		}
+		pendingSpace, pendingNewline = "", false
+
+		b.WriteRune(r)
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		}
+	}
+	return b.String()
+}
+
+// get opens the results cached under key, if they haven't expired.
+func (rc *ResultCache) get(key string) (io.ReadCloser, bool) {
+	path := filepath.Join(rc.Dir, key+cacheFileExt)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > rc.TTL {
+		return nil, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// cacheEntry writes results to a temporary file, which is only moved into place
+// once the full results have been read.
+type cacheEntry struct {
+	f    *os.File
+	path string
+}
+
+func (rc *ResultCache) create(key string) (*cacheEntry, error) {
+	if err := os.MkdirAll(rc.Dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(rc.Dir, key+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &cacheEntry{f: f, path: filepath.Join(rc.Dir, key+cacheFileExt)}, nil
+}
+
+func (e *cacheEntry) commit() error {
+	if err := e.f.Close(); err != nil {
+		_ = os.Remove(e.f.Name())
+		return err
+	}
+	return os.Rename(e.f.Name(), e.path)
+}
+
+func (e *cacheEntry) abort() {
+	_ = e.f.Close()
+	_ = os.Remove(e.f.Name())
+}
+
+// teeReadCloser copies everything read from r to w, tracking whether r was read to the end.
+type teeReadCloser struct {
+	r      io.ReadCloser
+	w      io.Writer
+	eof    bool
+	failed bool
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 && !t.failed {
+		if _, werr := t.w.Write(p[:n]); werr != nil {
+			// Caching is best-effort, don't fail the query because of it.
+			t.failed = true
+		}
+	}
+	if err == io.EOF {
+		t.eof = true
+	}
+	return n, err
+}
+
+func (t *teeReadCloser) Close() error {
+	return t.r.Close()
+}
+
+// cachedQuery prints the results of query from the cache if possible. Otherwise, the query
+// is executed and its results are cached as they're printed.
+//
+// Results are only cached if they were printed in full, so output cut short by errors or
+// row limits never ends up in the cache.
+func (c Client) cachedQuery(ctx context.Context, params *Params, query api.Query, out io.Writer) error {
+	org := params.OrgName
+	if params.OrgID != "" {
+		org = "id:" + params.OrgID
+	} else if org == "" {
+		org = c.ActiveConfig.Org
+	}
+	key, err := c.Cache.cacheKey(org, query)
+	if err != nil {
+		return err
+	}
+	if cached, ok := c.Cache.get(key); ok {
+		defer cached.Close()
+		return c.PrintQueryResults(cached, out)
+	}
+
+	respBody, err := PostQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, query)
+	if err != nil {
+		return err
+	}
+	defer respBody.Close()
+
+	entry, err := c.Cache.create(key)
+	if err != nil {
+		return c.PrintQueryResults(respBody, out)
+	}
+	body := &teeReadCloser{r: respBody, w: entry.f}
+	if err := c.PrintQueryResults(body, out); err != nil {
+		entry.abort()
+		return err
+	}
+	if !body.eof || body.failed {
+		entry.abort()
+		return nil
+	}
+	// A failure to cache results doesn't affect the output, so it isn't reported.
+	_ = entry.commit()
+	return nil
+}
+
+// Prune removes cached results older than olderThan, or all cached results if olderThan is zero.
+// It returns the number of results removed.
+func (rc *ResultCache) Prune(olderThan time.Duration) (int, error) {
+	entries, err := os.ReadDir(rc.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return removed, err
+		}
+		isResult := strings.HasSuffix(e.Name(), cacheFileExt)
+		// Leftover temporary files are always removed.
+		if isResult && olderThan > 0 && time.Since(info.ModTime()) < olderThan {
+			continue
+		}
+		if !isResult && !strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(rc.Dir, e.Name())); err != nil {
+			return removed, err
+		}
+		if isResult {
+			removed++
+		}
+	}
+	return removed, nil
+}
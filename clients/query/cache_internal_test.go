@@ -0,0 +1,46 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{
+			name:     "trims",
+			in:       "\n  from(bucket: \"b\")  \n",
+			expected: `from(bucket: "b")`,
+		},
+		{
+			name:     "collapses line breaks",
+			in:       "from(bucket: \"b\")\n\n\t  |> range(start: -1h)\r\n  |> count()",
+			expected: "from(bucket: \"b\")\n|> range(start: -1h)\n|> count()",
+		},
+		{
+			name:     "keeps whitespace within lines",
+			in:       "x = 1  +  2",
+			expected: "x = 1  +  2",
+		},
+		{
+			name:     "keeps string literals",
+			in:       "x = \"a\n   \\\"b\\\"\n  c\"\n  y = 1",
+			expected: "x = \"a\n   \\\"b\\\"\n  c\"\ny = 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, normalizeQuery(tc.in))
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+type ChartStyle int
+
+const (
+	UnicodeChart ChartStyle = iota
+	ASCIIChart
+)
+
+func (s *ChartStyle) Set(v string) error {
+	switch v {
+	case "unicode":
+		*s = UnicodeChart
+	case "ascii":
+		*s = ASCIIChart
+	default:
+		return fmt.Errorf("unsupported chart style: %q", v)
+	}
+	return nil
+}
+
+func (s ChartStyle) String() string {
+	switch s {
+	case UnicodeChart:
+		return "unicode"
+	case ASCIIChart:
+		return "ascii"
+	default:
+		panic("Impossible!")
+	}
+}
+
+const (
+	DefaultChartWidth  = 80
+	DefaultChartHeight = 15
+
+	// minPlotWidth is the narrowest plot area we'll draw, regardless of the
+	// requested chart width.
+	minPlotWidth  = 10
+	minPlotHeight = 3
+)
+
+// chartGlyphs holds the characters used to draw a chart in a given style.
+type chartGlyphs struct {
+	markers    []rune
+	axis       rune
+	axisTick   rune
+	axisCorner rune
+	axisLine   rune
+}
+
+var glyphsByStyle = map[ChartStyle]chartGlyphs{
+	UnicodeChart: {
+		markers:    []rune{'●', '▲', '■', '◆', '○', '△', '□', '◇'},
+		axis:       '│',
+		axisTick:   '┤',
+		axisCorner: '└',
+		axisLine:   '─',
+	},
+	ASCIIChart: {
+		markers:    []rune{'*', 'o', 'x', '#', '@', '%', '&', '='},
+		axis:       '|',
+		axisTick:   '+',
+		axisCorner: '+',
+		axisLine:   '-',
+	},
+}
+
+// chartPrinter renders numeric _value columns of query results as line charts over _time,
+// drawing one chart per result and one series per table (group key).
+type chartPrinter struct {
+	style  ChartStyle
+	width  int
+	height int
+}
+
+func NewChartPrinter(style ChartStyle, width, height int) *chartPrinter {
+	if width <= 0 {
+		width = DefaultChartWidth
+	}
+	if height <= 0 {
+		height = DefaultChartHeight
+	}
+	return &chartPrinter{style: style, width: width, height: height}
+}
+
+type chartPoint struct {
+	t time.Time
+	v float64
+}
+
+type chartSeries struct {
+	label  string
+	points []chartPoint
+}
+
+func (c *chartPrinter) PrintQueryResults(resultStream io.ReadCloser, out io.Writer) error {
+	res := fluxcsv.NewQueryTableResult(resultStream)
+	defer res.Close()
+
+	var resultName string
+	var series []*chartSeries
+	var current *chartSeries
+	for res.Next() {
+		record := res.Record()
+
+		if res.ResultChanged() && current != nil {
+			if err := c.render(out, resultName, series); err != nil {
+				return err
+			}
+			series, current = nil, nil
+		}
+		if res.ResultChanged() || res.TableIdChanged() || res.AnnotationsChanged() || current == nil {
+			resultName = record.Result()
+			current = &chartSeries{label: seriesLabel(res.Metadata(), record)}
+			series = append(series, current)
+		}
+
+		v, ok := numericValue(record.Value())
+		if !ok || record.Time().IsZero() {
+			continue
+		}
+		current.points = append(current.points, chartPoint{t: record.Time(), v: v})
+	}
+	if err := res.Err(); err != nil {
+		return err
+	}
+	if current != nil {
+		return c.render(out, resultName, series)
+	}
+	return nil
+}
+
+// seriesLabel builds a legend entry from the group key of the table containing record.
+// The _start and _stop columns are left out because they're shared by all tables in
+// typical range queries, and only add noise.
+func seriesLabel(metadata *fluxcsv.FluxTableMetadata, record *fluxcsv.FluxRecord) string {
+	var parts []string
+	for _, k := range metadata.GroupKeyCols() {
+		if k == "_start" || k == "_stop" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", k, record.ValueByKey(k)))
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("table %d", record.TableId())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// render draws a single chart containing all series with at least one point.
+func (c *chartPrinter) render(out io.Writer, resultName string, allSeries []*chartSeries) error {
+	w := &writeHelper{w: out}
+	w.write([]byte("Result: " + resultName + "\n"))
+
+	var series []*chartSeries
+	for _, s := range allSeries {
+		if len(s.points) > 0 {
+			sort.Slice(s.points, func(i, j int) bool { return s.points[i].t.Before(s.points[j].t) })
+			series = append(series, s)
+		}
+	}
+	if len(series) == 0 {
+		w.write([]byte("No numeric _value data to chart\n\n"))
+		return w.err
+	}
+
+	tMin, tMax := series[0].points[0].t, series[0].points[0].t
+	vMin, vMax := series[0].points[0].v, series[0].points[0].v
+	for _, s := range series {
+		for _, p := range s.points {
+			if p.t.Before(tMin) {
+				tMin = p.t
+			}
+			if p.t.After(tMax) {
+				tMax = p.t
+			}
+			vMin = math.Min(vMin, p.v)
+			vMax = math.Max(vMax, p.v)
+		}
+	}
+	if vMin == vMax {
+		// Give flat lines some room so they're drawn in the middle of the chart.
+		pad := math.Abs(vMin) * 0.1
+		if pad == 0 {
+			pad = 1
+		}
+		vMin, vMax = vMin-pad, vMax+pad
+	}
+
+	height := c.height
+	if height < minPlotHeight {
+		height = minPlotHeight
+	}
+	yLabels := make([]string, height)
+	labelWidth := 0
+	for _, r := range []int{0, (height - 1) / 2, height - 1} {
+		v := vMax - (vMax-vMin)*float64(r)/float64(height-1)
+		yLabels[r] = strconv.FormatFloat(v, 'g', 6, 64)
+		if len(yLabels[r]) > labelWidth {
+			labelWidth = len(yLabels[r])
+		}
+	}
+	plotWidth := c.width - labelWidth - 2
+	if plotWidth < minPlotWidth {
+		plotWidth = minPlotWidth
+	}
+
+	glyphs := glyphsByStyle[c.style]
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", plotWidth))
+	}
+
+	rowOf := func(v float64) int {
+		return int(math.Round((vMax - v) / (vMax - vMin) * float64(height-1)))
+	}
+	colOf := func(t time.Time) int {
+		if !tMax.After(tMin) {
+			return 0
+		}
+		return int(math.Round(float64(t.Sub(tMin)) / float64(tMax.Sub(tMin)) * float64(plotWidth-1)))
+	}
+
+	for i, s := range series {
+		marker := glyphs.markers[i%len(glyphs.markers)]
+		first, last := colOf(s.points[0].t), colOf(s.points[len(s.points)-1].t)
+		if len(s.points) == 1 || first == last {
+			grid[rowOf(s.points[0].v)][first] = marker
+			continue
+		}
+
+		// Walk the plot columns covered by the series, interpolating a value for each one
+		// and filling vertical gaps between neighbouring columns so the line stays connected.
+		seg, prevRow := 0, -1
+		for col := first; col <= last; col++ {
+			t := tMin.Add(time.Duration(float64(tMax.Sub(tMin)) * float64(col) / float64(plotWidth-1)))
+			for seg < len(s.points)-2 && s.points[seg+1].t.Before(t) {
+				seg++
+			}
+			p0, p1 := s.points[seg], s.points[seg+1]
+			v := p0.v
+			if span := p1.t.Sub(p0.t); span > 0 {
+				frac := math.Max(0, math.Min(1, float64(t.Sub(p0.t))/float64(span)))
+				v = p0.v + (p1.v-p0.v)*frac
+			}
+			row := rowOf(v)
+			from, to := row, row
+			if prevRow >= 0 {
+				if prevRow < row {
+					from = prevRow + 1
+				} else if prevRow > row {
+					to = prevRow - 1
+				}
+			}
+			for r := from; r <= to; r++ {
+				grid[r][col] = marker
+			}
+			prevRow = row
+		}
+	}
+
+	w.write([]byte(strings.Repeat(" ", labelWidth+1) + "_value\n"))
+	for r, line := range grid {
+		axis := glyphs.axis
+		if yLabels[r] != "" {
+			axis = glyphs.axisTick
+		}
+		w.write([]byte(fmt.Sprintf("%*s %c%s\n", labelWidth, yLabels[r], axis, strings.TrimRight(string(line), " "))))
+	}
+	w.write([]byte(fmt.Sprintf("%s%c%s\n", strings.Repeat(" ", labelWidth+1), glyphs.axisCorner, strings.Repeat(string(glyphs.axisLine), plotWidth))))
+
+	startLabel, stopLabel := tMin.Format(time.RFC3339), tMax.Format(time.RFC3339)
+	gap := plotWidth + 1 - len(startLabel) - len(stopLabel)
+	if gap < 1 {
+		gap = 1
+	}
+	w.write([]byte(strings.Repeat(" ", labelWidth+1) + startLabel + strings.Repeat(" ", gap) + stopLabel + "\n"))
+	w.write([]byte(fmt.Sprintf("%s_time\n", strings.Repeat(" ", labelWidth+1+(plotWidth-len("_time"))/2))))
+
+	w.write([]byte("Legend:\n"))
+	for i, s := range series {
+		w.write([]byte(fmt.Sprintf("  %c %s\n", glyphs.markers[i%len(glyphs.markers)], s.label)))
+	}
+	w.write(eol)
+	return w.err
+}
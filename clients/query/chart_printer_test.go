@@ -0,0 +1,108 @@
+package query_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChartPrinter_PrintQueryResults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		style    query.ChartStyle
+		in       string
+		expected string
+	}{
+		{
+			name:     "empty",
+			style:    query.ASCIIChart,
+			in:       "",
+			expected: "",
+		},
+		{
+			name:  "multiple series",
+			style: query.ASCIIChart,
+			in: `#group,false,false,true,true,false,false,true,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T00:00:00Z,1,usage,cpu,a
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T00:10:00Z,3,usage,cpu,a
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T00:20:00Z,5,usage,cpu,a
+,,1,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T00:00:00Z,5,usage,cpu,b
+,,1,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T00:20:00Z,1,usage,cpu,b
+`,
+			expected: `Result: _result
+  _value
+5 +oooooo                                   ******
+  |      oooooooooooo           ************
+3 +                  ooooooooooo
+  |      ************           oooooooooooo
+1 +******                                   oooooo
+  +-----------------------------------------------
+  2021-05-04T00:00:00Z        2021-05-04T00:20:00Z
+                       _time
+Legend:
+  * _field=usage, _measurement=cpu, host=a
+  o _field=usage, _measurement=cpu, host=b
+
+`,
+		},
+		{
+			name:  "unicode single point",
+			style: query.UnicodeChart,
+			in: `#group,false,false,false,false
+#datatype,string,long,dateTime:RFC3339,long
+#default,_result,,,
+,result,table,_time,_value
+,,0,2021-05-04T00:00:00Z,10
+`,
+			expected: `Result: _result
+   _value
+11 ┤
+   │
+10 ┤●
+   │
+ 9 ┤
+   └──────────────────────────────────────────────
+   2021-05-04T00:00:00Z       2021-05-04T00:00:00Z
+                       _time
+Legend:
+  ● table 0
+
+`,
+		},
+		{
+			name:  "non-numeric values",
+			style: query.ASCIIChart,
+			in: `#group,false,false,false,false
+#datatype,string,long,dateTime:RFC3339,string
+#default,_result,,,
+,result,table,_time,_value
+,,0,2021-05-04T00:00:00Z,foo
+`,
+			expected: `Result: _result
+No numeric _value data to chart
+
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := io.NopCloser(strings.NewReader(tc.in))
+			out := bytes.Buffer{}
+			require.NoError(t, query.NewChartPrinter(tc.style, 50, 5).PrintQueryResults(in, &out))
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+	"github.com/influxdata/influx-cli/v2/pkg/template"
+)
+
+// ErrResultsDiffer is returned by Diff when the compared results don't match,
+// so scripts can detect differences from the exit code.
+var ErrResultsDiffer = errors.New("query results differ")
+
+// DiffSide is one of the two query runs compared by Diff.
+type DiffSide struct {
+	api.QueryApi
+	clients.OrgParams
+
+	// Name labels the side in output, ex: the name of the config it was run against.
+	Name string
+
+	// Start and Stop optionally set the time range of the query through the
+	// v.timeRangeStart and v.timeRangeStop options. Stop defaults to now.
+	Start string
+	Stop  string
+}
+
+type DiffClient struct {
+	clients.CLI
+	Left  DiffSide
+	Right DiffSide
+}
+
+type DiffParams struct {
+	Query string
+	// Tolerance is the largest difference between two numeric values that are still considered equal.
+	Tolerance float64
+
+	RenderTableColors  bool
+	RenderTableBorders bool
+}
+
+// Diff runs a query on both sides, and reports series that only exist on one side
+// and values that don't match.
+//
+// Tables are aligned by group key, ignoring _start and _stop. Rows are aligned by
+// _time, relative to the start of the range if one was given. Rows in tables
+// without a _time column are aligned by position.
+func (c DiffClient) Diff(ctx context.Context, params *DiffParams) error {
+	if params.Tolerance < 0 {
+		return errors.New("tolerance must not be negative")
+	}
+	if (c.Left.Start == "") != (c.Right.Start == "") {
+		return errors.New("start times must be given for both sides, or neither")
+	}
+
+	left, err := c.run(ctx, c.Left, params.Query)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", c.Left.Name, err)
+	}
+	right, err := c.run(ctx, c.Right, params.Query)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", c.Right.Name, err)
+	}
+
+	report := compareResults(left, right, params.Tolerance)
+	if err := c.printReport(report, params); err != nil {
+		return err
+	}
+	if len(report.missing)+len(report.extra)+len(report.values) > 0 {
+		return ErrResultsDiffer
+	}
+	return nil
+}
+
+func (c DiffClient) run(ctx context.Context, side DiffSide, query string) (*diffResult, error) {
+	if side.OrgID == "" && side.OrgName == "" {
+		return nil, clients.ErrMustSpecifyOrg
+	}
+
+	q := BuildDefaultAST(query)
+	var start time.Time
+	if side.Start != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339Nano, side.Start)
+		if err != nil {
+			return nil, fmt.Errorf("start time %q cannot be parsed as RFC3339Nano: %w", side.Start, err)
+		}
+		stop := time.Now().UTC()
+		if side.Stop != "" {
+			if stop, err = time.Parse(time.RFC3339Nano, side.Stop); err != nil {
+				return nil, fmt.Errorf("stop time %q cannot be parsed as RFC3339Nano: %w", side.Stop, err)
+			}
+		}
+		q.Extern = buildTimeRangeExtern(start, stop)
+	}
+
+	respBody, err := PostQuery(ctx, side.QueryApi, side.OrgParams, "", q)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Close()
+	return readDiffResult(respBody, start)
+}
+
+// buildTimeRangeExtern constructs a Flux AST setting the time range option used by dashboards:
+//
+//	option v = {timeRangeStart: <start>, timeRangeStop: <stop>}
+func buildTimeRangeExtern(start, stop time.Time) *api.Extern {
+	property := func(name string, t time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "Property",
+			"key":  map[string]interface{}{"type": "Identifier", "name": name},
+			"value": map[string]interface{}{
+				"type":  "DateTimeLiteral",
+				"value": t.Format(time.RFC3339Nano),
+			},
+		}
+	}
+	optionExpr := map[string]interface{}{
+		"type": "OptionStatement",
+		"assignment": map[string]interface{}{
+			"type": "VariableAssignment",
+			"id":   map[string]interface{}{"type": "Identifier", "name": "v"},
+			"init": map[string]interface{}{
+				"type": "ObjectExpression",
+				"properties": []interface{}{
+					property("timeRangeStart", start),
+					property("timeRangeStop", stop),
+				},
+			},
+		},
+	}
+
+	extern := api.NewExternWithDefaults()
+	extern.AdditionalProperties = map[string]interface{}{
+		"body": []interface{}{optionExpr},
+	}
+	return extern
+}
+
+type diffValue struct {
+	typ fluxcsv.ColType
+	v   interface{}
+}
+
+func (v diffValue) String() string {
+	return string(appendValue(nil, v.typ, v.v))
+}
+
+type diffSeries struct {
+	rows     map[string]map[string]diffValue
+	rowOrder []string
+}
+
+// diffResult holds the series returned by a query, indexed by group key.
+type diffResult struct {
+	series      map[string]*diffSeries
+	seriesOrder []string
+}
+
+// readDiffResult parses query results so they can be compared. If start is non-zero,
+// rows are keyed by their offset from start instead of by their _time.
+func readDiffResult(in io.ReadCloser, start time.Time) (*diffResult, error) {
+	res := fluxcsv.NewQueryTableResult(in)
+	defer res.Close()
+
+	result := diffResult{series: map[string]*diffSeries{}}
+	var series *diffSeries
+	var hasTime bool
+	var tableRows int
+	for res.Next() {
+		record := res.Record()
+		metadata := res.Metadata()
+
+		if res.ResultChanged() || res.TableIdChanged() || res.AnnotationsChanged() {
+			key := seriesKey(record, metadata)
+			if series = result.series[key]; series == nil {
+				series = &diffSeries{rows: map[string]map[string]diffValue{}}
+				result.series[key] = series
+				result.seriesOrder = append(result.seriesOrder, key)
+			}
+			hasTime = false
+			for _, c := range metadata.Columns() {
+				if c.Name() == "_time" && !c.IsGroup() {
+					hasTime = true
+				}
+			}
+			tableRows = 0
+		}
+
+		var row string
+		if t, ok := record.ValueByKey("_time").(time.Time); hasTime && ok {
+			if start.IsZero() {
+				row = t.Format(time.RFC3339Nano)
+			} else {
+				row = "+" + t.Sub(start).String()
+			}
+		} else {
+			row = "#" + strconv.Itoa(tableRows)
+		}
+		tableRows++
+		// Keep duplicate timestamps distinct, so they're compared in order.
+		for i, base := 2, row; series.rows[row] != nil; i++ {
+			row = fmt.Sprintf("%s (%d)", base, i)
+		}
+
+		values := make(map[string]diffValue)
+		for _, c := range metadata.Columns() {
+			if c.IsGroup() || (hasTime && c.Name() == "_time") || c.Name() == "_start" || c.Name() == "_stop" {
+				continue
+			}
+			values[c.Name()] = diffValue{typ: c.DataType(), v: record.ValueByKey(c.Name())}
+		}
+		series.rows[row] = values
+		series.rowOrder = append(series.rowOrder, row)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// seriesKey identifies a table by its result name and group key. The _start and _stop
+// columns are left out so tables from different time ranges can be matched.
+func seriesKey(record *fluxcsv.FluxRecord, metadata *fluxcsv.FluxTableMetadata) string {
+	var cols []fluxcsv.FluxColumn
+	for _, c := range metadata.Columns() {
+		if c.IsGroup() && c.Name() != "_start" && c.Name() != "_stop" {
+			cols = append(cols, c)
+		}
+	}
+	sort.Slice(cols, func(i, j int) bool { return cols[i].Name() < cols[j].Name() })
+
+	pairs := make([]string, len(cols))
+	for i, c := range cols {
+		pairs[i] = c.Name() + "=" + diffValue{typ: c.DataType(), v: record.ValueByKey(c.Name())}.String()
+	}
+	key := "{" + strings.Join(pairs, ", ") + "}"
+	if record.Result() != "_result" {
+		key = record.Result() + " " + key
+	}
+	return key
+}
+
+// valueDiff is a row only found on one side, or a value that doesn't match.
+type valueDiff struct {
+	series, row, column string
+	left, right         string
+	// Rows only found on one side are reported without a column, with all their values.
+	leftOnly, rightOnly bool
+}
+
+type diffReport struct {
+	compared int
+	// missing holds series only found on the left side, and extra those only found on the right.
+	missing, extra []string
+	seriesRows     map[string]int
+	values         []valueDiff
+}
+
+func compareResults(left, right *diffResult, tolerance float64) *diffReport {
+	report := diffReport{seriesRows: map[string]int{}}
+	for _, key := range left.seriesOrder {
+		l := left.series[key]
+		r, ok := right.series[key]
+		if !ok {
+			report.missing = append(report.missing, key)
+			report.seriesRows[key] = len(l.rowOrder)
+			continue
+		}
+		report.compared++
+
+		for _, row := range l.rowOrder {
+			lValues := l.rows[row]
+			rValues, ok := r.rows[row]
+			if !ok {
+				report.values = append(report.values, valueDiff{
+					series: key, row: row, left: formatRowValues(lValues), leftOnly: true,
+				})
+				continue
+			}
+			for _, col := range unionColumns(lValues, rValues) {
+				lv, lok := lValues[col]
+				rv, rok := rValues[col]
+				if lok && rok && valuesEqual(lv, rv, tolerance) {
+					continue
+				}
+				report.values = append(report.values, valueDiff{
+					series: key, row: row, column: col, left: lv.String(), right: rv.String(),
+				})
+			}
+		}
+		for _, row := range r.rowOrder {
+			if _, ok := l.rows[row]; !ok {
+				report.values = append(report.values, valueDiff{
+					series: key, row: row, right: formatRowValues(r.rows[row]), rightOnly: true,
+				})
+			}
+		}
+	}
+	for _, key := range right.seriesOrder {
+		if _, ok := left.series[key]; !ok {
+			report.extra = append(report.extra, key)
+			report.seriesRows[key] = len(right.series[key].rowOrder)
+		}
+	}
+	return &report
+}
+
+func unionColumns(a, b map[string]diffValue) []string {
+	cols := make([]string, 0, len(a))
+	for k := range a {
+		cols = append(cols, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			cols = append(cols, k)
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// valuesEqual compares numeric values within tolerance, and everything else exactly.
+func valuesEqual(a, b diffValue, tolerance float64) bool {
+	af, aOk := numericValue(a.v)
+	bf, bOk := numericValue(b.v)
+	if aOk && bOk {
+		return math.Abs(af-bf) <= tolerance
+	}
+	return a.typ == b.typ && a.String() == b.String()
+}
+
+func formatRowValues(values map[string]diffValue) string {
+	cols := unionColumns(values, nil)
+	pairs := make([]string, len(cols))
+	for i, col := range cols {
+		pairs[i] = col + "=" + values[col].String()
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (c DiffClient) printReport(report *diffReport, params *DiffParams) error {
+	if _, err := fmt.Fprintf(c.StdIO, "--- %s\n+++ %s\n\n", c.Left.Name, c.Right.Name); err != nil {
+		return err
+	}
+
+	if len(report.missing)+len(report.extra) > 0 {
+		printer := template.NewDiffPrinter(c.StdIO, params.RenderTableColors, params.RenderTableBorders).
+			Title("Series").
+			SetHeaders("Group Key", "Rows")
+		for _, key := range report.missing {
+			printer.AppendDiff([]string{key, strconv.Itoa(report.seriesRows[key])}, nil, false)
+		}
+		for _, key := range report.extra {
+			printer.AppendDiff(nil, []string{key, strconv.Itoa(report.seriesRows[key])}, false)
+		}
+		printer.Render()
+		if _, err := c.StdIO.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+
+	if len(report.values) > 0 {
+		printer := template.NewDiffPrinter(c.StdIO, params.RenderTableColors, params.RenderTableBorders).
+			Title("Values").
+			SetHeaders("Group Key", "Row", "Column", "Value")
+		for _, v := range report.values {
+			var remove, add []string
+			if !v.rightOnly {
+				remove = []string{v.series, v.row, v.column, v.left}
+			}
+			if !v.leftOnly {
+				add = []string{v.series, v.row, v.column, v.right}
+			}
+			printer.AppendDiff(remove, add, true)
+		}
+		printer.Render()
+		if _, err := c.StdIO.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(c.StdIO, "Compared %d series: %d only in %s, %d only in %s, %d differences in shared series\n",
+		report.compared, len(report.missing), c.Left.Name, len(report.extra), c.Right.Name, len(report.values))
+	return err
+}
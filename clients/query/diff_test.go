@@ -0,0 +1,154 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const diffHeader = `#group,false,false,true,true,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,host
+`
+
+func TestDiffClient_Diff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		left        string
+		right       string
+		leftStart   string
+		rightStart  string
+		tolerance   float64
+		expectedErr error
+		expected    string
+	}{
+		{
+			name: "identical",
+			left: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.5,usage,a
+`,
+			right: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.5,usage,a
+`,
+			expected: `--- left
++++ right
+
+Compared 1 series: 0 only in left, 0 only in right, 0 differences in shared series
+`,
+		},
+		{
+			name: "within tolerance",
+			left: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.5,usage,a
+`,
+			right: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.55,usage,a
+`,
+			tolerance: 0.1,
+			expected: `--- left
++++ right
+
+Compared 1 series: 0 only in left, 0 only in right, 0 differences in shared series
+`,
+		},
+		{
+			name: "differences",
+			left: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.5,usage,a
+,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T02:00:00Z,2,usage,a
+,,1,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,3,usage,b
+`,
+			right: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.7,usage,a
+,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T03:00:00Z,4,usage,a
+,,1,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,3,usage,c
+`,
+			tolerance:   0.1,
+			expectedErr: query.ErrResultsDiffer,
+			expected: `--- left
++++ right
+
+SERIES    +add | -remove | unchanged
+  +/- |       GROUP KEY        | ROWS  
+------+------------------------+-------
+  -   | {_field=usage, host=b} |    1  
+  +   | {_field=usage, host=c} |    1  
+------+------------------------+-------
+                TOTAL          |  2    
+      -------------------------+-------
+
+VALUES    +add | -remove | unchanged
+  +/- |       GROUP KEY        |         ROW          | COLUMN |  VALUE    
+------+------------------------+----------------------+--------+-----------
+  -   | {_field=usage, host=a} | 2021-01-01T01:00:00Z | _value |      1.5  
+  +   | {_field=usage, host=a} | 2021-01-01T01:00:00Z | _value |      1.7  
+  -   | {_field=usage, host=a} | 2021-01-01T02:00:00Z |        | _value=2  
+  +   | {_field=usage, host=a} | 2021-01-01T03:00:00Z |        | _value=4  
+------+------------------------+----------------------+--------+-----------
+                                                        TOTAL  |    3      
+                                                      ---------+-----------
+
+Compared 1 series: 1 only in left, 1 only in right, 3 differences in shared series
+`,
+		},
+		{
+			name: "time ranges",
+			left: diffHeader + `,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T01:00:00Z,1.5,usage,a
+`,
+			right: diffHeader + `,,0,2021-01-08T00:00:00Z,2021-01-09T00:00:00Z,2021-01-08T01:00:00Z,1.5,usage,a
+`,
+			leftStart:  "2021-01-01T00:00:00Z",
+			rightStart: "2021-01-08T00:00:00Z",
+			expected: `--- left
++++ right
+
+Compared 1 series: 0 only in left, 0 only in right, 0 differences in shared series
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			stdio := mock.NewMockStdIO(ctrl)
+			out := bytes.Buffer{}
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(out.Write).AnyTimes()
+
+			mockSide := func(name, body, start string) query.DiffSide {
+				queryApi := mock.NewMockQueryApi(ctrl)
+				queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi})
+				queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+					DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+						require.Equal(t, "my-org", *req.GetOrg())
+						require.Equal(t, start != "", req.GetQuery().Extern != nil)
+						return &http.Response{Body: io.NopCloser(strings.NewReader(body))}, nil
+					})
+				return query.DiffSide{
+					QueryApi:  queryApi,
+					OrgParams: clients.OrgParams{OrgName: "my-org"},
+					Name:      name,
+					Start:     start,
+				}
+			}
+
+			client := query.DiffClient{
+				CLI:   clients.CLI{StdIO: stdio},
+				Left:  mockSide("left", tc.left, tc.leftStart),
+				Right: mockSide("right", tc.right, tc.rightStart),
+			}
+			err := client.Diff(context.Background(), &query.DiffParams{Query: "from(bucket: \"b\")", Tolerance: tc.tolerance})
+			require.Equal(t, tc.expectedErr, err)
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
@@ -74,25 +74,8 @@ func (f *formattingPrinter) write(res *fluxcsv.QueryTableResult, out io.Writer)
 
 		if res.AnnotationsChanged() {
 			// Reset and sort cols
-			f.cols = res.Metadata().Columns()
+			f.cols = sortedColumns(res.Metadata())
 			f.lastColIdx = len(f.cols) - 1
-			groupKeys := make(map[string]int, len(res.Metadata().GroupKeyCols()))
-			for i, k := range res.Metadata().GroupKeyCols() {
-				groupKeys[k] = i
-			}
-			sort.Slice(f.cols, func(i, j int) bool {
-				iCol, jCol := f.cols[i], f.cols[j]
-				iGroupIdx, iIsGroup := groupKeys[iCol.Name()]
-				jGroupIdx, jIsGroup := groupKeys[jCol.Name()]
-
-				if iIsGroup && jIsGroup {
-					return iGroupIdx < jGroupIdx
-				}
-				if !iIsGroup && !jIsGroup {
-					return i < j
-				}
-				return iIsGroup && !jIsGroup
-			})
 
 			// Compute header widths
 			f.widths = make([]int, len(f.cols))
@@ -178,6 +161,28 @@ func (f *formattingPrinter) write(res *fluxcsv.QueryTableResult, out io.Writer)
 	return w.err
 }
 
+// sortedColumns returns the columns of a table with its group key columns first,
+// in group key order, followed by the remaining columns in their original order.
+func sortedColumns(metadata *fluxcsv.FluxTableMetadata) []fluxcsv.FluxColumn {
+	cols := make([]fluxcsv.FluxColumn, len(metadata.Columns()))
+	copy(cols, metadata.Columns())
+	groupKeys := make(map[string]int, len(metadata.GroupKeyCols()))
+	for i, k := range metadata.GroupKeyCols() {
+		groupKeys[k] = i
+	}
+	sort.SliceStable(cols, func(i, j int) bool {
+		iCol, jCol := cols[i], cols[j]
+		iGroupIdx, iIsGroup := groupKeys[iCol.Name()]
+		jGroupIdx, jIsGroup := groupKeys[jCol.Name()]
+
+		if iIsGroup && jIsGroup {
+			return iGroupIdx < jGroupIdx
+		}
+		return iIsGroup && !jIsGroup
+	})
+	return cols
+}
+
 func (f *formattingPrinter) makePaddingBuffers() {
 	if len(f.pad) != f.maxWidth {
 		f.pad = make([]byte, f.maxWidth)
@@ -241,29 +246,33 @@ func display(t fluxcsv.ColType) string {
 }
 
 func (f *formattingPrinter) valueBuf(typ fluxcsv.ColType, v interface{}) []byte {
-	var buf []byte
+	return appendValue(f.fmtBuf[0:0], typ, v)
+}
+
+// appendValue appends the display form of a value with the given type to dst.
+func appendValue(dst []byte, typ fluxcsv.ColType, v interface{}) []byte {
 	if v == nil {
-		return buf
+		return dst
 	}
 	switch typ {
 	case fluxcsv.StringDatatype:
-		buf = []byte(v.(string))
+		dst = append(dst, v.(string)...)
 	case fluxcsv.DoubleDatatype:
-		buf = strconv.AppendFloat(f.fmtBuf[0:0], v.(float64), 'f', -1, 64)
+		dst = strconv.AppendFloat(dst, v.(float64), 'f', -1, 64)
 	case fluxcsv.BoolDatatype:
-		buf = strconv.AppendBool(f.fmtBuf[0:0], v.(bool))
+		dst = strconv.AppendBool(dst, v.(bool))
 	case fluxcsv.LongDatatype:
-		buf = strconv.AppendInt(f.fmtBuf[0:0], v.(int64), 10)
+		dst = strconv.AppendInt(dst, v.(int64), 10)
 	case fluxcsv.ULongDatatype:
-		buf = strconv.AppendUint(f.fmtBuf[0:0], v.(uint64), 10)
+		dst = strconv.AppendUint(dst, v.(uint64), 10)
 	case fluxcsv.TimeDatatypeRFC:
 		fallthrough
 	case fluxcsv.TimeDatatypeRFCNano:
-		buf = []byte(v.(time.Time).Format(fixedWidthTimeFmt))
+		dst = v.(time.Time).AppendFormat(dst, fixedWidthTimeFmt)
 	case fluxcsv.DurationDatatype:
-		buf = []byte(v.(time.Duration).String())
+		dst = append(dst, v.(time.Duration).String()...)
 	case fluxcsv.Base64BinaryDataType:
-		base64.StdEncoding.Encode(buf, v.([]byte))
+		dst = base64.StdEncoding.AppendEncode(dst, v.([]byte))
 	}
-	return buf
+	return dst
 }
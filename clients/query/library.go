@@ -0,0 +1,170 @@
+package query
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influx-cli/v2/clients"
+)
+
+// SavedQuery is a Flux query stored in the local library.
+type SavedQuery struct {
+	Name        string `toml:"-" json:"name"`
+	Description string `toml:"description,omitempty" json:"description,omitempty"`
+	Query       string `toml:"query" json:"query"`
+}
+
+// SavedQueries holds the queries saved for each config, indexed by config name and then query name.
+type SavedQueries map[string]map[string]SavedQuery
+
+// LocalLibrary reads and writes saved queries from a TOML file on local disk.
+type LocalLibrary struct {
+	Path string
+}
+
+// DefaultLibraryPath computes where the query library is stored, next to the config
+// store at configPath.
+func DefaultLibraryPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "queries")
+}
+
+func (l LocalLibrary) Load() (SavedQueries, error) {
+	queries := make(SavedQueries)
+	if _, err := toml.DecodeFile(l.Path, &queries); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return queries, nil
+		}
+		return nil, fmt.Errorf("failed to read query library %q: %w", l.Path, err)
+	}
+	for _, byName := range queries {
+		for n, q := range byName {
+			q.Name = n
+			byName[n] = q
+		}
+	}
+	return queries, nil
+}
+
+func (l LocalLibrary) Store(queries SavedQueries) error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), os.ModePerm); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(queries); err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.Path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write query library %q: %w", l.Path, err)
+	}
+	return nil
+}
+
+// LibraryClient manages the queries saved for the active config.
+type LibraryClient struct {
+	clients.CLI
+	Library LocalLibrary
+}
+
+type SaveParams struct {
+	Name        string
+	Description string
+	Query       string
+}
+
+func (c LibraryClient) Save(params *SaveParams) error {
+	if params.Name == "" {
+		return errors.New("must specify a name for the saved query")
+	}
+	queries, err := c.Library.Load()
+	if err != nil {
+		return err
+	}
+	scope := c.ActiveConfig.Name
+	if queries[scope] == nil {
+		queries[scope] = make(map[string]SavedQuery)
+	}
+	saved := SavedQuery{Name: params.Name, Description: params.Description, Query: params.Query}
+	queries[scope][params.Name] = saved
+	if err := c.Library.Store(queries); err != nil {
+		return err
+	}
+	return c.printSavedQueries(savedQueryPrintOptions{query: &saved})
+}
+
+// Get looks up a query saved for the active config.
+func (c LibraryClient) Get(name string) (SavedQuery, error) {
+	queries, err := c.Library.Load()
+	if err != nil {
+		return SavedQuery{}, err
+	}
+	saved, ok := queries[c.ActiveConfig.Name][name]
+	if !ok {
+		return SavedQuery{}, fmt.Errorf("no query named %q is saved for config %q", name, c.ActiveConfig.Name)
+	}
+	return saved, nil
+}
+
+func (c LibraryClient) List() error {
+	queries, err := c.Library.Load()
+	if err != nil {
+		return err
+	}
+	saved := make([]SavedQuery, 0, len(queries[c.ActiveConfig.Name]))
+	for _, q := range queries[c.ActiveConfig.Name] {
+		saved = append(saved, q)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Name < saved[j].Name })
+	return c.printSavedQueries(savedQueryPrintOptions{queries: saved})
+}
+
+func (c LibraryClient) Delete(name string) error {
+	queries, err := c.Library.Load()
+	if err != nil {
+		return err
+	}
+	saved, ok := queries[c.ActiveConfig.Name][name]
+	if !ok {
+		return fmt.Errorf("no query named %q is saved for config %q", name, c.ActiveConfig.Name)
+	}
+	delete(queries[c.ActiveConfig.Name], name)
+	if err := c.Library.Store(queries); err != nil {
+		return err
+	}
+	return c.printSavedQueries(savedQueryPrintOptions{query: &saved})
+}
+
+type savedQueryPrintOptions struct {
+	query   *SavedQuery
+	queries []SavedQuery
+}
+
+func (c LibraryClient) printSavedQueries(options savedQueryPrintOptions) error {
+	if c.PrintAsJSON {
+		var v interface{} = options.queries
+		if options.query != nil {
+			v = options.query
+		}
+		return c.PrintJSON(v)
+	}
+
+	if options.query != nil {
+		options.queries = append(options.queries, *options.query)
+	}
+	headers := []string{"Name", "Description", "Query"}
+	rows := make([]map[string]interface{}, len(options.queries))
+	for i, q := range options.queries {
+		rows[i] = map[string]interface{}{
+			"Name":        q.Name,
+			"Description": q.Description,
+			// Collapse whitespace so multi-line queries fit on one row.
+			"Query": strings.Join(strings.Fields(q.Query), " "),
+		}
+	}
+	return c.PrintTable(headers, rows...)
+}
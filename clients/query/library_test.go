@@ -0,0 +1,52 @@
+package query_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryClient(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	stdio := mock.NewMockStdIO(ctrl)
+	out := bytes.Buffer{}
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(out.Write).AnyTimes()
+
+	library := query.LocalLibrary{Path: query.DefaultLibraryPath(filepath.Join(t.TempDir(), "configs"))}
+	client := func(configName string) query.LibraryClient {
+		return query.LibraryClient{
+			CLI:     clients.CLI{StdIO: stdio, PrintAsJSON: true, ActiveConfig: config.Config{Name: configName}},
+			Library: library,
+		}
+	}
+
+	require.NoError(t, client("a").Save(&query.SaveParams{Name: "cpu", Description: "CPU usage", Query: "from(bucket: \"cpu\")"}))
+	require.NoError(t, client("a").Save(&query.SaveParams{Name: "mem", Query: "from(bucket: \"mem\")"}))
+	require.NoError(t, client("b").Save(&query.SaveParams{Name: "cpu", Query: "from(bucket: \"other\")"}))
+
+	// Queries are scoped to the config they were saved for.
+	saved, err := client("a").Get("cpu")
+	require.NoError(t, err)
+	require.Equal(t, query.SavedQuery{Name: "cpu", Description: "CPU usage", Query: "from(bucket: \"cpu\")"}, saved)
+	saved, err = client("b").Get("cpu")
+	require.NoError(t, err)
+	require.Equal(t, "from(bucket: \"other\")", saved.Query)
+	_, err = client("b").Get("mem")
+	require.EqualError(t, err, `no query named "mem" is saved for config "b"`)
+
+	require.NoError(t, client("a").Delete("cpu"))
+	require.Error(t, client("a").Delete("cpu"))
+
+	out.Reset()
+	require.NoError(t, client("a").List())
+	require.JSONEq(t, `[{"name": "mem", "query": "from(bucket: \"mem\")"}]`, out.String())
+}
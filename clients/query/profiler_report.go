@@ -0,0 +1,380 @@
+package query
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+	"github.com/influxdata/influx-cli/v2/pkg/tabwriter"
+)
+
+const (
+	profilerQueryMeasurement    = "profiler/query"
+	profilerOperatorMeasurement = "profiler/operator"
+)
+
+type ProfilerReportFormat int
+
+const (
+	ProfilerReportTable ProfilerReportFormat = iota
+	ProfilerReportJSON
+)
+
+func (f *ProfilerReportFormat) Set(v string) error {
+	switch v {
+	case "table":
+		*f = ProfilerReportTable
+	case "json":
+		*f = ProfilerReportJSON
+	default:
+		return fmt.Errorf("unsupported profiler report format: %q", v)
+	}
+	return nil
+}
+
+func (f ProfilerReportFormat) String() string {
+	switch f {
+	case ProfilerReportTable:
+		return "table"
+	case ProfilerReportJSON:
+		return "json"
+	default:
+		panic("Impossible!")
+	}
+}
+
+// ProfilerReport summarizes the tables produced by the Flux "query" and "operator" profilers.
+// All durations are reported in nanoseconds, and all allocations in bytes.
+type ProfilerReport struct {
+	Query                 *QueryProfile     `json:"query,omitempty"`
+	Operators             []OperatorProfile `json:"operators"`
+	TotalOperatorDuration time.Duration     `json:"totalOperatorDuration"`
+}
+
+type QueryProfile struct {
+	TotalDuration   time.Duration `json:"totalDuration"`
+	CompileDuration time.Duration `json:"compileDuration"`
+	QueueDuration   time.Duration `json:"queueDuration"`
+	PlanDuration    time.Duration `json:"planDuration"`
+	RequeueDuration time.Duration `json:"requeueDuration"`
+	ExecuteDuration time.Duration `json:"executeDuration"`
+	Concurrency     int64         `json:"concurrency"`
+	MaxAllocated    int64         `json:"maxAllocated"`
+	TotalAllocated  int64         `json:"totalAllocated"`
+	ScannedBytes    int64         `json:"scannedBytes"`
+	ScannedValues   int64         `json:"scannedValues"`
+	RuntimeErrors   string        `json:"runtimeErrors,omitempty"`
+}
+
+type OperatorProfile struct {
+	Type         string        `json:"type"`
+	Label        string        `json:"label"`
+	Count        int64         `json:"count"`
+	MinDuration  time.Duration `json:"minDuration"`
+	MaxDuration  time.Duration `json:"maxDuration"`
+	DurationSum  time.Duration `json:"durationSum"`
+	MeanDuration time.Duration `json:"meanDuration"`
+	// Allocation stats are only reported by some versions of Flux.
+	MaxAllocated   *int64 `json:"maxAllocated,omitempty"`
+	TotalAllocated *int64 `json:"totalAllocated,omitempty"`
+}
+
+// profilerReportPrinter separates profiler tables from the rest of a query's results.
+// Other tables are passed through to a wrapped printer, and the profiler tables are
+// summarized in a report printed after all other results.
+type profilerReportPrinter struct {
+	inner  ResultPrinter
+	format ProfilerReportFormat
+}
+
+func NewProfilerReportPrinter(inner ResultPrinter, format ProfilerReportFormat) *profilerReportPrinter {
+	return &profilerReportPrinter{inner: inner, format: format}
+}
+
+func (p *profilerReportPrinter) PrintQueryResults(resultStream io.ReadCloser, out io.Writer) error {
+	pr, pw := io.Pipe()
+	profilerTables := bytes.Buffer{}
+	splitErr := make(chan error, 1)
+	go func() {
+		err := splitProfilerTables(resultStream, pw, &profilerTables)
+		pw.CloseWithError(err)
+		splitErr <- err
+	}()
+
+	err := p.inner.PrintQueryResults(pr, out)
+	// Unblock the splitter if the inner printer bailed out early.
+	pr.Close()
+	if sErr := <-splitErr; err == nil && sErr != nil {
+		err = sErr
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := ParseProfilerReport(io.NopCloser(&profilerTables))
+	if err != nil {
+		return fmt.Errorf("failed to parse profiler results: %w", err)
+	}
+	if report.Query == nil && len(report.Operators) == 0 {
+		return nil
+	}
+	return report.Write(out, p.format)
+}
+
+// splitProfilerTables copies the tables in the annotated CSV read from in to either
+// profilerOut or otherOut, depending on whether they were produced by a Flux profiler.
+// Tables are routed as soon as their first row is read, so other results are streamed
+// through without waiting for the end of the input.
+func splitProfilerTables(in io.ReadCloser, otherOut, profilerOut io.Writer) error {
+	defer in.Close()
+	r := newAnnotatedCSVReader(in)
+	others, profilers := csv.NewWriter(otherOut), csv.NewWriter(profilerOut)
+
+	var pending [][]string
+	var defaults []string
+	var dest *csv.Writer
+	measurementIdx := -1
+
+	for {
+		row, kind, newTable, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch kind {
+		case annotationRow:
+			if newTable {
+				if dest != nil {
+					dest.Flush()
+					if err := dest.Error(); err != nil {
+						return err
+					}
+				}
+				pending, defaults, dest = nil, nil, nil
+				measurementIdx = -1
+			}
+			if row[0] == "#default" {
+				defaults = row
+			}
+			pending = append(pending, row)
+		case headerRow:
+			for i, name := range row {
+				if name == "_measurement" {
+					measurementIdx = i
+				}
+			}
+			pending = append(pending, row)
+		case dataRow:
+			if dest == nil {
+				dest = others
+				if measurement := valueOrDefault(row, defaults, measurementIdx); strings.HasPrefix(measurement, "profiler/") {
+					dest = profilers
+				}
+				if err := dest.WriteAll(pending); err != nil {
+					return err
+				}
+				pending = nil
+			}
+			if err := dest.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Tables without any rows are still passed on, so error tables reach the wrapped printer.
+	if dest == nil && len(pending) > 0 {
+		if err := others.WriteAll(pending); err != nil {
+			return err
+		}
+	}
+	others.Flush()
+	profilers.Flush()
+	if err := others.Error(); err != nil {
+		return err
+	}
+	return profilers.Error()
+}
+
+// valueOrDefault returns the value at idx in row, falling back to the value at the same index
+// in the table's #default annotation.
+func valueOrDefault(row, defaults []string, idx int) string {
+	if idx < 0 || idx >= len(row) {
+		return ""
+	}
+	if row[idx] != "" || idx >= len(defaults) {
+		return row[idx]
+	}
+	return defaults[idx]
+}
+
+// ParseProfilerReport builds a report from the profiler/query and profiler/operator tables
+// in an annotated CSV stream. Tables from other sources are ignored.
+func ParseProfilerReport(in io.ReadCloser) (*ProfilerReport, error) {
+	res := fluxcsv.NewQueryTableResult(in)
+	defer res.Close()
+
+	report := ProfilerReport{Operators: []OperatorProfile{}}
+	for res.Next() {
+		record := res.Record()
+		switch record.Measurement() {
+		case profilerQueryMeasurement:
+			report.Query = &QueryProfile{
+				TotalDuration:   time.Duration(int64Value(record, "TotalDuration")),
+				CompileDuration: time.Duration(int64Value(record, "CompileDuration")),
+				QueueDuration:   time.Duration(int64Value(record, "QueueDuration")),
+				PlanDuration:    time.Duration(int64Value(record, "PlanDuration")),
+				RequeueDuration: time.Duration(int64Value(record, "RequeueDuration")),
+				ExecuteDuration: time.Duration(int64Value(record, "ExecuteDuration")),
+				Concurrency:     int64Value(record, "Concurrency"),
+				MaxAllocated:    int64Value(record, "MaxAllocated"),
+				TotalAllocated:  int64Value(record, "TotalAllocated"),
+				ScannedBytes:    int64Value(record, "influxdb/scanned-bytes"),
+				ScannedValues:   int64Value(record, "influxdb/scanned-values"),
+			}
+			if errs, ok := record.ValueByKey("RuntimeErrors").(string); ok {
+				report.Query.RuntimeErrors = errs
+			}
+		case profilerOperatorMeasurement:
+			op := OperatorProfile{
+				Count:        int64Value(record, "Count"),
+				MinDuration:  time.Duration(int64Value(record, "MinDuration")),
+				MaxDuration:  time.Duration(int64Value(record, "MaxDuration")),
+				DurationSum:  time.Duration(int64Value(record, "DurationSum")),
+				MeanDuration: time.Duration(int64Value(record, "MeanDuration")),
+			}
+			op.Type, _ = record.ValueByKey("Type").(string)
+			op.Label, _ = record.ValueByKey("Label").(string)
+			if _, ok := record.Values()["MaxAllocated"]; ok {
+				v := int64Value(record, "MaxAllocated")
+				op.MaxAllocated = &v
+			}
+			if _, ok := record.Values()["TotalAllocated"]; ok {
+				v := int64Value(record, "TotalAllocated")
+				op.TotalAllocated = &v
+			}
+			report.Operators = append(report.Operators, op)
+			report.TotalOperatorDuration += op.DurationSum
+		}
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+
+	// Most expensive operators first.
+	sort.SliceStable(report.Operators, func(i, j int) bool {
+		return report.Operators[i].DurationSum > report.Operators[j].DurationSum
+	})
+	return &report, nil
+}
+
+// int64Value reads an integer column from a profiler table, returning 0 if it's missing.
+func int64Value(record *fluxcsv.FluxRecord, key string) int64 {
+	switch v := record.ValueByKey(key).(type) {
+	case int64:
+		return v
+	case uint64:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case time.Duration:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// Write prints the report to out in the given format.
+func (r *ProfilerReport) Write(out io.Writer, format ProfilerReportFormat) error {
+	if format == ProfilerReportJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "\t")
+		return enc.Encode(r)
+	}
+
+	if _, err := fmt.Fprintln(out, "Profiler report:"); err != nil {
+		return err
+	}
+	if r.Query != nil {
+		q := r.Query
+		headers := []string{"Total", "Compile", "Queue", "Plan", "Requeue", "Execute", "Concurrency", "Max Allocated", "Total Allocated", "Scanned Bytes", "Scanned Values"}
+		if err := writeTable(out, headers, map[string]interface{}{
+			"Total":           q.TotalDuration,
+			"Compile":         q.CompileDuration,
+			"Queue":           q.QueueDuration,
+			"Plan":            q.PlanDuration,
+			"Requeue":         q.RequeueDuration,
+			"Execute":         q.ExecuteDuration,
+			"Concurrency":     q.Concurrency,
+			"Max Allocated":   q.MaxAllocated,
+			"Total Allocated": q.TotalAllocated,
+			"Scanned Bytes":   q.ScannedBytes,
+			"Scanned Values":  q.ScannedValues,
+		}); err != nil {
+			return err
+		}
+		if q.RuntimeErrors != "" {
+			if _, err := fmt.Fprintf(out, "Runtime errors: %s\n", q.RuntimeErrors); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(r.Operators) > 0 {
+		if r.Query != nil {
+			if _, err := fmt.Fprintln(out); err != nil {
+				return err
+			}
+		}
+		headers := []string{"Type", "Label", "Count", "Total Time", "Mean Time", "Min Time", "Max Time", "Max Allocated", "Total Allocated"}
+		rows := make([]map[string]interface{}, len(r.Operators))
+		for i, op := range r.Operators {
+			rows[i] = map[string]interface{}{
+				"Type":            op.Type,
+				"Label":           op.Label,
+				"Count":           op.Count,
+				"Total Time":      op.DurationSum,
+				"Mean Time":       op.MeanDuration,
+				"Min Time":        op.MinDuration,
+				"Max Time":        op.MaxDuration,
+				"Max Allocated":   optionalInt(op.MaxAllocated),
+				"Total Allocated": optionalInt(op.TotalAllocated),
+			}
+		}
+		if err := writeTable(out, headers, rows...); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "Total operator time: %s\n", r.TotalOperatorDuration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func optionalInt(v *int64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+func writeTable(out io.Writer, headers []string, rows ...map[string]interface{}) error {
+	w := tabwriter.NewTabWriter(out, false)
+	if err := w.WriteHeaders(headers...); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,141 @@
+package query_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/stretchr/testify/require"
+)
+
+const profiledResults = `#group,false,false,true,true,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T18:29:52Z,12345,qux,foo
+
+#group,false,false,true,false,false,false,false,false,false,false,false,false,false,false,false
+#datatype,string,long,string,long,long,long,long,long,long,long,long,long,string,long,long
+#default,_profiler,,,,,,,,,,,,,,
+,result,table,_measurement,TotalDuration,CompileDuration,QueueDuration,PlanDuration,RequeueDuration,ExecuteDuration,Concurrency,MaxAllocated,TotalAllocated,RuntimeErrors,influxdb/scanned-bytes,influxdb/scanned-values
+,,0,profiler/query,8924700,350900,33800,0,0,8486500,0,2072,2072,,0,0
+
+#group,false,false,true,false,false,false,false,false,false,false
+#datatype,string,long,string,string,string,long,long,long,long,double
+#default,_profiler,,,,,,,,,
+,result,table,_measurement,Type,Label,Count,MinDuration,MaxDuration,DurationSum,MeanDuration
+,,1,profiler/operator,*influxdb.readFilterSource,ReadRange2,1,367331,367331,367331,367331
+,,1,profiler/operator,*universe.filterTransformation,filter3,1,1000,1000,1000000,1000
+`
+
+func TestProfilerReportPrinter_PrintQueryResults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		format   query.ProfilerReportFormat
+		in       string
+		expected string
+	}{
+		{
+			name:     "empty",
+			in:       "",
+			expected: "",
+		},
+		{
+			name: "no profiler tables",
+			in: `#group,false,false,false
+#datatype,string,long,double
+#default,_result,,
+,result,table,_value
+,,0,1
+`,
+			expected: `#group,false,false,false
+#datatype,string,long,double
+#default,_result,,
+,result,table,_value
+,,0,1
+`,
+		},
+		{
+			name: "table report",
+			in:   profiledResults,
+			expected: `#group,false,false,true,true,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T18:29:52Z,12345,qux,foo
+Profiler report:
+Total		Compile	Queue	Plan	Requeue	Execute		Concurrency	Max Allocated	Total Allocated	Scanned Bytes	Scanned Values
+8.9247ms	350.9µs	33.8µs	0s	0s	8.4865ms	0		2072		2072		0		0
+
+Type				Label		Count	Total Time	Mean Time	Min Time	Max Time	Max Allocated	Total Allocated
+*universe.filterTransformation	filter3		1	1ms		1µs		1µs		1µs		-		-
+*influxdb.readFilterSource	ReadRange2	1	367.331µs	367.331µs	367.331µs	367.331µs	-		-
+Total operator time: 1.367331ms
+`,
+		},
+		{
+			name:   "json report",
+			format: query.ProfilerReportJSON,
+			in:     profiledResults,
+			expected: `#group,false,false,true,true,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement
+,,0,2021-05-04T00:00:00Z,2021-05-05T00:00:00Z,2021-05-04T18:29:52Z,12345,qux,foo
+{
+	"query": {
+		"totalDuration": 8924700,
+		"compileDuration": 350900,
+		"queueDuration": 33800,
+		"planDuration": 0,
+		"requeueDuration": 0,
+		"executeDuration": 8486500,
+		"concurrency": 0,
+		"maxAllocated": 2072,
+		"totalAllocated": 2072,
+		"scannedBytes": 0,
+		"scannedValues": 0
+	},
+	"operators": [
+		{
+			"type": "*universe.filterTransformation",
+			"label": "filter3",
+			"count": 1,
+			"minDuration": 1000,
+			"maxDuration": 1000,
+			"durationSum": 1000000,
+			"meanDuration": 1000
+		},
+		{
+			"type": "*influxdb.readFilterSource",
+			"label": "ReadRange2",
+			"count": 1,
+			"minDuration": 367331,
+			"maxDuration": 367331,
+			"durationSum": 367331,
+			"meanDuration": 367331
+		}
+	],
+	"totalOperatorDuration": 1367331
+}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := io.NopCloser(strings.NewReader(tc.in))
+			out := bytes.Buffer{}
+			printer := query.NewProfilerReportPrinter(query.RawResultPrinter, tc.format)
+			require.NoError(t, printer.PrintQueryResults(in, &out))
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
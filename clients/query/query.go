@@ -4,6 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/influxdata/influx-cli/v2/api"
 	"github.com/influxdata/influx-cli/v2/clients"
@@ -28,12 +33,24 @@ type Client struct {
 	clients.CLI
 	api.QueryApi
 	ResultPrinter
+
+	// Cache optionally stores query results locally. Caching is disabled if nil.
+	Cache *ResultCache
 }
 
 type Params struct {
 	clients.OrgParams
 	Query     string
 	Profilers []string
+	// ScriptParams are passed to the query as the 'params' record, the same way
+	// invokable scripts receive their parameters.
+	ScriptParams map[string]string
+
+	// WatchInterval re-runs the query on the given interval until the context
+	// is canceled. Zero means the query is only run once.
+	WatchInterval time.Duration
+	// HighlightChanges marks values that changed since the previous run in watch mode.
+	HighlightChanges bool
 }
 
 // BuildDefaultAST wraps a raw query string in the AST structure expected
@@ -50,6 +67,11 @@ func BuildDefaultAST(query string) api.Query {
 	}
 }
 
+// StringLiteral quotes s as a Flux string literal.
+func StringLiteral(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`).Replace(s) + `"`
+}
+
 // BuildExternAST constructs a Flux AST tree to import and set the profilers option.
 //
 // See the docs for more info: https://docs.influxdata.com/influxdb/cloud/reference/flux/stdlib/profiler/
@@ -119,34 +141,146 @@ func BuildExternAST(profilers []string) *api.Extern {
 	return extern
 }
 
+// BuildParamsExternAST constructs a Flux AST tree to set the 'params' option read by invokable scripts.
+//
+// Values are typed the same way as the equivalent JSON passed to 'influx scripts invoke':
+// integers, floats and booleans are recognized, and everything else is passed as a string.
+func BuildParamsExternAST(params map[string]string) *api.Extern {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// {<key>: <value> for each param}
+	properties := make([]interface{}, len(keys))
+	for i, k := range keys {
+		properties[i] = map[string]interface{}{
+			"type": "Property",
+			"key": map[string]interface{}{
+				"type": "Identifier",
+				"name": k,
+			},
+			"value": paramLiteral(params[k]),
+		}
+	}
+	// option params = {<key>: <value> for each param}
+	paramsOptionExpr := map[string]interface{}{
+		"type": "OptionStatement",
+		"assignment": map[string]interface{}{
+			"type": "VariableAssignment",
+			"id": map[string]interface{}{
+				"type": "Identifier",
+				"name": "params",
+			},
+			"init": map[string]interface{}{
+				"type":       "ObjectExpression",
+				"properties": properties,
+			},
+		},
+	}
+
+	extern := api.NewExternWithDefaults()
+	extern.AdditionalProperties = map[string]interface{}{
+		"body": []interface{}{paramsOptionExpr},
+	}
+	return extern
+}
+
+func paramLiteral(v string) map[string]interface{} {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		// Flux encodes integer literals as strings to avoid losing precision.
+		return map[string]interface{}{"type": "IntegerLiteral", "value": v}
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return map[string]interface{}{"type": "FloatLiteral", "value": f}
+	}
+	if v == "true" || v == "false" {
+		return map[string]interface{}{"type": "BooleanLiteral", "value": v == "true"}
+	}
+	return map[string]interface{}{"type": "StringLiteral", "value": v}
+}
+
+// mergeExterns combines the imports and statements of multiple externs into one.
+func mergeExterns(externs ...*api.Extern) *api.Extern {
+	switch len(externs) {
+	case 0:
+		return nil
+	case 1:
+		return externs[0]
+	}
+
+	var imports, body []interface{}
+	for _, e := range externs {
+		if i, ok := e.AdditionalProperties["imports"].([]interface{}); ok {
+			imports = append(imports, i...)
+		}
+		if b, ok := e.AdditionalProperties["body"].([]interface{}); ok {
+			body = append(body, b...)
+		}
+	}
+	extern := api.NewExternWithDefaults()
+	extern.AdditionalProperties = map[string]interface{}{
+		"imports": imports,
+		"body":    body,
+	}
+	return extern
+}
+
 func (c Client) Query(ctx context.Context, params *Params) error {
 	if params.OrgID == "" && params.OrgName == "" && c.ActiveConfig.Org == "" {
 		return clients.ErrMustSpecifyOrg
 	}
+	if params.WatchInterval > 0 {
+		return c.watch(ctx, params)
+	}
+	return c.query(ctx, params, c.StdIO)
+}
 
+// query executes the query once, printing its results to out.
+func (c Client) query(ctx context.Context, params *Params, out io.Writer) error {
 	query := BuildDefaultAST(params.Query)
+	var externs []*api.Extern
 	if len(params.Profilers) > 0 {
-		query.Extern = BuildExternAST(params.Profilers)
+		externs = append(externs, BuildExternAST(params.Profilers))
+	}
+	if len(params.ScriptParams) > 0 {
+		externs = append(externs, BuildParamsExternAST(params.ScriptParams))
+	}
+	query.Extern = mergeExterns(externs...)
+
+	if c.Cache != nil {
+		return c.cachedQuery(ctx, params, query, out)
 	}
 
-	req := c.PostQuery(ctx).Query(query).AcceptEncoding("gzip")
-	if params.OrgID != "" {
-		req = req.OrgID(params.OrgID)
-	} else if params.OrgName != "" {
-		req = req.Org(params.OrgName)
+	respBody, err := PostQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, query)
+	if err != nil {
+		return err
+	}
+	defer respBody.Close()
+
+	return c.PrintQueryResults(respBody, out)
+}
+
+// PostQuery executes query in the org identified by orgParams, falling back to defaultOrg,
+// and returns the decoded response body.
+func PostQuery(ctx context.Context, queryApi api.QueryApi, orgParams clients.OrgParams, defaultOrg string, query api.Query) (io.ReadCloser, error) {
+	req := queryApi.PostQuery(ctx).Query(query).AcceptEncoding("gzip")
+	if orgParams.OrgID != "" {
+		req = req.OrgID(orgParams.OrgID)
+	} else if orgParams.OrgName != "" {
+		req = req.Org(orgParams.OrgName)
 	} else {
-		req = req.Org(c.ActiveConfig.Org)
+		req = req.Org(defaultOrg)
 	}
 
 	resp, err := req.Execute()
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	respBody, err := api.GunzipIfNeeded(resp)
 	if err != nil {
-		return fmt.Errorf("failed to decode query response: %w", err)
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
 	}
-	defer respBody.Close()
-
-	return c.PrintQueryResults(respBody, c.StdIO)
+	return respBody, nil
 }
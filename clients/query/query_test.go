@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/influxdata/influx-cli/v2/api"
@@ -15,6 +18,7 @@ import (
 	"github.com/influxdata/influx-cli/v2/clients/query"
 	"github.com/influxdata/influx-cli/v2/config"
 	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/influxdata/influx-cli/v2/internal/testutils"
 	"github.com/stretchr/testify/assert"
 	tmock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -256,3 +260,133 @@ func TestQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	stdio := mock.NewMockStdIO(ctrl)
+	stdio.EXPECT().IsInteractive().Return(false)
+	writtenBytes := bytes.Buffer{}
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(writtenBytes.Write).AnyTimes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	queryApi := mock.NewMockQueryApi(ctrl)
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).Times(3)
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(api.ApiPostQueryRequest) (*http.Response, error) {
+			runs++
+			if runs == 3 {
+				// Simulate the user interrupting the CLI mid-query.
+				cancel()
+				return nil, context.Canceled
+			}
+			return &http.Response{Body: io.NopCloser(strings.NewReader(fmt.Sprintf("run %d\n", runs)))}, nil
+		}).Times(3)
+
+	cli := query.Client{
+		CLI:           clients.CLI{ActiveConfig: config.Config{Org: "my-org"}, StdIO: stdio},
+		QueryApi:      queryApi,
+		ResultPrinter: query.RawResultPrinter,
+	}
+	params := query.Params{Query: "I'm a query!", WatchInterval: time.Millisecond}
+	require.NoError(t, cli.Query(ctx, &params))
+
+	testutils.MatchLines(t, []string{
+		`^--- \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z ---$`,
+		`^run 1$`,
+		`^--- \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z ---$`,
+		`^run 2$`,
+	}, strings.Split(writtenBytes.String(), "\n"))
+}
+
+func TestBuildParamsExternAST(t *testing.T) {
+	t.Parallel()
+
+	extern := query.BuildParamsExternAST(map[string]string{
+		"host":  "server01",
+		"limit": "10",
+		"ratio": "0.5",
+		"debug": "true",
+	})
+	body, err := json.Marshal(extern)
+	require.NoError(t, err)
+
+	property := func(name, literal string) string {
+		return fmt.Sprintf(`{"type": "Property", "key": {"type": "Identifier", "name": %q}, "value": %s}`, name, literal)
+	}
+	expected := fmt.Sprintf(`{
+		"type": "File",
+		"body": [{
+			"type": "OptionStatement",
+			"assignment": {
+				"type": "VariableAssignment",
+				"id": {"type": "Identifier", "name": "params"},
+				"init": {"type": "ObjectExpression", "properties": [%s, %s, %s, %s]}
+			}
+		}]
+	}`,
+		property("debug", `{"type": "BooleanLiteral", "value": true}`),
+		property("host", `{"type": "StringLiteral", "value": "server01"}`),
+		property("limit", `{"type": "IntegerLiteral", "value": "10"}`),
+		property("ratio", `{"type": "FloatLiteral", "value": 0.5}`),
+	)
+	require.JSONEq(t, expected, string(body))
+}
+
+func TestQuery_Cache(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	stdio := mock.NewMockStdIO(ctrl)
+	writtenBytes := bytes.Buffer{}
+	stdio.EXPECT().Write(gomock.Any()).DoAndReturn(writtenBytes.Write).AnyTimes()
+	stdio.EXPECT().WriteErr(gomock.Any()).AnyTimes()
+
+	body := `#group,false,false,false
+#datatype,string,long,long
+#default,_result,,
+,result,table,_value
+,,0,1
+,,0,2
+`
+	queryApi := mock.NewMockQueryApi(ctrl)
+	// The first run is cut short by a row limit, so only the second reaches the cache.
+	// The last run uses another token, so it isn't served from the cache.
+	queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi}).Times(3)
+	queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+		DoAndReturn(func(api.ApiPostQueryRequest) (*http.Response, error) {
+			return &http.Response{Body: io.NopCloser(strings.NewReader(body))}, nil
+		}).Times(3)
+
+	cache := &query.ResultCache{Dir: t.TempDir(), TTL: time.Hour, Host: "http://localhost:8086", User: "my-token"}
+	client := query.Client{
+		CLI:           clients.CLI{ActiveConfig: config.Config{Org: "my-org"}, StdIO: stdio},
+		QueryApi:      queryApi,
+		ResultPrinter: query.NewRowLimitPrinter(query.RawResultPrinter, 1, stdio),
+		Cache:         cache,
+	}
+	require.NoError(t, client.Query(context.Background(), &query.Params{Query: "from(bucket: \"b\")"}))
+
+	client.ResultPrinter = query.RawResultPrinter
+	require.NoError(t, client.Query(context.Background(), &query.Params{Query: "from(bucket: \"b\")"}))
+	writtenBytes.Reset()
+	// Reformatting the query doesn't cause a cache miss.
+	require.NoError(t, client.Query(context.Background(), &query.Params{Query: "\n  from(bucket: \"b\")\n"}))
+	require.Equal(t, body, writtenBytes.String())
+
+	otherUser := *cache
+	otherUser.User = "other-token"
+	client.Cache = &otherUser
+	require.NoError(t, client.Query(context.Background(), &query.Params{Query: "from(bucket: \"b\")"}))
+
+	removed, err := cache.Prune(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+	removed, err = cache.Prune(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+}
@@ -0,0 +1,84 @@
+package query
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/influxdata/influx-cli/v2/pkg/stdio"
+)
+
+// rowLimitPrinter stops reading query results once a set number of data rows has been
+// passed to a wrapped printer. It protects terminals from accidentally huge results.
+// Truncation is reported on stderr, so the printed results stay machine-readable.
+type rowLimitPrinter struct {
+	inner ResultPrinter
+	limit int
+	stdio stdio.StdIO
+}
+
+func NewRowLimitPrinter(inner ResultPrinter, limit int, stdio stdio.StdIO) *rowLimitPrinter {
+	return &rowLimitPrinter{inner: inner, limit: limit, stdio: stdio}
+}
+
+func (p *rowLimitPrinter) PrintQueryResults(resultStream io.ReadCloser, out io.Writer) error {
+	pr, pw := io.Pipe()
+	type limitResult struct {
+		truncated bool
+		err       error
+	}
+	done := make(chan limitResult, 1)
+	go func() {
+		truncated, err := limitRows(resultStream, pw, p.limit)
+		pw.CloseWithError(err)
+		done <- limitResult{truncated: truncated, err: err}
+	}()
+
+	err := p.inner.PrintQueryResults(pr, out)
+	pr.Close()
+	res := <-done
+	if err == nil {
+		err = res.err
+	}
+	if err != nil {
+		return err
+	}
+	if res.truncated {
+		_, _ = p.stdio.WriteErr([]byte(fmt.Sprintf("Output truncated after %d rows, use --limit-rows to change the limit\n", p.limit)))
+	}
+	return nil
+}
+
+// limitRows copies annotated CSV from in to out until limit data rows have been copied,
+// reporting whether any rows were left unread.
+func limitRows(in io.ReadCloser, out io.Writer, limit int) (bool, error) {
+	// Closing the response body early stops the rest of the results from being downloaded.
+	defer in.Close()
+	r := newAnnotatedCSVReader(in)
+	w := csv.NewWriter(out)
+
+	rows := 0
+	for {
+		row, kind, _, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		if kind == dataRow {
+			if rows == limit {
+				w.Flush()
+				return true, w.Error()
+			}
+			rows++
+		}
+		if err := w.Write(row); err != nil {
+			return false, err
+		}
+		// Hand each row to the printer as soon as it's read.
+		w.Flush()
+	}
+	w.Flush()
+	return false, w.Error()
+}
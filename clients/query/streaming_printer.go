@@ -0,0 +1,164 @@
+package query
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+const DefaultSampleRows = 100
+
+type StreamingParams struct {
+	// ColumnWidth sets a fixed width for every column.
+	// If zero, widths are computed from a sample of each table's first rows.
+	ColumnWidth int
+
+	// SampleRows is the number of rows buffered per table to compute column widths.
+	SampleRows int
+}
+
+// streamingPrinter formats query results into a structured table like formattingPrinter,
+// but writes each row as soon as it's parsed. Column widths are either fixed, or computed
+// from a bounded sample at the start of each table, so memory use stays constant no matter
+// how large the result is. Values that don't fit in their column are truncated. Output is
+// flushed at the end of each table, and whenever more of the result has to be read, so rows
+// show up while the server is slow to send the rest.
+type streamingPrinter struct {
+	params StreamingParams
+
+	cols    []fluxcsv.FluxColumn
+	widths  []int
+	sample  [][]string
+	sampled bool
+	// fmtBuf is used to format values
+	fmtBuf [64]byte
+}
+
+func NewStreamingPrinter(params StreamingParams) *streamingPrinter {
+	if params.SampleRows <= 0 {
+		params.SampleRows = DefaultSampleRows
+	}
+	return &streamingPrinter{params: params}
+}
+
+func (s *streamingPrinter) PrintQueryResults(resultStream io.ReadCloser, out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	w := &writeHelper{w: bw}
+	res := fluxcsv.NewQueryTableResult(&flushingReader{ReadCloser: resultStream, bw: bw, w: w})
+	defer res.Close()
+
+	for res.Next() {
+		record := res.Record()
+
+		if res.ResultChanged() || res.TableIdChanged() || res.AnnotationsChanged() {
+			s.flushSample(w)
+			if w.err == nil {
+				w.err = bw.Flush()
+			}
+			if res.ResultChanged() {
+				w.write([]byte("Result: " + record.Result()))
+				w.write(eol)
+			}
+			w.write([]byte("Table: keys: [" + strings.Join(res.Metadata().GroupKeyCols(), ", ") + "]"))
+			w.write(eol)
+			s.cols = sortedColumns(res.Metadata())
+			s.sample, s.sampled = nil, false
+		}
+
+		cells := make([]string, len(s.cols))
+		for i, c := range s.cols {
+			cells[i] = string(appendValue(s.fmtBuf[0:0], c.DataType(), record.ValueByKey(c.Name())))
+		}
+		if s.sampled {
+			s.writeRow(w, cells)
+		} else {
+			s.sample = append(s.sample, cells)
+			if s.params.ColumnWidth > 0 || len(s.sample) >= s.params.SampleRows {
+				s.flushSample(w)
+			}
+		}
+
+		if w.err != nil {
+			return w.err
+		}
+	}
+	s.flushSample(w)
+	if w.err == nil {
+		w.err = bw.Flush()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return res.Err()
+}
+
+// flushSample computes column widths for the current table, and writes its header
+// followed by any buffered rows. Later rows in the table are written immediately.
+func (s *streamingPrinter) flushSample(w *writeHelper) {
+	if s.sampled || s.cols == nil {
+		return
+	}
+	s.sampled = true
+
+	headers := make([]string, len(s.cols))
+	s.widths = make([]int, len(s.cols))
+	for i, c := range s.cols {
+		headers[i] = c.Name() + ":" + display(c.DataType())
+		if s.params.ColumnWidth > 0 {
+			s.widths[i] = s.params.ColumnWidth
+			continue
+		}
+		s.widths[i] = utf8.RuneCountInString(headers[i])
+		for _, row := range s.sample {
+			if n := utf8.RuneCountInString(row[i]); n > s.widths[i] {
+				s.widths[i] = n
+			}
+		}
+	}
+
+	s.writeRow(w, headers)
+	dashes := make([]string, len(s.cols))
+	for i, width := range s.widths {
+		dashes[i] = strings.Repeat("-", width)
+	}
+	s.writeRow(w, dashes)
+	for _, row := range s.sample {
+		s.writeRow(w, row)
+	}
+	s.sample = nil
+}
+
+func (s *streamingPrinter) writeRow(w *writeHelper, cells []string) {
+	for i, cell := range cells {
+		width := s.widths[i]
+		if padding := width - utf8.RuneCountInString(cell); padding >= 0 {
+			w.write([]byte(strings.Repeat(" ", padding) + cell))
+		} else if width > 3 {
+			w.write([]byte(string([]rune(cell)[:width-3]) + "..."))
+		} else {
+			w.write([]byte(string([]rune(cell)[:width])))
+		}
+		if i != len(cells)-1 {
+			w.write([]byte("  "))
+		}
+	}
+	w.write(eol)
+}
+
+// flushingReader flushes the printer's output before reading more of the query response,
+// so rows that were already printed aren't held back while waiting for the server.
+type flushingReader struct {
+	io.ReadCloser
+	bw *bufio.Writer
+	w  *writeHelper
+}
+
+func (r *flushingReader) Read(p []byte) (int, error) {
+	if r.w.err == nil {
+		r.w.err = r.bw.Flush()
+	}
+	return r.ReadCloser.Read(p)
+}
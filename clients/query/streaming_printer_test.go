@@ -0,0 +1,258 @@
+package query_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingPrinter_PrintQueryResults(t *testing.T) {
+	t.Parallel()
+
+	in := `#group,false,false,true,false,false
+#datatype,string,long,string,long,string
+#default,_result,,,,
+,result,table,host,_value,msg
+,,0,a,1,short
+,,0,a,22,a much longer message
+,,0,a,333,ok
+
+#group,false,false,true,false,false
+#datatype,string,long,string,long,string
+#default,_result,,,,
+,result,table,host,_value,msg
+,,1,b,4,hi
+`
+
+	testCases := []struct {
+		name     string
+		params   query.StreamingParams
+		expected string
+	}{
+		{
+			name:   "sampled widths",
+			params: query.StreamingParams{SampleRows: 2},
+			expected: `Result: _result
+Table: keys: [host]
+host:string  _value:int             msg:string
+-----------  ----------  ---------------------
+          a           1                  short
+          a          22  a much longer message
+          a         333                     ok
+Table: keys: [host]
+host:string  _value:int  msg:string
+-----------  ----------  ----------
+          b           4          hi
+`,
+		},
+		{
+			name:   "fixed widths",
+			params: query.StreamingParams{ColumnWidth: 8},
+			expected: `Result: _result
+Table: keys: [host]
+host:...  _valu...  msg:s...
+--------  --------  --------
+       a         1     short
+       a        22  a muc...
+       a       333        ok
+Table: keys: [host]
+host:...  _valu...  msg:s...
+--------  --------  --------
+       b         4        hi
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			out := bytes.Buffer{}
+			require.NoError(t, query.NewStreamingPrinter(tc.params).PrintQueryResults(io.NopCloser(strings.NewReader(in)), &out))
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestStreamingPrinter_MultiByteValues(t *testing.T) {
+	t.Parallel()
+
+	in := `#group,false,false,false
+#datatype,string,long,string
+#default,_result,,
+,result,table,msg
+,,0,héllo wörld
+,,0,日本語のテキスト
+`
+
+	testCases := []struct {
+		name     string
+		params   query.StreamingParams
+		expected string
+	}{
+		{
+			name:   "sampled widths",
+			params: query.StreamingParams{},
+			expected: `Result: _result
+Table: keys: []
+ msg:string
+-----------
+héllo wörld
+   日本語のテキスト
+`,
+		},
+		{
+			name:   "fixed widths",
+			params: query.StreamingParams{ColumnWidth: 7},
+			expected: `Result: _result
+Table: keys: []
+msg:...
+-------
+héll...
+日本語の...
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			out := bytes.Buffer{}
+			require.NoError(t, query.NewStreamingPrinter(tc.params).PrintQueryResults(io.NopCloser(strings.NewReader(in)), &out))
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that can be read while the printer writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamingPrinter_FlushesWhenInputStalls(t *testing.T) {
+	t.Parallel()
+
+	pr, pw := io.Pipe()
+	out := lockedBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- query.NewStreamingPrinter(query.StreamingParams{ColumnWidth: 6}).PrintQueryResults(pr, &out)
+	}()
+
+	_, err := pw.Write([]byte(`#group,false,false,false
+#datatype,string,long,long
+#default,_result,,
+,result,table,_value
+,,0,1
+`))
+	require.NoError(t, err)
+	// The first row is printed while the printer waits for the rest of the table.
+	require.Eventually(t, func() bool { return strings.HasSuffix(out.String(), "     1\n") }, time.Second, time.Millisecond)
+
+	_, err = pw.Write([]byte(",,0,2\n"))
+	require.NoError(t, err)
+	require.NoError(t, pw.Close())
+	require.NoError(t, <-done)
+	require.Equal(t, `Result: _result
+Table: keys: []
+_va...
+------
+     1
+     2
+`, out.String())
+}
+
+func TestRowLimitPrinter_PrintQueryResults(t *testing.T) {
+	t.Parallel()
+
+	in := `#group,false,false,true,false
+#datatype,string,long,string,long
+#default,_result,,,
+,result,table,host,_value
+,,0,a,1
+,,0,a,2
+
+#group,false,false,true,false
+#datatype,string,long,string,long
+#default,_result,,,
+,result,table,host,_value
+,,1,b,3
+`
+
+	testCases := []struct {
+		name        string
+		limit       int
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:  "under limit",
+			limit: 3,
+			expected: `#group,false,false,true,false
+#datatype,string,long,string,long
+#default,_result,,,
+,result,table,host,_value
+,,0,a,1
+,,0,a,2
+#group,false,false,true,false
+#datatype,string,long,string,long
+#default,_result,,,
+,result,table,host,_value
+,,1,b,3
+`,
+		},
+		{
+			name:  "truncated",
+			limit: 1,
+			expected: `#group,false,false,true,false
+#datatype,string,long,string,long
+#default,_result,,,
+,result,table,host,_value
+,,0,a,1
+`,
+			expectedErr: "Output truncated after 1 rows, use --limit-rows to change the limit\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			stderr := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().WriteErr(gomock.Any()).DoAndReturn(stderr.Write).AnyTimes()
+
+			out := bytes.Buffer{}
+			printer := query.NewRowLimitPrinter(query.RawResultPrinter, tc.limit, stdio)
+			require.NoError(t, printer.PrintQueryResults(io.NopCloser(strings.NewReader(in)), &out))
+			require.Equal(t, tc.expected, out.String())
+			require.Equal(t, tc.expectedErr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// clearScreen moves the cursor to the top-left corner of the terminal and clears it.
+const clearScreen = "\033[H\033[2J"
+
+var (
+	colorChanged = color.New(color.FgYellow, color.Bold)
+	valueRegex   = regexp.MustCompile(`\S+`)
+)
+
+// watch re-runs the query every params.WatchInterval until ctx is canceled.
+//
+// When the output is interactive, each run's results replace the previous ones on screen.
+// Otherwise, results are appended as blocks tagged with the time of the run.
+func (c Client) watch(ctx context.Context, params *Params) error {
+	ticker := time.NewTicker(params.WatchInterval)
+	defer ticker.Stop()
+
+	interactive := c.StdIO.IsInteractive()
+	var prevLines []string
+	for {
+		out := bytes.Buffer{}
+		err := c.query(ctx, params, &out)
+		// Cancellation is the only way out of watch mode, so it isn't reported as an error.
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// Keep watching, a later run may succeed.
+			fmt.Fprintf(&out, "Error: %v\n", err)
+		}
+
+		lines := strings.Split(out.String(), "\n")
+		display := lines
+		if params.HighlightChanges && prevLines != nil {
+			display = highlightChanges(prevLines, lines, colorChanged)
+		}
+		prevLines = lines
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		var block bytes.Buffer
+		if interactive {
+			block.WriteString(clearScreen)
+			fmt.Fprintf(&block, "Every %s: %s\n\n", params.WatchInterval, now)
+		} else {
+			fmt.Fprintf(&block, "--- %s ---\n", now)
+		}
+		block.WriteString(strings.Join(display, "\n"))
+		if _, err := c.StdIO.Write(block.Bytes()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// highlightChanges compares the output of two runs line-by-line, highlighting every
+// whitespace-delimited value in cur that differs from the value at the same position in prev.
+func highlightChanges(prev, cur []string, highlight *color.Color) []string {
+	out := make([]string, len(cur))
+	for i, line := range cur {
+		var prevValues []string
+		if i < len(prev) {
+			if prev[i] == line {
+				out[i] = line
+				continue
+			}
+			prevValues = valueRegex.FindAllString(prev[i], -1)
+		}
+
+		var b strings.Builder
+		last := 0
+		for j, loc := range valueRegex.FindAllStringIndex(line, -1) {
+			value := line[loc[0]:loc[1]]
+			b.WriteString(line[last:loc[0]])
+			if j < len(prevValues) && prevValues[j] == value {
+				b.WriteString(value)
+			} else {
+				b.WriteString(highlight.Sprint(value))
+			}
+			last = loc[1]
+		}
+		b.WriteString(line[last:])
+		out[i] = b.String()
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlightChanges(t *testing.T) {
+	t.Parallel()
+
+	highlight := color.New(color.Bold)
+	highlight.EnableColor()
+	bold := func(s string) string { return "\x1b[1m" + s + "\x1b[0m" }
+
+	prev := []string{
+		"Result: _result",
+		"  host:string  _value:float",
+		"           a            10",
+		"           b            20",
+	}
+	cur := []string{
+		"Result: _result",
+		"  host:string  _value:float",
+		"           a            11",
+		"           b            20",
+		"           c            30",
+	}
+	expected := []string{
+		"Result: _result",
+		"  host:string  _value:float",
+		"           a            " + bold("11"),
+		"           b            20",
+		"           " + bold("c") + "            " + bold("30"),
+	}
+	require.Equal(t, expected, highlightChanges(prev, cur, highlight))
+}
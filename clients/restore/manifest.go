@@ -1,10 +1,12 @@
 package restore
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/influxdata/influx-cli/v2/api"
 	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
@@ -15,31 +17,50 @@ type versionSwitch struct {
 	Version int `json:"manifestVersion,omitempty"`
 }
 
-// readManifest parses the manifest file at the given path, converting it to the latest version of our manifest
-// if needed.
-func readManifest(path string) (manifest br.Manifest, err error) {
+// FindManifests returns the names of the backup manifests in storage, in ascending time order.
+func FindManifests(ctx context.Context, storage br.Storage) ([]string, error) {
+	names, err := storage.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backup manifests at %q: %w", storage.Location(""), err)
+	}
+	var manifests []string
+	for _, name := range names {
+		if strings.HasSuffix(name, "."+br.ManifestExtension) {
+			manifests = append(manifests, name)
+		}
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no backup manifests found at %q", storage.Location(""))
+	}
+	return manifests, nil
+}
+
+// ReadManifest parses the manifest file with the given name in storage, converting it to the latest version
+// of our manifest if needed.
+func ReadManifest(ctx context.Context, storage br.Storage, name string) (manifest br.Manifest, err error) {
 	var w struct {
 		versionSwitch
 		*br.Manifest
 		*legacyManifest
 	}
-	buf, err := os.ReadFile(path)
+	path := storage.Location(name)
+	buf, err := readAll(ctx, storage, name)
 	if err != nil {
-		return br.Manifest{}, fmt.Errorf("failed to read local manifest at %q: %w", path, err)
+		return br.Manifest{}, fmt.Errorf("failed to read manifest at %q: %w", path, err)
 	}
 
 	if err := json.Unmarshal(buf, &w.versionSwitch); err != nil {
 		return br.Manifest{}, fmt.Errorf("failed to check version of local manifest at %q: %w", path, err)
 	}
 	switch w.versionSwitch.Version {
-	case br.ManifestVersion:
+	case br.CompatibleManifestVersion, br.ManifestVersion:
 		err = json.Unmarshal(buf, &manifest)
 	case 0: // InfluxDB 2.0.x manifests didn't have a version field.
 		var lm legacyManifest
 		if err := json.Unmarshal(buf, &lm); err != nil {
 			return br.Manifest{}, fmt.Errorf("failed to parse legacy manifest at %q: %w", path, err)
 		}
-		manifest, err = convertManifest(path, lm)
+		manifest, err = convertManifest(ctx, storage, path, lm)
 	default:
 		return br.Manifest{}, fmt.Errorf("unsupported version %d found in manifest at %q", w.versionSwitch.Version, path)
 	}
@@ -50,10 +71,13 @@ func readManifest(path string) (manifest br.Manifest, err error) {
 // convertManifest converts a manifest from the 2.0.x CLI into the latest manifest schema.
 // NOTE: 2.0.x manifests didn't contain all the info needed by 2.1.x+, so this process requires opening & inspecting
 // the bolt file referenced by the legacy manifest.
-func convertManifest(path string, lm legacyManifest) (br.Manifest, error) {
-	// Extract bucket metadata from the local KV snapshot.
-	boltPath := filepath.Join(filepath.Dir(path), lm.KV.FileName)
-	metadata, err := br.ExtractBucketMetadata(boltPath)
+func convertManifest(ctx context.Context, storage br.Storage, path string, lm legacyManifest) (br.Manifest, error) {
+	// Extract bucket metadata from the KV snapshot, which must be stored locally to be read.
+	var metadata []api.BucketMetadataManifest
+	err := withLocalFile(ctx, storage, lm.KV.FileName, func(boltPath string) (err error) {
+		metadata, err = br.ExtractBucketMetadata(boltPath)
+		return
+	})
 	if err != nil {
 		return br.Manifest{}, err
 	}
@@ -91,6 +115,42 @@ func convertManifest(path string, lm legacyManifest) (br.Manifest, error) {
 	return m, nil
 }
 
+func readAll(ctx context.Context, storage br.Storage, name string) ([]byte, error) {
+	r, err := storage.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// withLocalFile calls fn with the path of a local copy of a file in storage, which is
+// downloaded to a temporary file if the storage isn't local.
+func withLocalFile(ctx context.Context, storage br.Storage, name string, fn func(path string) error) error {
+	if local, ok := storage.(br.LocalStorage); ok {
+		return fn(local.Path(name))
+	}
+
+	r, err := storage.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tmp, err := os.CreateTemp("", "influx-restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %q: %w", storage.Location(name), err)
+	}
+	return fn(tmp.Name())
+}
+
 // legacyManifest models the subset of data stored in 2.0.x CLI backup manifests that is needed for conversion
 // into the latest manifest format.
 type legacyManifest struct {
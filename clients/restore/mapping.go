@@ -0,0 +1,152 @@
+package restore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConflictPolicy decides what a partial restore does with buckets that already exist on the server.
+type ConflictPolicy int
+
+const (
+	// FailOnConflict fails the restore before anything is restored.
+	FailOnConflict ConflictPolicy = iota
+	// SkipOnConflict leaves the existing bucket untouched, and doesn't restore the backed-up one.
+	SkipOnConflict
+	// SuffixOnConflict restores the backed-up bucket under its name with a "-restored" suffix.
+	SuffixOnConflict
+)
+
+func (p *ConflictPolicy) Set(v string) error {
+	switch v {
+	case "fail":
+		*p = FailOnConflict
+	case "skip":
+		*p = SkipOnConflict
+	case "suffix":
+		*p = SuffixOnConflict
+	default:
+		return fmt.Errorf("unsupported conflict policy: %q", v)
+	}
+	return nil
+}
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case FailOnConflict:
+		return "fail"
+	case SkipOnConflict:
+		return "skip"
+	case SuffixOnConflict:
+		return "suffix"
+	default:
+		panic("Impossible!")
+	}
+}
+
+// wildcard matches every bucket of an organization in a bucket mapping.
+const wildcard = "*"
+
+// BucketMapping maps a backed-up bucket to the organization and name it is restored as.
+type BucketMapping struct {
+	OrgName    string
+	BucketName string
+
+	NewOrgName    string
+	NewBucketName string
+}
+
+func (m BucketMapping) String() string {
+	return fmt.Sprintf("%s/%s -> %s/%s", m.OrgName, m.BucketName, m.NewOrgName, m.NewBucketName)
+}
+
+// ReadBucketMappings reads a file of bucket mappings, one per line, like:
+//
+//	orgA/bucket1 -> orgB/bucket1-copy
+//
+// Mapping "orgA/* -> orgB/*" restores every bucket of an organization into another one under its
+// original name. Blank lines and lines starting with '#' are ignored.
+func ReadBucketMappings(path string) ([]BucketMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapping file: %w", err)
+	}
+	defer f.Close()
+
+	var mappings []BucketMapping
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m, err := parseBucketMapping(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping on line %d of %q: %w", lineNum, path, err)
+		}
+		source := m.OrgName + "/" + m.BucketName
+		if seen[source] {
+			return nil, fmt.Errorf("invalid mapping on line %d of %q: %q is already mapped", lineNum, path, source)
+		}
+		seen[source] = true
+		mappings = append(mappings, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mapping file %q: %w", path, err)
+	}
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("mapping file %q doesn't map any buckets", path)
+	}
+	return mappings, nil
+}
+
+func parseBucketMapping(line string) (BucketMapping, error) {
+	parts := strings.Split(line, "->")
+	if len(parts) != 2 {
+		return BucketMapping{}, fmt.Errorf("expected '<org>/<bucket> -> <new-org>/<new-bucket>', got %q", line)
+	}
+	split := func(s string) (string, string, error) {
+		s = strings.TrimSpace(s)
+		i := strings.Index(s, "/")
+		if i <= 0 || i == len(s)-1 {
+			return "", "", fmt.Errorf("expected '<org>/<bucket>', got %q", s)
+		}
+		return s[:i], s[i+1:], nil
+	}
+	var m BucketMapping
+	var err error
+	if m.OrgName, m.BucketName, err = split(parts[0]); err != nil {
+		return BucketMapping{}, err
+	}
+	if m.NewOrgName, m.NewBucketName, err = split(parts[1]); err != nil {
+		return BucketMapping{}, err
+	}
+	if (m.BucketName == wildcard) != (m.NewBucketName == wildcard) {
+		return BucketMapping{}, fmt.Errorf("%q must map every bucket of an organization to another organization, like 'orgA/* -> orgB/*'", line)
+	}
+	return m, nil
+}
+
+// mapBucket returns the mapping applying to a backed-up bucket, preferring mappings of the
+// bucket itself over mappings of its whole organization.
+func mapBucket(mappings []BucketMapping, orgName, bucketName string) (BucketMapping, bool) {
+	var orgMapping *BucketMapping
+	for i, m := range mappings {
+		if m.OrgName != orgName {
+			continue
+		}
+		if m.BucketName == bucketName {
+			return m, true
+		}
+		if m.BucketName == wildcard {
+			orgMapping = &mappings[i]
+		}
+	}
+	if orgMapping == nil {
+		return BucketMapping{}, false
+	}
+	return *orgMapping, true
+}
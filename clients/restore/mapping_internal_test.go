@@ -0,0 +1,308 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBucketMappings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		contents      string
+		expected      []BucketMapping
+		expectedError string
+	}{
+		{
+			name: "buckets and wildcards",
+			contents: `# Comments and blank lines are ignored.
+
+orgA/bucket1 -> orgB/bucket1-copy
+  orgA/*->orgC/*
+`,
+			expected: []BucketMapping{
+				{OrgName: "orgA", BucketName: "bucket1", NewOrgName: "orgB", NewBucketName: "bucket1-copy"},
+				{OrgName: "orgA", BucketName: "*", NewOrgName: "orgC", NewBucketName: "*"},
+			},
+		},
+		{
+			name:          "duplicate source",
+			contents:      "orgA/bucket1 -> orgB/bucket1\norgA/bucket1 -> orgC/bucket1\n",
+			expectedError: `invalid mapping on line 2 of "%s": "orgA/bucket1" is already mapped`,
+		},
+		{
+			name:          "duplicate wildcard",
+			contents:      "orgA/* -> orgB/*\n\norgA/* -> orgC/*\n",
+			expectedError: `invalid mapping on line 3 of "%s": "orgA/*" is already mapped`,
+		},
+		{
+			name:          "missing arrow",
+			contents:      "orgA/bucket1 orgB/bucket1\n",
+			expectedError: `invalid mapping on line 1 of "%s": expected '<org>/<bucket> -> <new-org>/<new-bucket>', got "orgA/bucket1 orgB/bucket1"`,
+		},
+		{
+			name:          "too many arrows",
+			contents:      "orgA/bucket1 -> orgB/bucket1 -> orgC/bucket1\n",
+			expectedError: `invalid mapping on line 1 of "%s": expected '<org>/<bucket> -> <new-org>/<new-bucket>', got "orgA/bucket1 -> orgB/bucket1 -> orgC/bucket1"`,
+		},
+		{
+			name:          "missing bucket",
+			contents:      "orgA/ -> orgB/bucket1\n",
+			expectedError: `invalid mapping on line 1 of "%s": expected '<org>/<bucket>', got "orgA/"`,
+		},
+		{
+			name:          "missing org",
+			contents:      "orgA/bucket1 -> bucket1\n",
+			expectedError: `invalid mapping on line 1 of "%s": expected '<org>/<bucket>', got "bucket1"`,
+		},
+		{
+			name:          "wildcard to bucket",
+			contents:      "orgA/* -> orgB/bucket1\n",
+			expectedError: `invalid mapping on line 1 of "%s": "orgA/* -> orgB/bucket1" must map every bucket of an organization to another organization, like 'orgA/* -> orgB/*'`,
+		},
+		{
+			name:          "bucket to wildcard",
+			contents:      "orgA/bucket1 -> orgB/*\n",
+			expectedError: `invalid mapping on line 1 of "%s": "orgA/bucket1 -> orgB/*" must map every bucket of an organization to another organization, like 'orgA/* -> orgB/*'`,
+		},
+		{
+			name:          "no mappings",
+			contents:      "# Nothing to see here\n\n",
+			expectedError: `mapping file "%s" doesn't map any buckets`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "mappings.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0600))
+
+			mappings, err := ReadBucketMappings(path)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, fmt.Sprintf(tc.expectedError, path))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mappings)
+		})
+	}
+}
+
+func TestMapBucket(t *testing.T) {
+	t.Parallel()
+
+	wildcardA := BucketMapping{OrgName: "orgA", BucketName: "*", NewOrgName: "orgB", NewBucketName: "*"}
+	bucketA := BucketMapping{OrgName: "orgA", BucketName: "bucket1", NewOrgName: "orgC", NewBucketName: "bucket1-copy"}
+
+	testCases := []struct {
+		name       string
+		mappings   []BucketMapping
+		orgName    string
+		bucketName string
+		expected   BucketMapping
+		notMapped  bool
+	}{
+		{
+			name:       "bucket mapping listed after org mapping",
+			mappings:   []BucketMapping{wildcardA, bucketA},
+			orgName:    "orgA",
+			bucketName: "bucket1",
+			expected:   bucketA,
+		},
+		{
+			name:       "bucket mapping listed before org mapping",
+			mappings:   []BucketMapping{bucketA, wildcardA},
+			orgName:    "orgA",
+			bucketName: "bucket1",
+			expected:   bucketA,
+		},
+		{
+			name:       "org mapping",
+			mappings:   []BucketMapping{bucketA, wildcardA},
+			orgName:    "orgA",
+			bucketName: "bucket2",
+			expected:   wildcardA,
+		},
+		{
+			name:       "unmapped bucket",
+			mappings:   []BucketMapping{bucketA},
+			orgName:    "orgA",
+			bucketName: "bucket2",
+			notMapped:  true,
+		},
+		{
+			name:       "unmapped org",
+			mappings:   []BucketMapping{bucketA, wildcardA},
+			orgName:    "orgB",
+			bucketName: "bucket1",
+			notMapped:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, ok := mapBucket(tc.mappings, tc.orgName, tc.bucketName)
+			require.Equal(t, !tc.notMapped, ok)
+			require.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestClient_plannedBuckets(t *testing.T) {
+	t.Parallel()
+
+	client := Client{
+		manifest: br.Manifest{Buckets: []br.ManifestBucketEntry{
+			{OrganizationName: "orgA", BucketName: "bucket1", BucketID: "1"},
+			{OrganizationName: "orgA", BucketName: "bucket2", BucketID: "2"},
+			{OrganizationName: "orgA", BucketName: "_monitoring", BucketID: "3"},
+			{OrganizationName: "orgB", BucketName: "bucket3", BucketID: "4"},
+		}},
+		mappings: []BucketMapping{
+			{OrgName: "orgA", BucketName: "*", NewOrgName: "orgC", NewBucketName: "*"},
+			{OrgName: "orgA", BucketName: "bucket2", NewOrgName: "orgD", NewBucketName: "bucket2-copy"},
+		},
+	}
+	buckets, err := client.plannedBuckets(&Params{})
+	require.NoError(t, err)
+
+	var restoredAs []string
+	for _, bkt := range buckets {
+		restoredAs = append(restoredAs, bkt.originalOrgName+"/"+bkt.originalBucketName+" -> "+bkt.OrganizationName+"/"+bkt.BucketName)
+	}
+	// orgB isn't mapped, and internal buckets are never restored.
+	require.Equal(t, []string{"orgA/bucket1 -> orgC/bucket1", "orgA/bucket2 -> orgD/bucket2-copy"}, restoredAs)
+
+	// Mapping two buckets to the same name is caught before anything is restored.
+	client.mappings = append(client.mappings, BucketMapping{OrgName: "orgB", BucketName: "bucket3", NewOrgName: "orgC", NewBucketName: "bucket1"})
+	_, err = client.plannedBuckets(&Params{})
+	require.EqualError(t, err, `buckets with IDs "1" and "4" would both be restored as bucket "bucket1" in organization "orgC"`)
+}
+
+// mockServer sets up organization and bucket APIs serving the organizations with the given names
+// and IDs, and the buckets with the given names in each organization ID. Buckets get IDs like
+// "<org ID>-<bucket name>".
+func mockServer(ctrl *gomock.Controller, orgIds map[string]string, buckets map[string][]string) (*mock.MockOrganizationsApi, *mock.MockBucketsApi) {
+	orgsApi := mock.NewMockOrganizationsApi(ctrl)
+	orgsApi.EXPECT().GetOrgs(gomock.Any()).Return(api.ApiGetOrgsRequest{ApiService: orgsApi}).AnyTimes()
+	orgsApi.EXPECT().GetOrgsExecute(gomock.Any()).DoAndReturn(func(req api.ApiGetOrgsRequest) (api.Organizations, error) {
+		for name, id := range orgIds {
+			id := id
+			if (req.GetOrg() == nil || *req.GetOrg() == name) && (req.GetOrgID() == nil || *req.GetOrgID() == id) {
+				return api.Organizations{Orgs: &[]api.Organization{{Id: &id, Name: name}}}, nil
+			}
+		}
+		return api.Organizations{}, &api.Error{Code: api.ERRORCODE_NOT_FOUND}
+	}).AnyTimes()
+
+	bucketsApi := mock.NewMockBucketsApi(ctrl)
+	bucketsApi.EXPECT().GetBuckets(gomock.Any()).Return(api.ApiGetBucketsRequest{ApiService: bucketsApi}).AnyTimes()
+	bucketsApi.EXPECT().GetBucketsExecute(gomock.Any()).DoAndReturn(func(req api.ApiGetBucketsRequest) (api.Buckets, error) {
+		found := []api.Bucket{}
+		for orgID, names := range buckets {
+			if req.GetOrgID() != nil && *req.GetOrgID() != orgID {
+				continue
+			}
+			for _, name := range names {
+				if req.GetName() == nil || *req.GetName() == name {
+					id, orgID := orgID+"-"+name, orgID
+					found = append(found, api.Bucket{Id: &id, Name: name, OrgID: &orgID})
+				}
+			}
+		}
+		return api.Buckets{Buckets: &found}, nil
+	}).AnyTimes()
+	return orgsApi, bucketsApi
+}
+
+func TestClient_resolveConflicts(t *testing.T) {
+	t.Parallel()
+
+	planned := func(org, bucket string) plannedBucket {
+		return plannedBucket{
+			ManifestBucketEntry: br.ManifestBucketEntry{OrganizationName: org, BucketName: bucket},
+			originalOrgName:     "old-" + org,
+			originalBucketName:  bucket,
+		}
+	}
+	orgIds := map[string]string{"orgA": "1111"}
+	serverBuckets := map[string][]string{"1111": {"exists", "taken", "taken-restored"}}
+	buckets := []plannedBucket{
+		planned("orgA", "exists"),
+		planned("orgA", "new"),
+		planned("orgA", "taken"),
+		// Also restored, so "taken-restored-2" can't be used for the conflicting "taken" bucket.
+		planned("orgA", "taken-restored-2"),
+		// Nothing can conflict with buckets of organizations which don't exist yet.
+		planned("orgB", "exists"),
+	}
+
+	testCases := []struct {
+		name            string
+		policy          ConflictPolicy
+		expectedResolve []string
+		expectedSkip    []string
+		expectedError   string
+	}{
+		{
+			name:          "fail",
+			policy:        FailOnConflict,
+			expectedError: `bucket "exists" already exists in organization "orgA", use --on-conflict to skip it or restore it under a new name`,
+		},
+		{
+			name:            "skip",
+			policy:          SkipOnConflict,
+			expectedResolve: []string{"orgA/new", "orgA/taken-restored-2", "orgB/exists"},
+			expectedSkip:    []string{"orgA/exists", "orgA/taken"},
+		},
+		{
+			name:            "suffix",
+			policy:          SuffixOnConflict,
+			expectedResolve: []string{"orgA/exists-restored", "orgA/new", "orgA/taken-restored-3", "orgA/taken-restored-2", "orgB/exists"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orgsApi, bucketsApi := mockServer(ctrl, orgIds, serverBuckets)
+			client := Client{OrganizationsApi: orgsApi, BucketsApi: bucketsApi}
+
+			resolved, skipped, err := client.resolveConflicts(context.Background(), tc.policy, buckets)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			names := func(buckets []plannedBucket) (names []string) {
+				for _, bkt := range buckets {
+					require.Equal(t, "old-"+bkt.OrganizationName, bkt.originalOrgName)
+					names = append(names, bkt.OrganizationName+"/"+bkt.BucketName)
+				}
+				return
+			}
+			require.Equal(t, tc.expectedResolve, names(resolved))
+			require.Equal(t, tc.expectedSkip, names(skipped))
+		})
+	}
+}
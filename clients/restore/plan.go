@@ -0,0 +1,296 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+)
+
+// Plan describes what a restore would do to the server.
+type Plan struct {
+	// LegacyServer is true if the server predates v2.1.0, so legacy APIs would be used to restore.
+	LegacyServer bool `json:"legacyServer"`
+	Full         bool `json:"full"`
+
+	// Metadata snapshots which would replace all metadata on the server, for full restores.
+	KV  *br.ManifestFileEntry `json:"kv,omitempty"`
+	SQL *br.ManifestFileEntry `json:"sql,omitempty"`
+
+	// Organizations used by a partial restore. A full restore replaces all organizations with the
+	// ones in the KV snapshot.
+	Orgs    []PlannedOrg    `json:"orgs"`
+	Buckets []PlannedBucket `json:"buckets"`
+
+	// Number of shards and bytes of shard snapshots which would be uploaded.
+	Shards int   `json:"shards"`
+	Size   int64 `json:"size"`
+}
+
+const (
+	actionCreate    = "create"
+	actionExisting  = "use existing"
+	actionOverwrite = "overwrite"
+	actionSkip      = "skip"
+	actionReplace   = "replace"
+	actionDelete    = "delete"
+)
+
+// PlannedOrg describes what a restore would do to an organization.
+type PlannedOrg struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+// PlannedBucket describes what a restore would do to a bucket.
+type PlannedBucket struct {
+	OrgName            string `json:"orgName"`
+	BucketName         string `json:"bucketName"`
+	OriginalOrgName    string `json:"originalOrgName,omitempty"`
+	OriginalBucketName string `json:"originalBucketName,omitempty"`
+	Action             string `json:"action"`
+	Shards             int    `json:"shards"`
+	Size               int64  `json:"size"`
+}
+
+// printPlan prints what a restore would do, only reading from the server.
+func (c Client) printPlan(ctx context.Context, params *Params, legacy bool) error {
+	plan, err := c.plan(ctx, params, legacy)
+	if err != nil {
+		return err
+	}
+	if c.PrintAsJSON {
+		return c.PrintJSON(plan)
+	}
+
+	var rows []map[string]interface{}
+	for _, f := range []struct {
+		kind string
+		file *br.ManifestFileEntry
+	}{{"KV snapshot", plan.KV}, {"SQL snapshot", plan.SQL}} {
+		if f.file != nil {
+			rows = append(rows, map[string]interface{}{
+				"Type":          f.kind,
+				"Name":          "",
+				"Restored From": f.file.FileName,
+				"Action":        actionReplace,
+				"Shards":        0,
+				"Size":          f.file.Size,
+			})
+		}
+	}
+	for _, o := range plan.Orgs {
+		rows = append(rows, map[string]interface{}{
+			"Type":          "organization",
+			"Name":          o.Name,
+			"Restored From": "",
+			"Action":        o.Action,
+			"Shards":        0,
+			"Size":          0,
+		})
+	}
+	for _, b := range plan.Buckets {
+		// Deleted buckets aren't restored from anything.
+		var restoredFrom string
+		if b.OriginalBucketName != "" {
+			restoredFrom = b.OriginalOrgName + "/" + b.OriginalBucketName
+		}
+		rows = append(rows, map[string]interface{}{
+			"Type":          "bucket",
+			"Name":          b.OrgName + "/" + b.BucketName,
+			"Restored From": restoredFrom,
+			"Action":        b.Action,
+			"Shards":        b.Shards,
+			"Size":          b.Size,
+		})
+	}
+	if err := c.PrintTable([]string{"Type", "Name", "Restored From", "Action", "Shards", "Size"}, rows...); err != nil {
+		return err
+	}
+
+	server := "running v2.1.0 or later"
+	if plan.LegacyServer {
+		server = "running a version older than v2.1.0, so legacy APIs would be used"
+	}
+	_, _ = fmt.Fprintf(c.StdIO, `Dry run complete. Would upload %d shards (%d bytes) to a server %s
+Rerun without '--dry-run' to execute
+`, plan.Shards, plan.Size, server)
+	return nil
+}
+
+// plan works out what a restore would do, using the same checks as the restore itself.
+func (c Client) plan(ctx context.Context, params *Params, legacy bool) (*Plan, error) {
+	plan := Plan{
+		LegacyServer: legacy,
+		Full:         params.Full,
+		Orgs:         []PlannedOrg{},
+		Buckets:      []PlannedBucket{},
+	}
+
+	if params.Full {
+		if err := c.checkFullRestore(legacy); err != nil {
+			return nil, err
+		}
+		plan.KV = &c.manifest.KV
+		plan.SQL = c.manifest.SQL
+		if err := c.planFullBuckets(ctx, &plan); err != nil {
+			return nil, err
+		}
+		return &plan, nil
+	}
+
+	buckets, err := c.plannedBuckets(params)
+	if err != nil {
+		return nil, err
+	}
+	buckets, skipped, err := c.resolveConflicts(ctx, params.OnConflict, buckets)
+	if err != nil {
+		return nil, err
+	}
+	orgIds := map[string]string{}
+	for _, bkt := range buckets {
+		if _, ok := orgIds[bkt.OrganizationName]; !ok {
+			orgID, err := c.findOrg(ctx, bkt.OrganizationName)
+			if err != nil {
+				return nil, err
+			}
+			orgIds[bkt.OrganizationName] = orgID
+			action := actionExisting
+			if orgID == "" {
+				action = actionCreate
+			}
+			plan.Orgs = append(plan.Orgs, PlannedOrg{Name: bkt.OrganizationName, Action: action})
+		}
+		// Conflicts with existing buckets were already resolved, so every bucket is created.
+		shards, size := countShards(bkt.ManifestBucketEntry)
+		plan.addBucket(bkt.planned(actionCreate, shards, size))
+	}
+	for _, bkt := range skipped {
+		plan.Buckets = append(plan.Buckets, bkt.planned(actionSkip, 0, 0))
+	}
+	return &plan, nil
+}
+
+// planFullBuckets plans the buckets of a full restore. Restoring the KV snapshot replaces all
+// organizations and buckets on the server, so buckets are matched with the server's by ID, and
+// buckets on the server which aren't in the backup would be deleted.
+func (c Client) planFullBuckets(ctx context.Context, plan *Plan) error {
+	existing, err := c.listBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	existingIds := make(map[string]bool, len(existing))
+	for _, bkt := range existing {
+		existingIds[bkt.GetId()] = true
+	}
+
+	buckets := make([]br.ManifestBucketEntry, len(c.manifest.Buckets))
+	copy(buckets, c.manifest.Buckets)
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if buckets[i].OrganizationName != buckets[j].OrganizationName {
+			return buckets[i].OrganizationName < buckets[j].OrganizationName
+		}
+		return buckets[i].BucketName < buckets[j].BucketName
+	})
+	backedUpIds := make(map[string]bool, len(buckets))
+	orgNames := map[string]string{}
+	for _, bkt := range buckets {
+		backedUpIds[bkt.BucketID] = true
+		orgNames[bkt.OrganizationID] = bkt.OrganizationName
+		action := actionCreate
+		if existingIds[bkt.BucketID] {
+			action = actionOverwrite
+		}
+		planned := plannedBucket{ManifestBucketEntry: bkt, originalOrgName: bkt.OrganizationName, originalBucketName: bkt.BucketName}
+		shards, size := countShards(bkt)
+		plan.addBucket(planned.planned(action, shards, size))
+	}
+
+	var deleted []PlannedBucket
+	for _, bkt := range existing {
+		if backedUpIds[bkt.GetId()] {
+			continue
+		}
+		orgName, ok := orgNames[bkt.GetOrgID()]
+		if !ok {
+			if orgName, err = c.orgName(ctx, bkt.GetOrgID()); err != nil {
+				return err
+			}
+			orgNames[bkt.GetOrgID()] = orgName
+		}
+		deleted = append(deleted, PlannedBucket{OrgName: orgName, BucketName: bkt.Name, Action: actionDelete})
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		if deleted[i].OrgName != deleted[j].OrgName {
+			return deleted[i].OrgName < deleted[j].OrgName
+		}
+		return deleted[i].BucketName < deleted[j].BucketName
+	})
+	plan.Buckets = append(plan.Buckets, deleted...)
+	return nil
+}
+
+// listBucketsPageSize is the number of buckets requested per page when listing all buckets.
+const listBucketsPageSize = 100
+
+// listBuckets lists every bucket on the server.
+func (c Client) listBuckets(ctx context.Context) ([]api.Bucket, error) {
+	// Paginated by offset for the same reasons as `influx bucket list`.
+	var buckets []api.Bucket
+	for {
+		res, err := c.GetBuckets(ctx).Limit(listBucketsPageSize).Offset(int32(len(buckets))).Execute()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list buckets: %w", err)
+		}
+		page := res.GetBuckets()
+		buckets = append(buckets, page...)
+		if len(page) < listBucketsPageSize {
+			return buckets, nil
+		}
+	}
+}
+
+// orgName gets the name of the org with the given ID.
+func (c Client) orgName(ctx context.Context, id string) (string, error) {
+	orgs, err := c.GetOrgs(ctx).OrgID(id).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to find organization %q: %w", id, err)
+	}
+	if len(orgs.GetOrgs()) == 0 {
+		return "", fmt.Errorf("no organization found with ID %q", id)
+	}
+	return orgs.GetOrgs()[0].Name, nil
+}
+
+func (p *Plan) addBucket(b PlannedBucket) {
+	p.Buckets = append(p.Buckets, b)
+	p.Shards += b.Shards
+	p.Size += b.Size
+}
+
+func (b plannedBucket) planned(action string, shards int, size int64) PlannedBucket {
+	return PlannedBucket{
+		OrgName:            b.OrganizationName,
+		BucketName:         b.BucketName,
+		OriginalOrgName:    b.originalOrgName,
+		OriginalBucketName: b.originalBucketName,
+		Action:             action,
+		Shards:             shards,
+		Size:               size,
+	}
+}
+
+// countShards returns the number of shards in a bucket's manifest, and the total size of their snapshots.
+func countShards(b br.ManifestBucketEntry) (n int, size int64) {
+	for _, rp := range b.RetentionPolicies {
+		for _, sg := range rp.ShardGroups {
+			for _, sh := range sg.Shards {
+				n++
+				size += sh.Size
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,137 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/clients"
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_printPlan(t *testing.T) {
+	t.Parallel()
+
+	bucket := func(orgID, org, name string, shardSizes ...int64) br.ManifestBucketEntry {
+		var shards []br.ManifestShardEntry
+		for i, size := range shardSizes {
+			shards = append(shards, br.ManifestShardEntry{ID: int64(i + 1), ManifestFileEntry: br.ManifestFileEntry{Size: size}})
+		}
+		return br.ManifestBucketEntry{
+			OrganizationID:    orgID,
+			OrganizationName:  org,
+			BucketID:          orgID + "-" + name,
+			BucketName:        name,
+			RetentionPolicies: []br.ManifestRetentionPolicy{{ShardGroups: []br.ManifestShardGroup{{Shards: shards}}}},
+		}
+	}
+	manifest := br.Manifest{
+		KV:  br.ManifestFileEntry{FileName: "kv.bolt.gz", Size: 100},
+		SQL: &br.ManifestFileEntry{FileName: "sql.sqlite.gz", Size: 50},
+		Buckets: []br.ManifestBucketEntry{
+			bucket("1111", "orgA", "kept", 10, 20),
+			bucket("1111", "orgA", "new", 5),
+			bucket("2222", "orgB", "moved", 1),
+		},
+	}
+	orgIds := map[string]string{"orgA": "1111", "orgC": "3333"}
+	serverBuckets := map[string][]string{
+		"1111": {"kept", "gone"},
+		"3333": {"other"},
+	}
+
+	newClient := func(t *testing.T, stdout *bytes.Buffer, asJSON bool) Client {
+		ctrl := gomock.NewController(t)
+		stdio := mock.NewMockStdIO(ctrl)
+		stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+		orgsApi, bucketsApi := mockServer(ctrl, orgIds, serverBuckets)
+		return Client{
+			CLI: clients.CLI{StdIO: stdio, PrintAsJSON: asJSON},
+			// No expected calls: planning must never change anything on the server.
+			RestoreApi:       mock.NewMockRestoreApi(ctrl),
+			OrganizationsApi: orgsApi,
+			BucketsApi:       bucketsApi,
+			manifest:         manifest,
+		}
+	}
+
+	t.Run("partial table", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, false)
+		require.NoError(t, client.printPlan(context.Background(), &Params{OnConflict: SkipOnConflict}, false))
+
+		var rows [][]string
+		for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+			rows = append(rows, strings.Fields(line))
+		}
+		require.Equal(t, [][]string{
+			{"Type", "Name", "Restored", "From", "Action", "Shards", "Size"},
+			{"organization", "orgA", "use", "existing", "0", "0"},
+			{"organization", "orgB", "create", "0", "0"},
+			{"bucket", "orgA/new", "orgA/new", "create", "1", "5"},
+			{"bucket", "orgB/moved", "orgB/moved", "create", "1", "1"},
+			{"bucket", "orgA/kept", "orgA/kept", "skip", "0", "0"},
+			{"Dry", "run", "complete.", "Would", "upload", "2", "shards", "(6", "bytes)", "to", "a", "server", "running", "v2.1.0", "or", "later"},
+			{"Rerun", "without", "'--dry-run'", "to", "execute"},
+		}, rows)
+	})
+
+	t.Run("full json", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, true)
+		require.NoError(t, client.printPlan(context.Background(), &Params{Full: true}, false))
+
+		var plan Plan
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &plan))
+		require.Equal(t, Plan{
+			Full: true,
+			KV:   &manifest.KV,
+			SQL:  manifest.SQL,
+			// A full restore replaces every organization, so it never creates any.
+			Orgs: []PlannedOrg{},
+			Buckets: []PlannedBucket{
+				{OrgName: "orgA", BucketName: "kept", OriginalOrgName: "orgA", OriginalBucketName: "kept", Action: actionOverwrite, Shards: 2, Size: 30},
+				{OrgName: "orgA", BucketName: "new", OriginalOrgName: "orgA", OriginalBucketName: "new", Action: actionCreate, Shards: 1, Size: 5},
+				{OrgName: "orgB", BucketName: "moved", OriginalOrgName: "orgB", OriginalBucketName: "moved", Action: actionCreate, Shards: 1, Size: 1},
+				// Buckets which aren't in the backup are removed by restoring the KV snapshot.
+				{OrgName: "orgA", BucketName: "gone", Action: actionDelete},
+				{OrgName: "orgC", BucketName: "other", Action: actionDelete},
+			},
+			Shards: 4,
+			Size:   36,
+		}, plan)
+	})
+
+	t.Run("partial conflict", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, false)
+		err := client.printPlan(context.Background(), &Params{OnConflict: FailOnConflict}, false)
+		require.EqualError(t, err, `bucket "kept" already exists in organization "orgA", use --on-conflict to skip it or restore it under a new name`)
+		require.Empty(t, stdout.String())
+	})
+
+	t.Run("full legacy", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := bytes.Buffer{}
+		client := newClient(t, &stdout, false)
+		storage, err := br.NewStorage(t.TempDir(), br.S3Config{})
+		require.NoError(t, err)
+		client.storage = storage
+		err = client.printPlan(context.Background(), &Params{Full: true}, true)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "target server's version too old to restore SQL metadata")
+		require.Empty(t, stdout.String())
+	})
+}
@@ -3,13 +3,13 @@ package restore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influx-cli/v2/api"
@@ -32,12 +32,22 @@ type Client struct {
 	ApiConfig
 
 	manifest br.Manifest
+	storage  br.Storage
+	secret   *br.EncryptionSecret
+	mappings []BucketMapping
 }
 
 type Params struct {
-	// Path to local backup data created using `influx backup`
+	// Path to local backup data created using `influx backup`, or an s3:// URL of backup data
+	// in S3-compatible object storage.
 	Path string
 
+	// S3 configures the connection to object storage, for S3 paths.
+	S3 br.S3Config
+
+	// Encryption configures the passphrase or key used to decrypt encrypted backups.
+	Encryption br.EncryptionParams
+
 	// Original ID/name of the organization to restore.
 	// If not set, all orgs will be restored.
 	clients.OrgParams
@@ -54,6 +64,13 @@ type Params struct {
 	// If not set, the bucket will be restored using its backed-up name.
 	NewBucketName string
 
+	// Path to a file mapping backed-up buckets to the organizations and names they're restored as,
+	// in the format read by ReadBucketMappings. If set, only mapped buckets are restored.
+	MappingFile string
+
+	// OnConflict decides what happens to buckets which already exist on the server.
+	OnConflict ConflictPolicy
+
 	// If true, replace all data on the server with the local backup.
 	// Otherwise only restore the requested org/bucket, leaving other data untouched.
 	Full bool
@@ -62,6 +79,12 @@ type Params struct {
 	// a plaintext operator token available. If the restored KV store does have a
 	// plaintext operator token available, then this is ignored.
 	OperatorToken string
+
+	// Parallelism is the number of shards uploaded concurrently.
+	Parallelism int
+
+	// If true, print what the restore would do without changing anything on the server.
+	DryRun bool
 }
 
 func (p *Params) matches(bkt br.ManifestBucketEntry) bool {
@@ -81,7 +104,26 @@ func (p *Params) matches(bkt br.ManifestBucketEntry) bool {
 }
 
 func (c *Client) Restore(ctx context.Context, params *Params) error {
-	if err := c.loadManifests(params.Path); err != nil {
+	if params.Parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+	storage, err := br.NewStorage(params.Path, params.S3)
+	if err != nil {
+		return err
+	}
+	c.storage = storage
+	if params.MappingFile != "" {
+		if c.mappings, err = ReadBucketMappings(params.MappingFile); err != nil {
+			return err
+		}
+	}
+	if c.secret, err = br.NewEncryptionSecret(params.Encryption); err != nil {
+		return err
+	}
+	if err := c.loadManifests(ctx); err != nil {
+		return err
+	}
+	if err := c.checkEncryption(); err != nil {
 		return err
 	}
 
@@ -91,34 +133,27 @@ func (c *Client) Restore(ctx context.Context, params *Params) error {
 		return err
 	}
 
+	if params.DryRun {
+		return c.printPlan(ctx, params, legacyServer)
+	}
 	if params.Full {
-		return c.fullRestore(ctx, params.Path, legacyServer, params.OperatorToken)
+		return c.fullRestore(ctx, params, legacyServer)
 	}
 	return c.partialRestore(ctx, params, legacyServer)
 }
 
-// loadManifests finds and merges all backup manifests stored in a given directory,
-// keeping the latest top-level metadata and latest metadata per-bucket.
-func (c *Client) loadManifests(path string) error {
-	// Read all manifest files from path, sort in ascending time.
-	manifests, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("*.%s", br.ManifestExtension)))
+// loadManifests finds and merges all backup manifests in storage, keeping the latest
+// top-level metadata and latest metadata per-bucket. Backups limited to a time range only
+// replace the shard groups they hold, keeping the rest from earlier backups.
+func (c *Client) loadManifests(ctx context.Context) error {
+	manifests, err := FindManifests(ctx, c.storage)
 	if err != nil {
-		return fmt.Errorf("failed to find backup manifests at %q: %w", path, err)
-	} else if len(manifests) == 0 {
-		return fmt.Errorf("no backup manifests found at %q", path)
+		return err
 	}
-	sort.Strings(manifests)
 
 	bucketManifests := map[string]br.ManifestBucketEntry{}
 	for _, manifestFile := range manifests {
-		// Skip file if it is a directory.
-		if fi, err := os.Stat(manifestFile); err != nil {
-			return fmt.Errorf("failed to inspect local manifest at %q: %w", manifestFile, err)
-		} else if fi.IsDir() {
-			continue
-		}
-
-		manifest, err := readManifest(manifestFile)
+		manifest, err := ReadManifest(ctx, c.storage, manifestFile)
 		if err != nil {
 			return err
 		}
@@ -127,12 +162,20 @@ func (c *Client) loadManifests(path string) error {
 		c.manifest.KV = manifest.KV
 		c.manifest.SQL = manifest.SQL
 
+		if manifest.IsPartialRange() {
+			log.Printf("INFO: Backup %q only holds shard groups overlapping %s\n", manifestFile, describeRange(manifest))
+		}
+
 		// Keep the latest manifest per-bucket.
 		for _, bkt := range manifest.Buckets {
 			// NOTE: Deduplicate here by keeping only the latest entry for each `<org-name>/<bucket-name>` pair.
 			// This prevents "bucket already exists" errors during the restore when the backup manifests contain
 			// entries for multiple buckets with the same name (which can happen when a bucket is deleted & re-created).
-			bucketManifests[fmt.Sprintf("%s/%s", bkt.OrganizationName, bkt.BucketName)] = bkt
+			key := fmt.Sprintf("%s/%s", bkt.OrganizationName, bkt.BucketName)
+			if prev, ok := bucketManifests[key]; ok && manifest.IsPartialRange() && prev.BucketID == bkt.BucketID {
+				bkt = mergeShardGroups(prev, bkt)
+			}
+			bucketManifests[key] = bkt
 		}
 	}
 
@@ -141,32 +184,122 @@ func (c *Client) loadManifests(path string) error {
 		c.manifest.Buckets = append(c.manifest.Buckets, bkt)
 	}
 
+	return c.checkShardFiles(ctx)
+}
+
+// mergeShardGroups returns the metadata of a bucket from a backup limited to a time range,
+// along with the shard groups captured by an earlier backup of the bucket which it doesn't hold.
+func mergeShardGroups(earlier, partial br.ManifestBucketEntry) br.ManifestBucketEntry {
+	merged := partial
+	merged.RetentionPolicies = make([]br.ManifestRetentionPolicy, 0, len(partial.RetentionPolicies))
+	earlierRPs := make(map[string]br.ManifestRetentionPolicy, len(earlier.RetentionPolicies))
+	for _, rp := range earlier.RetentionPolicies {
+		earlierRPs[rp.Name] = rp
+	}
+	for _, rp := range partial.RetentionPolicies {
+		if earlierRP, ok := earlierRPs[rp.Name]; ok {
+			held := make(map[int64]bool, len(rp.ShardGroups))
+			for _, sg := range rp.ShardGroups {
+				held[sg.ID] = true
+			}
+			shardGroups := append([]br.ManifestShardGroup{}, rp.ShardGroups...)
+			for _, sg := range earlierRP.ShardGroups {
+				if !held[sg.ID] {
+					shardGroups = append(shardGroups, sg)
+				}
+			}
+			sort.SliceStable(shardGroups, func(i, j int) bool {
+				return shardGroups[i].StartTime.Before(shardGroups[j].StartTime)
+			})
+			rp.ShardGroups = shardGroups
+			delete(earlierRPs, rp.Name)
+		}
+		merged.RetentionPolicies = append(merged.RetentionPolicies, rp)
+	}
+	// Keep retention policies the partial backup didn't capture at all.
+	for _, rp := range earlier.RetentionPolicies {
+		if _, ok := earlierRPs[rp.Name]; ok {
+			merged.RetentionPolicies = append(merged.RetentionPolicies, rp)
+		}
+	}
+	return merged
+}
+
+// describeRange describes the time range of a backup limited to one.
+func describeRange(m br.Manifest) string {
+	switch {
+	case m.Start != nil && m.Stop != nil:
+		return fmt.Sprintf("%s to %s", m.Start.Format(time.RFC3339Nano), m.Stop.Format(time.RFC3339Nano))
+	case m.Start != nil:
+		return fmt.Sprintf("%s onwards", m.Start.Format(time.RFC3339Nano))
+	default:
+		return fmt.Sprintf("times before %s", m.Stop.Format(time.RFC3339Nano))
+	}
+}
+
+// checkShardFiles checks that the snapshot of every shard in the manifest exists, since
+// incremental backups refer to the files of the backups they were taken from.
+func (c Client) checkShardFiles(ctx context.Context) error {
+	for _, b := range c.manifest.Buckets {
+		for _, rp := range b.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				for _, s := range sg.Shards {
+					if s.Dir == "" {
+						continue
+					}
+					f, err := c.storage.Open(ctx, s.Name())
+					if err != nil {
+						return fmt.Errorf("snapshot of shard %d in bucket %q was captured by an earlier backup at %q, which can't be read: %w",
+							s.ID, b.BucketName, c.storage.Location(s.Dir), err)
+					}
+					_ = f.Close()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkEncryption checks that every encrypted file in the manifest can be decrypted with the
+// given secret, before anything is restored.
+func (c Client) checkEncryption() error {
+	for _, f := range c.manifest.Files() {
+		if f.Encryption == nil {
+			continue
+		}
+		if c.secret == nil {
+			return fmt.Errorf("backup at %q is encrypted, pass its encryption passphrase or key file to restore it", c.storage.Location(""))
+		}
+		if !c.secret.Matches(*f.Encryption) {
+			return fmt.Errorf("cannot decrypt %q: %w", c.storage.Location(f.Name()), br.ErrWrongEncryptionKey)
+		}
+	}
 	return nil
 }
 
 // fullRestore completely replaces all metadata and data on the server with the contents of a local backup.
-func (c Client) fullRestore(ctx context.Context, path string, legacy bool, operatorToken string) error {
-	if legacy && c.manifest.SQL != nil {
-		return fmt.Errorf("cannot fully restore data from %s: target server's version too old to restore SQL metadata", path)
+func (c Client) fullRestore(ctx context.Context, params *Params, legacy bool) error {
+	if err := c.checkFullRestore(legacy); err != nil {
+		return err
 	}
 
 	// Make sure we can read both local metadata snapshots before
-	readKv := readFileGzipped
+	readKv := c.readFileGzipped
 	if legacy {
 		// The legacy API didn't support gzipped uploads.
-		readKv = readFileGunzipped
+		readKv = c.readFileGunzipped
 	}
-	kvBytes, err := readKv(path, c.manifest.KV)
+	kvBytes, err := readKv(ctx, c.manifest.KV)
 	if err != nil {
-		return fmt.Errorf("failed to open local KV backup at %q: %w", filepath.Join(path, c.manifest.KV.FileName), err)
+		return fmt.Errorf("failed to open KV backup at %q: %w", c.storage.Location(c.manifest.KV.Name()), err)
 	}
 	defer kvBytes.Close()
 
 	var sqlBytes io.ReadCloser
 	if c.manifest.SQL != nil {
-		sqlBytes, err = readFileGzipped(path, *c.manifest.SQL)
+		sqlBytes, err = c.readFileGzipped(ctx, *c.manifest.SQL)
 		if err != nil {
-			return fmt.Errorf("failed to open local SQL backup at %q: %w", filepath.Join(path, c.manifest.SQL.FileName), err)
+			return fmt.Errorf("failed to open SQL backup at %q: %w", c.storage.Location(c.manifest.SQL.Name()), err)
 		}
 		defer sqlBytes.Close()
 	}
@@ -189,7 +322,7 @@ func (c Client) fullRestore(ctx context.Context, path string, legacy bool, opera
 	}
 	if len(newOperatorToken) == 0 {
 		// The backup might have used a hashed operator token, so try operator token from command line.
-		newOperatorToken = operatorToken
+		newOperatorToken = params.OperatorToken
 	}
 	if len(newOperatorToken) > 0 {
 		newAuthorization := fmt.Sprintf("Token %s", newOperatorToken)
@@ -214,65 +347,58 @@ func (c Client) fullRestore(ctx context.Context, path string, legacy bool, opera
 	}
 
 	// Drill down through bucket manifests to reach shard info, and upload it.
+	var shards []br.ManifestShardEntry
 	for _, b := range c.manifest.Buckets {
 		for _, rp := range b.RetentionPolicies {
 			for _, sg := range rp.ShardGroups {
-				for _, s := range sg.Shards {
-					if err := c.restoreShard(ctx, path, s, legacy); err != nil {
-						return err
-					}
-				}
+				shards = append(shards, sg.Shards...)
 			}
 		}
 	}
 
+	return c.restoreShards(ctx, params, shards, legacy)
+}
+
+// checkFullRestore checks that the server can restore all the metadata in the backup.
+func (c Client) checkFullRestore(legacy bool) error {
+	if legacy && c.manifest.SQL != nil {
+		return fmt.Errorf("cannot fully restore data from %s: target server's version too old to restore SQL metadata", c.storage.Location(""))
+	}
 	return nil
 }
 
 // partialRestore creates a bucket (or buckets) on the target server, and seeds it with data
 // from a local backup.
 func (c Client) partialRestore(ctx context.Context, params *Params, legacy bool) (err error) {
-	orgIds := map[string]string{}
+	buckets, err := c.plannedBuckets(params)
+	if err != nil {
+		return err
+	}
+	// Resolve every conflict before anything is restored, so the restore can't fail part-way
+	// through because of one.
+	if buckets, _, err = c.resolveConflicts(ctx, params.OnConflict, buckets); err != nil {
+		return err
+	}
 
-	for _, bkt := range c.manifest.Buckets {
-		// Skip internal buckets.
-		if strings.HasPrefix(bkt.BucketName, "_") {
-			continue
-		}
-		if !params.matches(bkt) {
-			continue
-		}
+	orgIds := map[string]string{}
+	// Shards are uploaded once all buckets have been created, so uploads can run in parallel.
+	var shards []br.ManifestShardEntry
 
+	for _, bkt := range buckets {
 		orgName := bkt.OrganizationName
-		// Before this method is called, we ensure that new-org-name is only set if
-		// a filter on org-name or org-id is set. If that check passes and execution
-		// reaches this code, we can assume that all buckets matching the filter come
-		// from the same org, so we can swap in the new org name unconditionally.
-		if params.NewOrgName != "" {
-			orgName = params.NewOrgName
-		}
-
 		if _, ok := orgIds[orgName]; !ok {
 			orgIds[orgName], err = c.restoreOrg(ctx, orgName)
 			if err != nil {
 				return
 			}
 		}
-		bkt.OrganizationName = orgName
 		bkt.OrganizationID = orgIds[orgName]
 
-		// By the same reasoning as above, if new-bucket-name is non-empty we know
-		// filters must have been set to ensure we only match 1 bucket, so we can
-		// swap the name without additional checks.
-		if params.NewBucketName != "" {
-			bkt.BucketName = params.NewBucketName
-		}
-
 		restoreBucket := c.restoreBucket
 		if legacy {
 			restoreBucket = c.restoreBucketLegacy
 		}
-		shardIdMap, err := restoreBucket(ctx, bkt)
+		shardIdMap, err := restoreBucket(ctx, bkt.ManifestBucketEntry)
 		if err != nil {
 			return fmt.Errorf("failed to restore bucket %q: %w", bkt.BucketName, err)
 		}
@@ -286,15 +412,161 @@ func (c Client) partialRestore(ctx context.Context, params *Params, legacy bool)
 						continue
 					}
 					sh.ID = newID
-					if err := c.restoreShard(ctx, params.Path, sh, legacy); err != nil {
-						return err
-					}
+					shards = append(shards, sh)
 				}
 			}
 		}
 	}
 
-	return nil
+	return c.restoreShards(ctx, params, shards, legacy)
+}
+
+// plannedBucket is a backed-up bucket selected for a partial restore, renamed to the organization
+// and name it is restored as.
+type plannedBucket struct {
+	br.ManifestBucketEntry
+	originalOrgName    string
+	originalBucketName string
+}
+
+// plannedBuckets returns the backed-up buckets selected for a partial restore, sorted by
+// organization and bucket name, and renamed to the organizations and names they're restored as.
+func (c Client) plannedBuckets(params *Params) ([]plannedBucket, error) {
+	var buckets []plannedBucket
+	used := map[BucketMapping]bool{}
+	for _, bkt := range c.manifest.Buckets {
+		// Skip internal buckets.
+		if strings.HasPrefix(bkt.BucketName, "_") {
+			continue
+		}
+		if !params.matches(bkt) {
+			continue
+		}
+		planned := plannedBucket{originalOrgName: bkt.OrganizationName, originalBucketName: bkt.BucketName}
+
+		if c.mappings != nil {
+			m, ok := mapBucket(c.mappings, bkt.OrganizationName, bkt.BucketName)
+			if !ok {
+				log.Printf("INFO: Bucket %q in organization %q isn't in the mapping file, skipping\n", bkt.BucketName, bkt.OrganizationName)
+				continue
+			}
+			used[m] = true
+			bkt.OrganizationName = m.NewOrgName
+			if m.NewBucketName != wildcard {
+				bkt.BucketName = m.NewBucketName
+			}
+		}
+
+		// Before this method is called, we ensure that new-org-name is only set if
+		// a filter on org-name or org-id is set. If that check passes and execution
+		// reaches this code, we can assume that all buckets matching the filter come
+		// from the same org, so we can swap in the new org name unconditionally.
+		if params.NewOrgName != "" {
+			bkt.OrganizationName = params.NewOrgName
+		}
+		// By the same reasoning as above, if new-bucket-name is non-empty we know
+		// filters must have been set to ensure we only match 1 bucket, so we can
+		// swap the name without additional checks.
+		if params.NewBucketName != "" {
+			bkt.BucketName = params.NewBucketName
+		}
+		planned.ManifestBucketEntry = bkt
+		buckets = append(buckets, planned)
+	}
+	for _, m := range c.mappings {
+		if !used[m] {
+			log.Printf("WARN: No bucket in the backup matches mapping %q\n", m)
+		}
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if buckets[i].OrganizationName != buckets[j].OrganizationName {
+			return buckets[i].OrganizationName < buckets[j].OrganizationName
+		}
+		return buckets[i].BucketName < buckets[j].BucketName
+	})
+	for i := 1; i < len(buckets); i++ {
+		prev, bkt := buckets[i-1], buckets[i]
+		if prev.OrganizationName == bkt.OrganizationName && prev.BucketName == bkt.BucketName {
+			return nil, fmt.Errorf("buckets with IDs %q and %q would both be restored as bucket %q in organization %q",
+				prev.BucketID, bkt.BucketID, bkt.BucketName, bkt.OrganizationName)
+		}
+	}
+	return buckets, nil
+}
+
+// resolveConflicts applies a conflict policy to the planned buckets which already exist on the
+// server, returning the buckets to restore and the buckets skipped.
+func (c Client) resolveConflicts(ctx context.Context, policy ConflictPolicy, buckets []plannedBucket) (resolved, skipped []plannedBucket, err error) {
+	planned := make(map[string]bool, len(buckets))
+	for _, bkt := range buckets {
+		planned[bkt.OrganizationName+"/"+bkt.BucketName] = true
+	}
+
+	orgIds := map[string]string{}
+	resolved = make([]plannedBucket, 0, len(buckets))
+	for _, bkt := range buckets {
+		orgID, ok := orgIds[bkt.OrganizationName]
+		if !ok {
+			if orgID, err = c.findOrg(ctx, bkt.OrganizationName); err != nil {
+				return nil, nil, err
+			}
+			orgIds[bkt.OrganizationName] = orgID
+		}
+		// Buckets can't conflict with anything in organizations that don't exist yet.
+		if orgID == "" {
+			resolved = append(resolved, bkt)
+			continue
+		}
+		exists, err := c.bucketExists(ctx, orgID, bkt.BucketName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			resolved = append(resolved, bkt)
+			continue
+		}
+
+		switch policy {
+		case FailOnConflict:
+			return nil, nil, fmt.Errorf("bucket %q already exists in organization %q, use --on-conflict to skip it or restore it under a new name",
+				bkt.BucketName, bkt.OrganizationName)
+		case SkipOnConflict:
+			log.Printf("WARN: Bucket %q already exists in organization %q, skipping\n", bkt.BucketName, bkt.OrganizationName)
+			skipped = append(skipped, bkt)
+		case SuffixOnConflict:
+			name, err := c.unusedBucketName(ctx, orgID, bkt.ManifestBucketEntry, planned)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.Printf("INFO: Bucket %q already exists in organization %q, restoring it as %q\n", bkt.BucketName, bkt.OrganizationName, name)
+			planned[bkt.OrganizationName+"/"+name] = true
+			bkt.BucketName = name
+			resolved = append(resolved, bkt)
+		}
+	}
+	return resolved, skipped, nil
+}
+
+// unusedBucketName returns the first of "<name>-restored", "<name>-restored-2", and so on which
+// neither exists on the server nor is planned to be restored.
+func (c Client) unusedBucketName(ctx context.Context, orgID string, bkt br.ManifestBucketEntry, planned map[string]bool) (string, error) {
+	for n := 1; ; n++ {
+		name := bkt.BucketName + "-restored"
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		if planned[bkt.OrganizationName+"/"+name] {
+			continue
+		}
+		exists, err := c.bucketExists(ctx, orgID, name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+	}
 }
 
 // restoreBucket creates a new bucket and pre-generates a set of shards within that bucket, returning
@@ -361,6 +633,21 @@ func (c Client) restoreBucketLegacy(ctx context.Context, bkt br.ManifestBucketEn
 
 // restoreOrg gets the ID for the org with the given name, creating the org if it doesn't already exist.
 func (c Client) restoreOrg(ctx context.Context, name string) (string, error) {
+	id, err := c.findOrg(ctx, name)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	// Create any missing orgs.
+	newOrg, err := c.PostOrgs(ctx).PostOrganizationRequest(api.PostOrganizationRequest{Name: name}).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to create organization %q: %w", name, err)
+	}
+	return *newOrg.Id, nil
+}
+
+// findOrg gets the ID for the org with the given name, or an empty ID if it doesn't exist.
+func (c Client) findOrg(ctx context.Context, name string) (string, error) {
 	// NOTE: Our orgs API returns a 404 instead of an empty list when filtering by a specific name.
 	orgs, err := c.GetOrgs(ctx).Org(name).Execute()
 	if err != nil {
@@ -371,17 +658,23 @@ func (c Client) restoreOrg(ctx context.Context, name string) (string, error) {
 
 	// If we've gotten this far and err != nil, it means err was a 404.
 	if err != nil || len(orgs.GetOrgs()) == 0 {
-		// Create any missing orgs.
-		newOrg, err := c.PostOrgs(ctx).PostOrganizationRequest(api.PostOrganizationRequest{Name: name}).Execute()
-		if err != nil {
-			return "", fmt.Errorf("failed to create organization %q: %w", name, err)
-		}
-		return *newOrg.Id, nil
+		return "", nil
 	}
-
 	return *orgs.GetOrgs()[0].Id, nil
 }
 
+// bucketExists checks whether a bucket with the given name exists in an org.
+func (c Client) bucketExists(ctx context.Context, orgID, name string) (bool, error) {
+	buckets, err := c.GetBuckets(ctx).OrgID(orgID).Name(name).Execute()
+	if err != nil {
+		if apiErr, ok := err.(api.ApiError); ok && apiErr.ErrorCode() == api.ERRORCODE_NOT_FOUND {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check existence of bucket %q: %w", name, err)
+	}
+	return len(buckets.GetBuckets()) > 0, nil
+}
+
 func bucketToDBI(b br.ManifestBucketEntry) *br.DatabaseInfo {
 	dbi := br.DatabaseInfo{
 		Name:                   &b.BucketID,
@@ -461,11 +754,10 @@ func shardToSI(shard br.ManifestShardEntry) *br.ShardInfo {
 	return &si
 }
 
-// readFileGzipped opens a local file and returns a reader of its contents,
+// readFileGzipped opens a file in storage and returns a reader of its decrypted contents,
 // compressed with gzip.
-func readFileGzipped(path string, file br.ManifestFileEntry) (io.ReadCloser, error) {
-	fullPath := filepath.Join(path, file.FileName)
-	f, err := os.Open(fullPath)
+func (c Client) readFileGzipped(ctx context.Context, file br.ManifestFileEntry) (io.ReadCloser, error) {
+	f, err := br.OpenFile(ctx, c.storage, file, c.secret)
 	if err != nil {
 		return nil, err
 	}
@@ -475,11 +767,10 @@ func readFileGzipped(path string, file br.ManifestFileEntry) (io.ReadCloser, err
 	return gzip.NewGzipPipe(f), nil
 }
 
-// readFileGunzipped opens a local file and returns a reader of its contents,
+// readFileGunzipped opens a file in storage and returns a reader of its decrypted contents,
 // gunzipping it if it is compressed.
-func readFileGunzipped(path string, file br.ManifestFileEntry) (io.ReadCloser, error) {
-	fullPath := filepath.Join(path, file.FileName)
-	f, err := os.Open(fullPath)
+func (c Client) readFileGunzipped(ctx context.Context, file br.ManifestFileEntry) (io.ReadCloser, error) {
+	f, err := br.OpenFile(ctx, c.storage, file, c.secret)
 	if err != nil {
 		return nil, err
 	}
@@ -494,17 +785,39 @@ func readFileGunzipped(path string, file br.ManifestFileEntry) (io.ReadCloser, e
 	return reader, nil
 }
 
-// restoreShard overwrites the contents of a single shard on the server using TSM stored in a local backup.
-func (c Client) restoreShard(ctx context.Context, path string, m br.ManifestShardEntry, legacy bool) error {
-	read := readFileGzipped
+// restoreShards uploads the TSM snapshots of shards to the server, using up to params.Parallelism
+// concurrent uploads.
+func (c Client) restoreShards(ctx context.Context, params *Params, shards []br.ManifestShardEntry, legacy bool) error {
+	var (
+		mu   sync.Mutex
+		done int
+		size int64
+	)
+	return br.RunParallel(ctx, params.Parallelism, len(shards), func(ctx context.Context, i int) error {
+		if err := c.restoreShard(ctx, shards[i], legacy); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		size += shards[i].Size
+		log.Printf("INFO: Restored %d/%d shards (%d bytes)\n", done, len(shards), size)
+		return nil
+	})
+}
+
+// restoreShard overwrites the contents of a single shard on the server using TSM stored in a backup.
+func (c Client) restoreShard(ctx context.Context, m br.ManifestShardEntry, legacy bool) error {
+	read := c.readFileGzipped
 	if legacy {
 		// The legacy API didn't support gzipped uploads.
-		read = readFileGunzipped
+		read = c.readFileGunzipped
 	}
-	// Make sure we can read the local snapshot.
-	tsmBytes, err := read(path, m.ManifestFileEntry)
+	// Make sure we can read the snapshot.
+	tsmBytes, err := read(ctx, m.ManifestFileEntry)
 	if err != nil {
-		return fmt.Errorf("failed to open local TSM snapshot at %q: %w", filepath.Join(path, m.FileName), err)
+		return fmt.Errorf("failed to open TSM snapshot at %q: %w", c.storage.Location(m.Name()), err)
 	}
 	defer tsmBytes.Close()
 
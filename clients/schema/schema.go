@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/pkg/duration"
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+// DefaultStart is how far back schema is explored when no start is given, which
+// matches the default of the Flux schema package.
+const DefaultStart = "-30d"
+
+// Client explores the measurements, fields and tags of a bucket using the Flux schema package.
+type Client struct {
+	clients.CLI
+	api.QueryApi
+}
+
+type Params struct {
+	clients.OrgParams
+	Bucket      string
+	Measurement string
+	// Tag is the tag key whose values are listed.
+	Tag string
+	// Start is either an RFC3339 time, or a negative duration relative to now.
+	Start string
+}
+
+// systemTagKeys are included in the tag keys returned by the schema package, but aren't tags.
+var systemTagKeys = map[string]bool{
+	"_start":       true,
+	"_stop":        true,
+	"_field":       true,
+	"_measurement": true,
+}
+
+func (c Client) ListMeasurements(ctx context.Context, params *Params) ([]string, error) {
+	return c.list(ctx, params, "schema.measurements", "")
+}
+
+func (c Client) ListFields(ctx context.Context, params *Params) ([]string, error) {
+	if params.Measurement != "" {
+		return c.list(ctx, params, "schema.measurementFieldKeys", "measurement: "+query.StringLiteral(params.Measurement))
+	}
+	return c.list(ctx, params, "schema.fieldKeys", "")
+}
+
+func (c Client) ListTagKeys(ctx context.Context, params *Params) ([]string, error) {
+	var keys []string
+	var err error
+	if params.Measurement != "" {
+		keys, err = c.list(ctx, params, "schema.measurementTagKeys", "measurement: "+query.StringLiteral(params.Measurement))
+	} else {
+		keys, err = c.list(ctx, params, "schema.tagKeys", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	tagKeys := keys[:0]
+	for _, k := range keys {
+		if !systemTagKeys[k] {
+			tagKeys = append(tagKeys, k)
+		}
+	}
+	return tagKeys, nil
+}
+
+func (c Client) ListTagValues(ctx context.Context, params *Params) ([]string, error) {
+	if params.Tag == "" {
+		return nil, errors.New("must specify a tag key")
+	}
+	tag := "tag: " + query.StringLiteral(params.Tag)
+	if params.Measurement != "" {
+		return c.list(ctx, params, "schema.measurementTagValues", "measurement: "+query.StringLiteral(params.Measurement)+", "+tag)
+	}
+	return c.list(ctx, params, "schema.tagValues", tag)
+}
+
+func (c Client) Measurements(ctx context.Context, params *Params) error {
+	measurements, err := c.ListMeasurements(ctx, params)
+	if err != nil {
+		return err
+	}
+	return c.printValues("Measurement", measurements)
+}
+
+func (c Client) Fields(ctx context.Context, params *Params) error {
+	fields, err := c.ListFields(ctx, params)
+	if err != nil {
+		return err
+	}
+	return c.printValues("Field", fields)
+}
+
+func (c Client) TagKeys(ctx context.Context, params *Params) error {
+	keys, err := c.ListTagKeys(ctx, params)
+	if err != nil {
+		return err
+	}
+	return c.printValues("Tag Key", keys)
+}
+
+func (c Client) TagValues(ctx context.Context, params *Params) error {
+	values, err := c.ListTagValues(ctx, params)
+	if err != nil {
+		return err
+	}
+	return c.printValues("Tag Value", values)
+}
+
+// list calls the schema function fn with args, and returns the values it returns in sorted order.
+func (c Client) list(ctx context.Context, params *Params, fn string, args string) ([]string, error) {
+	if params.Bucket == "" {
+		return nil, clients.ErrMustSpecifyBucket
+	}
+	start := params.Start
+	if start == "" {
+		start = DefaultStart
+	}
+	if err := validateStart(start); err != nil {
+		return nil, err
+	}
+	if args != "" {
+		args = ", " + args
+	}
+	flux := fmt.Sprintf("import \"influxdata/influxdb/schema\"\n%s(bucket: %s%s, start: %s)",
+		fn, query.StringLiteral(params.Bucket), args, start)
+
+	body, err := query.PostQuery(ctx, c.QueryApi, params.OrgParams, c.ActiveConfig.Org, query.BuildDefaultAST(flux))
+	if err != nil {
+		return nil, err
+	}
+	result := fluxcsv.NewQueryTableResult(body)
+	defer result.Close()
+
+	var values []string
+	for result.Next() {
+		if v, ok := result.Record().Value().(string); ok {
+			values = append(values, v)
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+// validateStart checks that start can be used as a Flux time literal.
+func validateStart(start string) error {
+	if _, err := time.Parse(time.RFC3339Nano, start); err == nil {
+		return nil
+	}
+	if strings.HasPrefix(start, "-") {
+		if d, err := duration.RawDurationToTimeDuration(start[1:]); err == nil && d > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("start %q must be an RFC3339 time or a negative duration, like %s", start, DefaultStart)
+}
+
+func (c Client) printValues(header string, values []string) error {
+	if c.PrintAsJSON {
+		if values == nil {
+			values = []string{}
+		}
+		return c.PrintJSON(values)
+	}
+	rows := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		rows[i] = map[string]interface{}{header: v}
+	}
+	return c.PrintTable([]string{header}, rows...)
+}
@@ -0,0 +1,130 @@
+package schema_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/influxdata/influx-cli/v2/api"
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/schema"
+	"github.com/influxdata/influx-cli/v2/config"
+	"github.com/influxdata/influx-cli/v2/internal/mock"
+	"github.com/influxdata/influx-cli/v2/internal/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func valuesCsv(values ...string) string {
+	csv := "#group,false,false,false\n#datatype,string,long,string\n#default,_result,,\n,result,table,_value\n"
+	for _, v := range values {
+		csv += ",,0," + v + "\n"
+	}
+	return csv
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		params        schema.Params
+		printJSON     bool
+		run           func(schema.Client, context.Context, *schema.Params) error
+		response      []string
+		expectedQuery string
+		expectedOut   string
+		expectedErr   string
+	}{
+		{
+			name:          "measurements",
+			params:        schema.Params{Bucket: "metrics"},
+			run:           schema.Client.Measurements,
+			response:      []string{"mem", "cpu"},
+			expectedQuery: `schema.measurements(bucket: "metrics", start: -30d)`,
+			expectedOut:   "Measurement\ncpu\nmem",
+		},
+		{
+			name:          "fields of measurement",
+			params:        schema.Params{Bucket: "metrics", Measurement: "cpu", Start: "-1d"},
+			run:           schema.Client.Fields,
+			response:      []string{"usage_user"},
+			expectedQuery: `schema.measurementFieldKeys(bucket: "metrics", measurement: "cpu", start: -1d)`,
+			expectedOut:   "Field\nusage_user",
+		},
+		{
+			name:          "tag keys",
+			params:        schema.Params{Bucket: "metrics", Start: "2021-01-01T00:00:00Z"},
+			run:           schema.Client.TagKeys,
+			response:      []string{"_field", "_measurement", "_start", "_stop", "host"},
+			expectedQuery: `schema.tagKeys(bucket: "metrics", start: 2021-01-01T00:00:00Z)`,
+			expectedOut:   "Tag Key\nhost",
+		},
+		{
+			name:          "tag values as JSON",
+			params:        schema.Params{Bucket: "metrics", Measurement: "cpu", Tag: "host"},
+			printJSON:     true,
+			run:           schema.Client.TagValues,
+			response:      []string{"b", "a"},
+			expectedQuery: `schema.measurementTagValues(bucket: "metrics", measurement: "cpu", tag: "host", start: -30d)`,
+			expectedOut:   `\[` + "\n\t\"a\",\n\t\"b\"\n" + `\]`,
+		},
+		{
+			name:        "tag values without tag",
+			params:      schema.Params{Bucket: "metrics"},
+			run:         schema.Client.TagValues,
+			expectedErr: "must specify a tag key",
+		},
+		{
+			name:        "invalid start",
+			params:      schema.Params{Bucket: "metrics", Start: "30d"},
+			run:         schema.Client.Measurements,
+			expectedErr: `start "30d" must be an RFC3339 time or a negative duration, like -30d`,
+		},
+		{
+			name:        "no bucket",
+			run:         schema.Client.Measurements,
+			expectedErr: clients.ErrMustSpecifyBucket.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			queryApi := mock.NewMockQueryApi(ctrl)
+			if tc.expectedQuery != "" {
+				queryApi.EXPECT().PostQuery(gomock.Any()).Return(api.ApiPostQueryRequest{ApiService: queryApi})
+				queryApi.EXPECT().PostQueryExecute(gomock.Any()).
+					DoAndReturn(func(req api.ApiPostQueryRequest) (*http.Response, error) {
+						require.Equal(t, "my-org", *req.GetOrg())
+						q := req.GetQuery().Query
+						require.Contains(t, q, `import "influxdata/influxdb/schema"`)
+						require.Contains(t, q, tc.expectedQuery)
+						return &http.Response{Body: io.NopCloser(strings.NewReader(valuesCsv(tc.response...)))}, nil
+					})
+			}
+
+			stdout := bytes.Buffer{}
+			stdio := mock.NewMockStdIO(ctrl)
+			stdio.EXPECT().Write(gomock.Any()).DoAndReturn(stdout.Write).AnyTimes()
+
+			client := schema.Client{
+				CLI:      clients.CLI{StdIO: stdio, ActiveConfig: config.Config{Org: "my-org"}, PrintAsJSON: tc.printJSON},
+				QueryApi: queryApi,
+			}
+			err := tc.run(client, context.Background(), &tc.params)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			testutils.MatchLines(t, strings.Split(tc.expectedOut, "\n"), strings.Split(stdout.String(), "\n"))
+		})
+	}
+}
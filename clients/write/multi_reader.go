@@ -27,6 +27,7 @@ const (
 	InputFormatDerived InputFormat = iota
 	InputFormatCSV
 	InputFormatLP
+	InputFormatFluxCSV
 )
 
 func (i *InputFormat) Set(v string) error {
@@ -37,6 +38,8 @@ func (i *InputFormat) Set(v string) error {
 		*i = InputFormatLP
 	case "csv":
 		*i = InputFormatCSV
+	case "flux-csv":
+		*i = InputFormatFluxCSV
 	default:
 		return fmt.Errorf("unsupported format: %q", v)
 	}
@@ -49,6 +52,8 @@ func (i InputFormat) String() string {
 		return "lp"
 	case InputFormatCSV:
 		return "csv"
+	case InputFormatFluxCSV:
+		return "flux-csv"
 	case InputFormatDerived:
 		fallthrough
 	default:
@@ -278,6 +283,8 @@ func (r *MultiInputLineReader) Open(ctx context.Context) (io.Reader, io.Closer,
 		csvReader.LineNumber = r.SkipHeader - len(r.Headers)
 		csvReader.RowSkipped = rowSkippedListener
 		reader = csvReader
+	} else if r.Format == InputFormatFluxCSV {
+		reader = csv2lp.FluxCsvToLineProtocol(reader).SkipRowOnError(r.SkipRowOnError)
 	} else if r.SkipRowOnError {
 		reader = csv2lp.LineProtocolFilter(reader)
 	}
@@ -329,6 +329,18 @@ func TestLineReader(t *testing.T) {
 				stdInLpContents,
 			},
 		},
+		{
+			name:   "read flux CSV data from stdin + transform to line protocol",
+			format: write.InputFormatFluxCSV,
+			stdIn: strings.NewReader("#group,false,false,true,true,false,false,true,true\n" +
+				"#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string\n" +
+				"#default,_result,,,,,,,\n" +
+				",result,table,_start,_stop,_time,_value,_field,_measurement\n" +
+				",,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T00:00:01Z,1.5,f,m\n"),
+			lines: []string{
+				"m f=1.5 1609459201000000000",
+			},
+		},
 		{
 			name:   "read CSV data from 1st argument + transform to line protocol",
 			format: write.InputFormatCSV,
@@ -10,6 +10,27 @@ import (
 )
 
 func newBackupCmd() cli.Command {
+	run := newBackupRunCmd()
+	return cli.Command{
+		Name:        run.Name,
+		Usage:       run.Usage,
+		Description: run.Description,
+		ArgsUsage:   run.ArgsUsage,
+		Flags:       run.Flags,
+		// NOTE: As with `influx query`, backups are run by re-parsing the original args as a
+		// standalone command, so flags may still follow the backup path.
+		Action: func(ctx *cli.Context) error {
+			return run.Run(ctx.Parent())
+		},
+		Subcommands: []cli.Command{
+			newBackupVerifyCmd(),
+			newBackupInspectCmd(),
+			newBackupPruneCmd(),
+		},
+	}
+}
+
+func newBackupRunCmd() cli.Command {
 	var params backup.Params
 	// Default to gzipping local files.
 	params.Compression = br.GzipCompression
@@ -22,10 +43,31 @@ func newBackupCmd() cli.Command {
 Examples:
 	# backup all data
 	influx backup /path/to/backup
+
+	# backup only the shard groups holding data from the last 30 days
+	influx backup --start -30d /path/to/backup
+
+	# backup only the shards that changed since an earlier backup
+	influx backup --incremental-from /path/to/backup /path/to/next-backup
+
+	# backup all data to S3-compatible object storage
+	influx backup --s3-endpoint http://localhost:9000 s3://bucket/path/to/backup
+
+	# backup all data, encrypting it with a key generated by e.g. 'openssl rand -hex 32'
+	influx backup --encryption-key-file /path/to/backup.key /path/to/backup
+
+	# check that a backup is intact
+	influx backup verify /path/to/backup
+
+	# show which organizations, buckets, and shard groups a backup holds
+	influx backup inspect /path/to/backup
+
+	# delete old backups, keeping the latest backup of each of the last 7 days
+	influx backup prune --keep-daily 7 /path/to/backups
 `,
 		ArgsUsage: "path",
 		Before:    middleware.WithBeforeFns(withCli(), withApi(true)),
-		Flags: append(
+		Flags: append(append(
 			append(commonFlagsNoPrint(), getOrgFlags(&params.OrgParams)...),
 			&cli.StringFlag{
 				Name:        "bucket-id",
@@ -47,7 +89,29 @@ Examples:
 				Usage:       "The level of gzip compression for server-side backup: 'default', 'full' (best compression), 'speedy' (fastest), or 'none'",
 				Destination: &params.ServerGzipCompressionLevel,
 			},
-		),
+			&cli.StringFlag{
+				Name:        "incremental-from",
+				Usage:       "Path to an earlier backup; shards unchanged since it was taken are not downloaded again",
+				Destination: &params.IncrementalFrom,
+				TakesFile:   true,
+			},
+			&cli.IntFlag{
+				Name:        "parallelism",
+				Usage:       "Number of shards to download concurrently",
+				Value:       1,
+				Destination: &params.Parallelism,
+			},
+			&cli.StringFlag{
+				Name:        "start",
+				Usage:       "Only backup shard groups with data after this time, in RFC3339Nano format (ex: '2009-01-02T23:00:00Z') or relative to now (ex: -30d)",
+				Destination: &params.Start,
+			},
+			&cli.StringFlag{
+				Name:        "stop",
+				Usage:       "Only backup shard groups with data before this time, in RFC3339Nano format (ex: '2009-01-02T23:00:00Z'), relative to now (ex: -1d), or 'now'",
+				Destination: &params.Stop,
+			},
+		), append(s3Flags(&params.S3), encryptionFlags(&params.Encryption)...)...),
 		Action: func(ctx *cli.Context) error {
 			if err := checkOrgFlags(&params.OrgParams); err != nil {
 				return err
@@ -73,3 +137,179 @@ Examples:
 		},
 	}
 }
+
+func newBackupVerifyCmd() cli.Command {
+	var params backup.VerifyParams
+	return cli.Command{
+		Name:  "verify",
+		Usage: "Check a backup for corruption",
+		Description: `Checks that the files of every backup in a directory or S3 location are intact.
+
+Each file is checked against the size and SHA-256 checksum recorded in its manifest, and
+compressed files are fully decompressed. The KV snapshot is parsed, and every bucket and shard
+in the manifest must be known to it. Manifests written by versions of the CLI that didn't record
+checksums are only checked for size and compression. The contents of encrypted files are only
+checked if the passphrase or key file they were encrypted with is given.
+
+Examples:
+	influx backup verify /path/to/backup
+	influx backup verify --s3-endpoint http://localhost:9000 s3://bucket/path/to/backup
+`,
+		ArgsUsage: "path",
+		Before:    withCli(),
+		Flags: append(append(append([]cli.Flag{configPathFlag()}, printFlags()...),
+			s3Flags(&params.S3)...), encryptionFlags(&params.Encryption)...),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return errors.New("backup path must be specified as a single positional argument")
+			}
+			params.Path = ctx.Args().Get(0)
+			client := backup.Client{CLI: getCLI(ctx)}
+			return client.Verify(getContext(ctx), &params)
+		},
+	}
+}
+
+func newBackupInspectCmd() cli.Command {
+	var params backup.InspectParams
+	return cli.Command{
+		Name:  "inspect",
+		Usage: "Print the contents of a backup",
+		Description: `Prints the organizations, buckets, retention policies, and shard groups recorded in the
+manifests of every backup in a directory, along with the time range, size, and compression of
+each shard group. Manifests written by older versions of the CLI are converted to the latest
+format first. None of the backed-up files are read.
+
+Examples:
+	influx backup inspect /path/to/backup
+
+	# print the full manifests as JSON
+	influx backup inspect --json /path/to/backup
+`,
+		ArgsUsage: "path",
+		Before:    withCli(),
+		Flags:     append(append([]cli.Flag{configPathFlag()}, printFlags()...), s3Flags(&params.S3)...),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return errors.New("backup path must be specified as a single positional argument")
+			}
+			params.Path = ctx.Args().Get(0)
+			client := backup.Client{CLI: getCLI(ctx)}
+			return client.Inspect(getContext(ctx), &params)
+		},
+	}
+}
+
+func newBackupPruneCmd() cli.Command {
+	var params backup.PruneParams
+	return cli.Command{
+		Name:  "prune",
+		Usage: "Delete old local backups according to a retention policy",
+		Description: `Deletes the backups in a directory which aren't kept by a retention policy.
+
+Backups are found by their manifests, named like 20060102T150405Z.manifest after the UTC time
+the backup was taken, both directly within the directory and within its subdirectories. For
+each of the given number of most recent days, weeks, and months with backups, the latest backup
+is kept. Backups holding files which kept incremental backups refer to are kept too, as are the
+earlier backups in the same directory which kept backups limited to a time range are merged with
+when restoring, so every kept backup can still be restored.
+
+Examples:
+	# keep daily backups for a week, weekly backups for a month, and monthly backups for a year
+	influx backup prune --keep-daily 7 --keep-weekly 4 --keep-monthly 12 /path/to/backups
+
+	# show which backups would be deleted, without deleting them
+	influx backup prune --keep-daily 7 --dry-run /path/to/backups
+`,
+		ArgsUsage: "path",
+		Before:    withCli(),
+		Flags: append(
+			append([]cli.Flag{configPathFlag()}, printFlags()...),
+			&cli.IntFlag{
+				Name:        "keep-daily",
+				Usage:       "Number of days for which to keep the latest backup",
+				Destination: &params.KeepDaily,
+			},
+			&cli.IntFlag{
+				Name:        "keep-weekly",
+				Usage:       "Number of weeks for which to keep the latest backup",
+				Destination: &params.KeepWeekly,
+			},
+			&cli.IntFlag{
+				Name:        "keep-monthly",
+				Usage:       "Number of months for which to keep the latest backup",
+				Destination: &params.KeepMonthly,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "Show which backups would be deleted without deleting them",
+				Destination: &params.DryRun,
+			},
+		),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return errors.New("backup path must be specified as a single positional argument")
+			}
+			params.Path = ctx.Args().Get(0)
+			client := backup.Client{CLI: getCLI(ctx)}
+			return client.Prune(getContext(ctx), &params)
+		},
+	}
+}
+
+// s3Flags returns flags configuring the connection to S3-compatible object storage, for
+// commands reading or writing backups at s3:// URLs.
+func s3Flags(config *br.S3Config) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "s3-endpoint",
+			Usage:       "URL of S3-compatible object storage, like http://localhost:9000 for MinIO, defaults to AWS S3 in the region",
+			EnvVar:      "AWS_ENDPOINT_URL_S3",
+			Destination: &config.Endpoint,
+		},
+		&cli.StringFlag{
+			Name:        "s3-region",
+			Usage:       "Region of the object storage bucket",
+			EnvVar:      "AWS_REGION",
+			Value:       "us-east-1",
+			Destination: &config.Region,
+		},
+		&cli.StringFlag{
+			Name:        "s3-access-key-id",
+			Usage:       "Access key ID to authenticate with object storage",
+			EnvVar:      "AWS_ACCESS_KEY_ID",
+			Destination: &config.AccessKeyID,
+		},
+		&cli.StringFlag{
+			Name:        "s3-secret-access-key",
+			Usage:       "Secret access key to authenticate with object storage",
+			EnvVar:      "AWS_SECRET_ACCESS_KEY",
+			Destination: &config.SecretAccessKey,
+		},
+		&cli.StringFlag{
+			Name:        "s3-session-token",
+			Usage:       "Session token to authenticate with object storage using temporary credentials",
+			EnvVar:      "AWS_SESSION_TOKEN",
+			Destination: &config.SessionToken,
+		},
+	}
+}
+
+// encryptionFlags returns flags configuring the passphrase or key used to encrypt and decrypt
+// backup files.
+func encryptionFlags(params *br.EncryptionParams) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "encryption-passphrase",
+			Usage:       "Passphrase to encrypt or decrypt backup files with",
+			EnvVar:      "INFLUX_BACKUP_PASSPHRASE",
+			Destination: &params.Passphrase,
+		},
+		&cli.StringFlag{
+			Name:        "encryption-key-file",
+			Usage:       "Path to a file holding a 32-byte key, raw or encoded as hex or base64, to encrypt or decrypt backup files with",
+			Destination: &params.KeyFile,
+			TakesFile:   true,
+		},
+	}
+}
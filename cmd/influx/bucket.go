@@ -13,8 +13,11 @@ func newBucketCmd() cli.Command {
 		Usage:  "Bucket management commands",
 		Before: middleware.NoArgs,
 		Subcommands: []cli.Command{
+			newBucketCardinalityCmd(),
+			newBucketCopyCmd(),
 			newBucketCreateCmd(),
 			newBucketDeleteCmd(),
+			newBucketExportDataCmd(),
 			newBucketListCmd(),
 			newBucketUpdateCmd(),
 		},
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/urfave/cli"
+)
+
+func newBucketCardinalityCmd() cli.Command {
+	var params bucket.CardinalityParams
+	return cli.Command{
+		Name:  "cardinality",
+		Usage: "Report the series cardinality of a bucket",
+		Description: `Report the number of series in a bucket and in each of its measurements, the tag keys
+with the most distinct values, and the number of series in each time window, to show how
+cardinality grows over time.
+
+Without --start, the last 7 windows are reported.
+
+Examples:
+	# report daily cardinality for the last week
+	influx bucket cardinality --bucket metrics
+
+	# report hourly cardinality for a day, as JSON
+	influx bucket cardinality --bucket metrics --window 1h \
+		--start 2021-01-01T00:00:00Z --stop 2021-01-02T00:00:00Z --json
+`,
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags: append(
+			append(commonFlags(), getOrgBucketFlags(&params.OrgBucketParams)...),
+			&cli.StringFlag{
+				Name:        "start",
+				Usage:       "Start of the time range to report on in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Destination: &params.Start,
+			},
+			&cli.StringFlag{
+				Name:        "stop",
+				Usage:       "Stop of the time range to report on in RFC3339Nano format, defaults to now",
+				Destination: &params.Stop,
+			},
+			&cli.DurationFlag{
+				Name:        "window",
+				Usage:       "Length of the time windows cardinality growth is reported for",
+				Value:       24 * time.Hour,
+				Destination: &params.Window,
+			},
+			&cli.IntFlag{
+				Name:        "top",
+				Usage:       "Number of tag keys with the most distinct values to report",
+				Value:       10,
+				Destination: &params.TopTagKeys,
+			},
+			&cli.IntFlag{
+				Name:        "max-windows",
+				Usage:       "Maximum number of windows to report, each of which is counted by a separate query",
+				Value:       bucket.DefaultMaxCardinalityWindows,
+				Destination: &params.MaxWindows,
+			},
+		),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			api := getAPI(ctx)
+			client := bucket.CardinalityClient{
+				CLI:        getCLI(ctx),
+				QueryApi:   api.QueryApi,
+				BucketsApi: api.BucketsApi,
+			}
+			return client.Cardinality(getContext(ctx), &params)
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/clients/write"
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/urfave/cli"
+)
+
+// getPrefixedOrgBucketFlags returns org/bucket flags whose names start with prefix, for
+// commands that operate on more than one bucket.
+func getPrefixedOrgBucketFlags(prefix, desc string, params *clients.OrgBucketParams) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        prefix + "-bucket-id",
+			Usage:       "The ID of the " + desc + " bucket",
+			Destination: &params.BucketID,
+		},
+		&cli.StringFlag{
+			Name:        prefix + "-bucket",
+			Usage:       "The name of the " + desc + " bucket",
+			Destination: &params.BucketName,
+		},
+		&cli.StringFlag{
+			Name:        prefix + "-org-id",
+			Usage:       "The ID of the organization owning the " + desc + " bucket",
+			Destination: &params.OrgID,
+		},
+		&cli.StringFlag{
+			Name:        prefix + "-org",
+			Usage:       "The name of the organization owning the " + desc + " bucket",
+			Destination: &params.OrgName,
+		},
+	}
+}
+
+func newBucketCopyCmd() cli.Command {
+	var params bucket.CopyParams
+	var srcConfig, dstConfig string
+	var rateLimit write.BytesPerSec
+	var flags []cli.Flag
+	flags = append(flags, commonFlags()...)
+	flags = append(flags, getPrefixedOrgBucketFlags("src", "source", &params.Src)...)
+	flags = append(flags, getPrefixedOrgBucketFlags("dst", "destination", &params.Dst)...)
+	flags = append(flags,
+		&cli.StringFlag{
+			Name:        "src-config",
+			Usage:       "Config of the server to copy data from, defaults to the active config",
+			Destination: &srcConfig,
+		},
+		&cli.StringFlag{
+			Name:        "dst-config",
+			Usage:       "Config of the server to copy data to, defaults to the active config",
+			Destination: &dstConfig,
+		},
+		&cli.StringFlag{
+			Name:        "start",
+			Usage:       "Start of the time range to copy in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+			Required:    true,
+			Destination: &params.Start,
+		},
+		&cli.StringFlag{
+			Name:        "stop",
+			Usage:       "Stop of the time range to copy in RFC3339Nano format, defaults to now",
+			Destination: &params.Stop,
+		},
+		&cli.DurationFlag{
+			Name:        "window",
+			Usage:       "Length of the time windows the copy is split into",
+			Value:       time.Hour,
+			Destination: &params.Window,
+		},
+		&cli.StringFlag{
+			Name:        "resume-file",
+			Usage:       "Path to a file tracking copied windows, used to resume an interrupted copy",
+			TakesFile:   true,
+			Destination: &params.ResumeFile,
+		},
+		&cli.GenericFlag{
+			Name:  "rate-limit",
+			Usage: `Throttles writes to the destination, examples: "5 MB / 5 min" , "17kBs"`,
+			Value: &rateLimit,
+		},
+	)
+
+	return cli.Command{
+		Name:  "copy",
+		Usage: "Copy data between buckets, possibly on different servers",
+		Description: `Copy the points of a time range from one bucket to another.
+
+The source and destination can be on different servers, selected with --src-config and
+--dst-config. The time range is copied one window at a time: data is queried from the
+source and written to the destination in batches.
+
+With --resume-file, progress is recorded after each window, and running the same copy
+again resumes after the last copied window. Writes are idempotent, so a window that was
+only partially copied is safe to copy again.
+
+Examples:
+	# copy a day of data from prod to staging
+	influx bucket copy --src-config prod --dst-config staging \
+		--src-bucket metrics --dst-bucket metrics \
+		--start 2021-01-01T00:00:00Z --stop 2021-01-02T00:00:00Z \
+		--resume-file metrics-copy.json
+`,
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags:  flags,
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.Src.OrgParams); err != nil {
+				return err
+			}
+			if err := checkOrgFlags(&params.Dst.OrgParams); err != nil {
+				return err
+			}
+
+			cli := getCLI(ctx)
+			client := bucket.CopyClient{
+				RateLimiter: write.NewThrottler(rateLimit),
+				BatchWriter: &write.BufferBatcher{
+					MaxFlushBytes:    write.DefaultMaxBytes,
+					MaxFlushInterval: write.DefaultInterval,
+				},
+				SrcQueryApi: getAPI(ctx).QueryApi,
+				SrcConfig:   cli.ActiveConfig,
+			}
+			if srcConfig != "" {
+				cfg, err := getNamedConfig(cli.ConfigService, srcConfig)
+				if err != nil {
+					return err
+				}
+				apiClient, err := newApiClientForConfig(ctx, cfg, true)
+				if err != nil {
+					return err
+				}
+				client.SrcQueryApi, client.SrcConfig = apiClient.QueryApi, cfg
+			}
+			client.WriteApi = getAPI(ctx).WriteApi
+			if dstConfig != "" {
+				cfg, err := getNamedConfig(cli.ConfigService, dstConfig)
+				if err != nil {
+					return err
+				}
+				apiClient, err := newApiClientForConfig(ctx, cfg, true)
+				if err != nil {
+					return err
+				}
+				client.WriteApi, cli.ActiveConfig = apiClient.WriteApi, cfg
+			}
+			client.CLI = cli
+			return client.Copy(getContext(ctx), &params)
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/influxdata/influx-cli/v2/clients/bucket"
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/urfave/cli"
+)
+
+func newBucketExportDataCmd() cli.Command {
+	params := bucket.ExportDataParams{
+		Format: bucket.ExportFormatLP,
+	}
+	return cli.Command{
+		Name:  "export-data",
+		Usage: "Export the data of a bucket to files partitioned by measurement and day",
+		Description: `Export the points of a bucket to portable files, for example for cold-storage archives.
+
+Points are written to files named <measurement>/date=YYYY-MM-DD/part.<format>.gz under the
+output directory, either as gzipped line protocol or annotated CSV, or as part.parquet for
+Parquet. Annotated CSV files can be written back with 'influx write --format flux-csv'.
+Parquet files have a row per series and timestamp, with a column per tag and field.
+
+A manifest.json listing the row count, size and SHA-256 checksum of every file is written
+next to the exported files.
+
+Examples:
+	# export a month of data as line protocol
+	influx bucket export-data --bucket metrics --start 2021-01-01T00:00:00Z --stop 2021-02-01T00:00:00Z ./archive
+
+	# export a day of data as Parquet
+	influx bucket export-data --bucket metrics --start 2021-01-01T00:00:00Z --stop 2021-01-02T00:00:00Z --format parquet ./archive
+`,
+		ArgsUsage: "path",
+		Before:    middleware.WithBeforeFns(withCli(), withApi(true)),
+		Flags: append(
+			append(commonFlags(), getOrgBucketFlags(&params.OrgBucketParams)...),
+			&cli.StringFlag{
+				Name:        "start",
+				Usage:       "Start of the time range to export in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Required:    true,
+				Destination: &params.Start,
+			},
+			&cli.StringFlag{
+				Name:        "stop",
+				Usage:       "Stop of the time range to export in RFC3339Nano format, defaults to now",
+				Destination: &params.Stop,
+			},
+			&cli.GenericFlag{
+				Name:  "format",
+				Usage: "Format of exported files, either 'lp' (Line Protocol), 'csv' (annotated CSV) or 'parquet'",
+				Value: &params.Format,
+			},
+			&cli.IntFlag{
+				Name:        "parallelism",
+				Usage:       "Number of partitions to export concurrently",
+				Value:       4,
+				Destination: &params.Parallelism,
+			},
+		),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			if ctx.NArg() != 1 {
+				return errors.New("export path must be specified as a single positional argument")
+			}
+			params.Path = ctx.Args().Get(0)
+
+			client := bucket.ExportDataClient{
+				CLI:      getCLI(ctx),
+				QueryApi: getAPI(ctx).QueryApi,
+			}
+			return client.ExportData(getContext(ctx), &params)
+		},
+	}
+}
@@ -1,19 +1,53 @@
 package main
 
 import (
+	"time"
+
 	"github.com/influxdata/influx-cli/v2/clients/delete"
 	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
 	"github.com/urfave/cli"
 )
 
+// defaultChunkRetries is the number of times requests of chunked deletes are retried by default.
+const defaultChunkRetries = 3
+
 func newDeleteCmd() cli.Command {
 	var params delete.Params
+	var predicates cli.StringSlice
 	return cli.Command{
-		Name:        "delete",
-		Usage:       "Delete points from InfluxDB",
-		Description: "Delete points from InfluxDB, by specify start, end time and a sql like predicate string",
+		Name:  "delete",
+		Usage: "Delete points from InfluxDB",
+		Description: `Delete points from InfluxDB, by specify start, end time and a sql like predicate string.
+
+The predicate is checked locally before it is sent. It compares tags or _measurement with
+values using '=' or '!=', combined with AND.
+
+Start and stop times are either RFC3339Nano times, negative durations relative to now
+(ex: -7d), or 'now'.
+
+With --chunk, the time range is split into windows which are deleted by separate requests,
+so deletes over long time ranges don't time out. With --resume-file, the deleted chunks are
+recorded, and running the same command again continues an interrupted delete.
+
+Several predicates can be given with repeated --predicate flags or a --predicate-file with one
+predicate per line. Each predicate is deleted by its own request over the same time range,
+and the outcome of each is reported.
+
+Examples:
+	# preview how many series and points would be deleted
+	influx delete --bucket metrics --start -7d --stop now \
+		--predicate '_measurement="cpu" AND host="a"' --dry-run
+
+	# delete a year of data one day at a time, four days at once
+	influx delete --bucket metrics --start 2020-01-01T00:00:00Z --stop 2021-01-01T00:00:00Z \
+		--chunk 1d --parallelism 4 --resume-file delete.progress
+
+	# delete the data of several users
+	influx delete --bucket events --start 2020-01-01T00:00:00Z --stop now \
+		--predicate 'user="alice"' --predicate 'user="bob"'
+`,
 		Flags: append(
-			commonFlagsNoPrint(),
+			commonFlags(),
 			&cli.StringFlag{
 				Name:        "org-id",
 				Usage:       "The ID of the organization that owns the bucket",
@@ -42,25 +76,72 @@ func newDeleteCmd() cli.Command {
 			// but the help-text generation is broken for it.
 			&cli.StringFlag{
 				Name:        "start",
-				Usage:       "The start time in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Usage:       "The start time in RFC3339Nano format (ex: '2009-01-02T23:00:00Z'), or relative to now (ex: -7d)",
 				Required:    true,
 				Destination: &params.Start,
 			},
 			&cli.StringFlag{
 				Name:        "stop",
-				Usage:       "The stop time in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Usage:       "The stop time in RFC3339Nano format (ex: '2009-01-02T23:00:00Z'), relative to now (ex: -1d), or 'now'",
 				Required:    true,
 				Destination: &params.Stop,
 			},
+			&cli.StringSliceFlag{
+				Name:  "predicate, p",
+				Usage: "sql like predicate string (ex: 'tag1=\"v1\" and (tag2=123)'); can be repeated to delete several predicates",
+				Value: &predicates,
+			},
+			&cli.StringFlag{
+				Name:        "predicate-file",
+				Usage:       "Path to a file containing one predicate per line, each deleted by its own request",
+				Destination: &params.PredicateFile,
+				TakesFile:   true,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "Report the number of series and points that would be deleted, without deleting them",
+				Destination: &params.DryRun,
+			},
+			&cli.StringFlag{
+				Name:        "chunk",
+				Usage:       "Split the time range into windows of this duration (ex: 1d), each deleted by its own request",
+				Destination: &params.Chunk,
+			},
+			&cli.IntFlag{
+				Name:        "parallelism",
+				Usage:       "Number of chunks to delete concurrently",
+				Value:       1,
+				Destination: &params.Parallelism,
+			},
+			&cli.IntFlag{
+				Name:        "retries",
+				Usage:       "Number of times a delete request failing with a server or connection error is retried (default 3 with --chunk)",
+				Destination: &params.Retries,
+			},
+			&cli.DurationFlag{
+				Name:        "retry-delay",
+				Usage:       "Delay before retrying a failed delete request, doubled after each retry",
+				Value:       time.Second,
+				Destination: &params.RetryDelay,
+			},
 			&cli.StringFlag{
-				Name:        "predicate, p",
-				Usage:       "sql like predicate string (ex: 'tag1=\"v1\" and (tag2=123)')",
-				Destination: &params.Predicate,
+				Name:        "resume-file",
+				Usage:       "Path to a file recording the deleted chunks, to resume an interrupted chunked delete",
+				Destination: &params.ResumeFile,
+				TakesFile:   true,
 			},
 		),
 		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
 		Action: func(ctx *cli.Context) error {
-			client := delete.Client{CLI: getCLI(ctx), DeleteApi: getAPI(ctx).DeleteApi}
+			params.Predicates = predicates
+			if params.Chunk != "" && !ctx.IsSet("retries") {
+				params.Retries = defaultChunkRetries
+			}
+			client := delete.Client{
+				CLI:       getCLI(ctx),
+				DeleteApi: getAPI(ctx).DeleteApi,
+				QueryApi:  getAPI(ctx).QueryApi,
+			}
 			return client.Delete(getContext(ctx), &params)
 		},
 	}
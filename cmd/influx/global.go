@@ -34,13 +34,9 @@ const (
 // newCli builds a CLI core that reads from stdin, writes to stdout/stderr, manages a local config store,
 // and optionally tracks a trace ID specified over the CLI.
 func newCli(ctx *cli.Context) (clients.CLI, error) {
-	configPath := ctx.String(configPathFlagName)
-	var err error
-	if configPath == "" {
-		configPath, err = config.DefaultPath()
-		if err != nil {
-			return clients.CLI{}, err
-		}
+	configPath, err := getConfigPath(ctx)
+	if err != nil {
+		return clients.CLI{}, err
 	}
 	configSvc := config.NewLocalConfigService(configPath)
 	var activeConfig config.Config
@@ -61,6 +57,15 @@ func newCli(ctx *cli.Context) (clients.CLI, error) {
 	}, nil
 }
 
+// getConfigPath returns the path of the local config store, as given by the
+// --configs-path flag or the default location.
+func getConfigPath(ctx *cli.Context) (string, error) {
+	if configPath := ctx.String(configPathFlagName); configPath != "" {
+		return configPath, nil
+	}
+	return config.DefaultPath()
+}
+
 // newApiClient returns an API clients configured to communicate with a remote InfluxDB instance over HTTP.
 // Client parameters are pulled from the CLI context.
 func newApiClient(ctx *cli.Context, configSvc config.Service, injectToken bool) (*api.APIClient, error) {
@@ -74,7 +79,12 @@ func newApiClient(ctx *cli.Context, configSvc config.Service, injectToken bool)
 	if ctx.IsSet(hostFlagName) {
 		cfg.Host = ctx.String(hostFlagName)
 	}
+	return newApiClientForConfig(ctx, cfg, injectToken)
+}
 
+// newApiClientForConfig returns an API client configured to communicate with the InfluxDB instance
+// described by cfg. Unlike newApiClient, the --host and --token flags aren't applied.
+func newApiClientForConfig(ctx *cli.Context, cfg config.Config, injectToken bool) (*api.APIClient, error) {
 	configParams := api.ConfigParams{
 		UserAgent:        fmt.Sprintf("influx/%s (%s) Sha/%s Date/%s", version, runtime.GOOS, commit, date),
 		AllowInsecureTLS: ctx.Bool(skipVerifyFlagName),
@@ -167,6 +177,19 @@ func withApi(injectToken bool) cli.BeforeFunc {
 	return middleware.WithBeforeFns(makeFn)
 }
 
+// getNamedConfig looks up a config by name, without making it the active config.
+func getNamedConfig(configSvc config.Service, name string) (config.Config, error) {
+	cfgs, err := configSvc.ListConfigs()
+	if err != nil {
+		return config.Config{}, err
+	}
+	cfg, ok := cfgs[name]
+	if !ok {
+		return config.Config{}, fmt.Errorf("config %q is not found", name)
+	}
+	return cfg, nil
+}
+
 func getAPI(ctx *cli.Context) *api.APIClient {
 	i, ok := ctx.App.Metadata["api"].(*api.APIClient)
 	if !ok {
@@ -61,6 +61,7 @@ func allCommands() []cli.Command {
 		newBucketCmd(),
 		newCompletionCmd(),
 		newQueryCmd(),
+		newSchemaCmd(),
 		newConfigCmd(),
 		newOrgCmd(),
 		newDeleteCmd(),
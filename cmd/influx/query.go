@@ -2,22 +2,287 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/influxdata/influx-cli/v2/clients"
 	"github.com/influxdata/influx-cli/v2/clients/query"
 	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
 	"github.com/urfave/cli"
+	"golang.org/x/term"
 )
 
 func newQueryCmd() cli.Command {
-	var orgParams clients.OrgParams
+	run := newQueryExecCmd()
+	return cli.Command{
+		Name:        run.Name,
+		Usage:       run.Usage,
+		Description: run.Description,
+		ArgsUsage:   run.ArgsUsage,
+		Flags:       run.Flags,
+		// NOTE: urfave/cli only moves flags given after positional args to the front for
+		// commands without subcommands, so flags following the query literal would be ignored
+		// here. Queries are run by re-parsing the original args as a standalone command instead.
+		Action: func(ctx *cli.Context) error {
+			return run.Run(ctx.Parent())
+		},
+		Subcommands: []cli.Command{
+			newQueryDiffCmd(),
+			newQuerySaveCmd(),
+			newQueryRunCmd(),
+			newQueryListCmd(),
+			newQueryDeleteCmd(),
+			newQueryPromoteCmd(),
+			newQueryCacheCmd(),
+		},
+	}
+}
+
+// queryParams holds the flags shared by commands that execute a query and print its results.
+type queryParams struct {
+	clients.OrgParams
+	chartStyle     query.ChartStyle
+	profilerFormat query.ProfilerReportFormat
+}
+
+func (p *queryParams) Flags() []cli.Flag {
+	return append(getOrgFlags(&p.OrgParams), []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "raw, r",
+			Usage: "Display raw query results",
+		},
+		&cli.StringSliceFlag{
+			Name:  "profilers, p",
+			Usage: "Names of Flux profilers to enable",
+		},
+		&cli.GenericFlag{
+			Name:  "profiler-format",
+			Usage: "Format of the report built from profiler results, either 'table' or 'json'",
+			Value: &p.profilerFormat,
+		},
+		&cli.BoolFlag{
+			Name:  "chart",
+			Usage: "Display numeric _value columns as line charts over _time",
+		},
+		&cli.GenericFlag{
+			Name:  "chart-style",
+			Usage: "Characters to draw charts with, either 'unicode' or 'ascii'",
+			Value: &p.chartStyle,
+		},
+		&cli.IntFlag{
+			Name:  "chart-width",
+			Usage: "Width of charts in columns, defaults to the terminal width",
+		},
+		&cli.IntFlag{
+			Name:  "chart-height",
+			Usage: "Height of charts in rows",
+			Value: query.DefaultChartHeight,
+		},
+		&cli.DurationFlag{
+			Name:  "watch",
+			Usage: "Re-run the query on the given interval (ex: '10s') until interrupted",
+		},
+		&cli.BoolFlag{
+			Name:  "highlight-changes",
+			Usage: "Highlight values that changed since the previous run when used with --watch",
+		},
+		&cli.BoolFlag{
+			Name:  "stream",
+			Usage: "Print formatted rows as they're received, sizing columns from a sample of each table",
+		},
+		&cli.IntFlag{
+			Name:  "column-width",
+			Usage: "Fixed width of columns when used with --stream, longer values are truncated",
+		},
+		&cli.IntFlag{
+			Name:  "sample-rows",
+			Usage: "Number of rows per table used to size columns when used with --stream",
+			Value: query.DefaultSampleRows,
+		},
+		&cli.IntFlag{
+			Name:  "limit-rows",
+			Usage: "Stop reading results after the given number of rows, 0 means no limit",
+		},
+		&cli.DurationFlag{
+			Name:   "cache-ttl",
+			Usage:  "Serve repeated identical queries from a local cache for the given duration (ex: '1h'), 0 disables caching",
+			EnvVar: "INFLUX_QUERY_CACHE_TTL",
+		},
+		&cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Bypass the local query cache, even if --cache-ttl is set",
+		},
+	}...)
+}
+
+// execute runs queryString with the given script params, printing its results as requested by the flags.
+func (p *queryParams) execute(ctx *cli.Context, queryString string, scriptParams map[string]string) error {
+	if err := checkOrgFlags(&p.OrgParams); err != nil {
+		return err
+	}
+
+	// The old CLI allowed specifying this either via repeated flags or
+	// via a single flag w/ a comma-separated value.
+	rawProfilers := ctx.StringSlice("profilers")
+	var profilers []string
+	for _, p := range rawProfilers {
+		profilers = append(profilers, strings.Split(p, ",")...)
+	}
+
+	params := query.Params{
+		OrgParams:        p.OrgParams,
+		Query:            queryString,
+		Profilers:        profilers,
+		ScriptParams:     scriptParams,
+		WatchInterval:    ctx.Duration("watch"),
+		HighlightChanges: ctx.Bool("highlight-changes"),
+	}
+	if params.WatchInterval < 0 {
+		return errors.New("--watch interval must be positive")
+	}
+	if params.HighlightChanges && params.WatchInterval == 0 {
+		return errors.New("--highlight-changes can only be used with --watch")
+	}
+
+	if ctx.Bool("raw") && ctx.Bool("chart") {
+		return errors.New("--raw and --chart cannot be used together")
+	}
+	if ctx.Bool("stream") && (ctx.Bool("raw") || ctx.Bool("chart")) {
+		return errors.New("--stream cannot be used with --raw or --chart")
+	}
+	if ctx.Int("limit-rows") < 0 {
+		return errors.New("--limit-rows must not be negative")
+	}
+
+	var printer query.ResultPrinter
+	if ctx.Bool("raw") {
+		printer = query.RawResultPrinter
+	} else if ctx.Bool("chart") {
+		width := ctx.Int("chart-width")
+		if width == 0 {
+			// Fill the terminal if we can, falling back to the default width otherwise.
+			if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+				width = w
+			}
+		}
+		printer = query.NewChartPrinter(p.chartStyle, width, ctx.Int("chart-height"))
+	} else if ctx.Bool("stream") {
+		printer = query.NewStreamingPrinter(query.StreamingParams{
+			ColumnWidth: ctx.Int("column-width"),
+			SampleRows:  ctx.Int("sample-rows"),
+		})
+	} else {
+		printer = query.NewFormattingPrinter()
+	}
+	// Raw output is left untouched so it can be consumed by other tools.
+	if len(profilers) > 0 && !ctx.Bool("raw") {
+		printer = query.NewProfilerReportPrinter(printer, p.profilerFormat)
+	}
+	if limit := ctx.Int("limit-rows"); limit > 0 {
+		printer = query.NewRowLimitPrinter(printer, limit, getCLI(ctx).StdIO)
+	}
+
+	client := query.Client{
+		CLI:           getCLI(ctx),
+		QueryApi:      getAPI(ctx).QueryApi,
+		ResultPrinter: printer,
+	}
+	// Caching would stop watched queries from ever showing new results.
+	if ttl := ctx.Duration("cache-ttl"); ttl > 0 && !ctx.Bool("no-cache") && params.WatchInterval == 0 {
+		configPath, err := getConfigPath(ctx)
+		if err != nil {
+			return err
+		}
+		apiConfig := getAPI(ctx).GetConfig()
+		// Results are cached per token, or per config for configs signing in with a password.
+		user := client.ActiveConfig.Token
+		if ctx.IsSet(tokenFlagName) {
+			user = ctx.String(tokenFlagName)
+		}
+		if user == "" {
+			user = "config:" + client.ActiveConfig.Name
+		}
+		client.Cache = &query.ResultCache{
+			Dir:  query.DefaultCacheDir(configPath),
+			TTL:  ttl,
+			Host: apiConfig.Scheme + "://" + apiConfig.Host,
+			User: user,
+		}
+	}
+	return client.Query(getContext(ctx), &params)
+}
+
+func newQueryExecCmd() cli.Command {
+	var params queryParams
 	return cli.Command{
 		Name:        "query",
 		Usage:       "Execute a Flux query",
 		Description: "Execute a Flux query provided via the first argument, a file, or stdin",
 		ArgsUsage:   "[query literal or '-' for stdin]",
 		Before:      middleware.WithBeforeFns(withCli(), withApi(true)),
+		Flags: append(
+			append(commonFlagsNoPrint(), &cli.StringFlag{
+				Name:      "file, f",
+				Usage:     "Path to Flux query file",
+				TakesFile: true,
+			}),
+			params.Flags()...,
+		),
+		Action: func(ctx *cli.Context) error {
+			queryString, err := readQueryString(ctx)
+			if err != nil {
+				return err
+			}
+			return params.execute(ctx, queryString, nil)
+		},
+	}
+}
+
+// readQueryString reads a query from the file given by the --file flag, or from the command's args.
+func readQueryString(ctx *cli.Context) (string, error) {
+	queryString, err := clients.ReadQuery(ctx.String("file"), ctx.Args())
+	if err != nil {
+		return "", err
+	}
+	queryString = strings.TrimSpace(queryString)
+	if queryString == "" {
+		return "", errors.New("no query provided")
+	}
+	return queryString, nil
+}
+
+func newQueryDiffCmd() cli.Command {
+	var orgParams clients.OrgParams
+	var left, right query.DiffSide
+	var leftConfig, rightConfig string
+	var params query.DiffParams
+	var noColor, noTableBorders bool
+	return cli.Command{
+		Name:  "diff",
+		Usage: "Compare the results of a Flux query between two configs or two time ranges",
+		Description: `Run a Flux query against two configs, or over two time ranges, and report series
+that are only found on one side and values that don't match.
+
+Time ranges are passed to the query through the v.timeRangeStart and v.timeRangeStop
+options, which the query can use as 'range(start: v.timeRangeStart, stop: v.timeRangeStop)'.
+Rows are then matched by their offset from the start of each range.
+
+The command exits with an error if any differences are found.
+
+Examples:
+	# compare a query between two servers
+	influx query diff --left-config old --right-config new 'from(bucket: "b") |> range(start: -1h)'
+
+	# compare this week's data to last week's
+	influx query diff \
+		--left-start 2021-01-01T00:00:00Z --left-stop 2021-01-08T00:00:00Z \
+		--right-start 2021-01-08T00:00:00Z --right-stop 2021-01-15T00:00:00Z \
+		-f query.flux
+`,
+		ArgsUsage: "[query literal or '-' for stdin]",
+		Before:    middleware.WithBeforeFns(withCli(), withApi(true)),
 		Flags: append(
 			append(commonFlagsNoPrint(), getOrgFlags(&orgParams)...),
 			&cli.StringFlag{
@@ -25,55 +290,145 @@ func newQueryCmd() cli.Command {
 				Usage:     "Path to Flux query file",
 				TakesFile: true,
 			},
+			&cli.StringFlag{
+				Name:        "left-config",
+				Usage:       "Config to run the left side of the comparison against, defaults to the active config",
+				Destination: &leftConfig,
+			},
+			&cli.StringFlag{
+				Name:        "right-config",
+				Usage:       "Config to run the right side of the comparison against, defaults to the active config",
+				Destination: &rightConfig,
+			},
+			&cli.StringFlag{
+				Name:        "left-start",
+				Usage:       "Start of the left time range in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Destination: &left.Start,
+			},
+			&cli.StringFlag{
+				Name:        "left-stop",
+				Usage:       "Stop of the left time range in RFC3339Nano format, defaults to now",
+				Destination: &left.Stop,
+			},
+			&cli.StringFlag{
+				Name:        "right-start",
+				Usage:       "Start of the right time range in RFC3339Nano format (ex: '2009-01-02T23:00:00Z')",
+				Destination: &right.Start,
+			},
+			&cli.StringFlag{
+				Name:        "right-stop",
+				Usage:       "Stop of the right time range in RFC3339Nano format, defaults to now",
+				Destination: &right.Stop,
+			},
+			&cli.Float64Flag{
+				Name:        "tolerance",
+				Usage:       "Largest difference between numeric values that are still considered equal",
+				Destination: &params.Tolerance,
+			},
 			&cli.BoolFlag{
-				Name:  "raw, r",
-				Usage: "Display raw query results",
+				Name:        "disable-color",
+				Usage:       "Disable color in output",
+				Destination: &noColor,
 			},
-			&cli.StringSliceFlag{
-				Name:  "profilers, p",
-				Usage: "Names of Flux profilers to enable",
+			&cli.BoolFlag{
+				Name:        "disable-table-borders",
+				Usage:       "Disable table borders",
+				Destination: &noTableBorders,
 			},
 		),
 		Action: func(ctx *cli.Context) error {
 			if err := checkOrgFlags(&orgParams); err != nil {
 				return err
 			}
-			queryString, err := clients.ReadQuery(ctx.String("file"), ctx.Args())
-			if err != nil {
+			var err error
+			if params.Query, err = readQueryString(ctx); err != nil {
 				return err
 			}
-			queryString = strings.TrimSpace(queryString)
-			if queryString == "" {
-				return errors.New("no query provided")
+			if leftConfig == "" && rightConfig == "" && left.Start == "" && right.Start == "" {
+				return errors.New("must compare two configs or two time ranges")
 			}
 
-			// The old CLI allowed specifying this either via repeated flags or
-			// via a single flag w/ a comma-separated value.
-			rawProfilers := ctx.StringSlice("profilers")
-			var profilers []string
-			for _, p := range rawProfilers {
-				profilers = append(profilers, strings.Split(p, ",")...)
+			cli := getCLI(ctx)
+			for _, side := range []struct {
+				*query.DiffSide
+				configName string
+			}{{&left, leftConfig}, {&right, rightConfig}} {
+				cfg := cli.ActiveConfig
+				side.QueryApi = getAPI(ctx).QueryApi
+				if side.configName != "" {
+					if cfg, err = getNamedConfig(cli.ConfigService, side.configName); err != nil {
+						return err
+					}
+					apiClient, err := newApiClientForConfig(ctx, cfg, true)
+					if err != nil {
+						return err
+					}
+					side.QueryApi = apiClient.QueryApi
+				}
+				side.OrgParams = orgParams
+				if side.OrgID == "" && side.OrgName == "" {
+					side.OrgName = cfg.Org
+				}
+				side.Name = cfg.Name
+				if side.Start != "" {
+					side.Name += " from " + side.Start
+				}
 			}
 
-			params := query.Params{
-				OrgParams: orgParams,
-				Query:     queryString,
-				Profilers: profilers,
+			client := query.DiffClient{
+				CLI:   cli,
+				Left:  left,
+				Right: right,
 			}
+			params.RenderTableColors = !noColor
+			params.RenderTableBorders = !noTableBorders
+			return client.Diff(getContext(ctx), &params)
+		},
+	}
+}
 
-			var printer query.ResultPrinter
-			if ctx.Bool("raw") {
-				printer = query.RawResultPrinter
-			} else {
-				printer = query.NewFormattingPrinter()
-			}
+func newQueryCacheCmd() cli.Command {
+	return cli.Command{
+		Name:   "cache",
+		Usage:  "Local query result cache management commands",
+		Before: middleware.NoArgs,
+		Subcommands: []cli.Command{
+			newQueryCachePruneCmd(),
+		},
+	}
+}
 
-			client := query.Client{
-				CLI:           getCLI(ctx),
-				QueryApi:      getAPI(ctx).QueryApi,
-				ResultPrinter: printer,
+func newQueryCachePruneCmd() cli.Command {
+	var olderThan time.Duration
+	return cli.Command{
+		Name:  "prune",
+		Usage: "Remove cached query results",
+		Description: `Remove query results cached by 'influx query --cache-ttl'.
+
+Results are stored next to the configs file (by default, in ~/.influxdbv2/query-cache).
+All cached results are removed unless --older-than is given.
+`,
+		Before: middleware.WithBeforeFns(withCli(), middleware.NoArgs),
+		Flags: []cli.Flag{
+			configPathFlag(),
+			&cli.DurationFlag{
+				Name:        "older-than",
+				Usage:       "Only remove results cached longer ago than the given duration (ex: '24h')",
+				Destination: &olderThan,
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			configPath, err := getConfigPath(ctx)
+			if err != nil {
+				return err
+			}
+			cache := query.ResultCache{Dir: query.DefaultCacheDir(configPath)}
+			removed, err := cache.Prune(olderThan)
+			if err != nil {
+				return fmt.Errorf("failed to prune query cache: %w", err)
 			}
-			return client.Query(getContext(ctx), &params)
+			_, err = fmt.Fprintf(getCLI(ctx).StdIO, "Removed %d cached query results\n", removed)
+			return err
 		},
 	}
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influx-cli/v2/clients"
+	"github.com/influxdata/influx-cli/v2/clients/query"
+	"github.com/influxdata/influx-cli/v2/clients/script"
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/urfave/cli"
+)
+
+func getQueryLibraryClient(ctx *cli.Context) (query.LibraryClient, error) {
+	configPath, err := getConfigPath(ctx)
+	if err != nil {
+		return query.LibraryClient{}, err
+	}
+	return query.LibraryClient{
+		CLI:     getCLI(ctx),
+		Library: query.LocalLibrary{Path: query.DefaultLibraryPath(configPath)},
+	}, nil
+}
+
+// getSavedQueryName returns the saved query name given as the first arg.
+func getSavedQueryName(ctx *cli.Context) (string, error) {
+	name := ctx.Args().First()
+	if name == "" {
+		return "", errors.New("must specify the name of a saved query")
+	}
+	return name, nil
+}
+
+func newQuerySaveCmd() cli.Command {
+	var params query.SaveParams
+	return cli.Command{
+		Name:  "save",
+		Usage: "Save a Flux query to the local library",
+		Description: `Save a Flux query under a name, so it can be run later with 'influx query run'.
+
+Saved queries are stored next to the configs file (by default, at ~/.influxdbv2/queries),
+and are scoped to the active config. Saving a query with an existing name replaces it.
+
+Examples:
+	# save a query from a file
+	influx query save cpu-usage -f cpu.flux
+
+	# save a query taking parameters, and run it
+	influx query save host-usage 'from(bucket: "b") |> range(start: -1h) |> filter(fn: (r) => r.host == params.host)'
+	influx query run host-usage --param host=server01
+`,
+		ArgsUsage: "<name> [query literal or '-' for stdin]",
+		Before:    middleware.WithBeforeFns(withCli()),
+		Flags: append(
+			commonFlagsNoToken(),
+			&cli.StringFlag{
+				Name:      "file, f",
+				Usage:     "Path to Flux query file",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:        "description, d",
+				Usage:       "Description of the saved query",
+				Destination: &params.Description,
+			},
+		),
+		Action: func(ctx *cli.Context) error {
+			var err error
+			if params.Name, err = getSavedQueryName(ctx); err != nil {
+				return err
+			}
+			queryString, err := clients.ReadQuery(ctx.String("file"), ctx.Args().Tail())
+			if err != nil {
+				return err
+			}
+			if params.Query = strings.TrimSpace(queryString); params.Query == "" {
+				return errors.New("no query provided")
+			}
+
+			client, err := getQueryLibraryClient(ctx)
+			if err != nil {
+				return err
+			}
+			return client.Save(&params)
+		},
+	}
+}
+
+func newQueryRunCmd() cli.Command {
+	var params queryParams
+	var scriptParams cli.StringSlice
+	return cli.Command{
+		Name:  "run",
+		Usage: "Execute a saved Flux query",
+		Description: `Execute a query saved with 'influx query save'.
+
+Parameters are passed to the query as the 'params' record, the same way invokable scripts
+receive them. Integer, float and boolean values are recognized, and all other values are strings.
+`,
+		ArgsUsage: "<name>",
+		Before:    middleware.WithBeforeFns(withCli(), withApi(true)),
+		Flags: append(
+			append(commonFlagsNoPrint(), &cli.StringSliceFlag{
+				Name:  "param",
+				Usage: "Parameter passed to the query, in the form key=value",
+				Value: &scriptParams,
+			}),
+			params.Flags()...,
+		),
+		Action: func(ctx *cli.Context) error {
+			name, err := getSavedQueryName(ctx)
+			if err != nil {
+				return err
+			}
+			if ctx.NArg() > 1 {
+				return fmt.Errorf("at most 1 saved query can be run, got %d", ctx.NArg())
+			}
+			parsedParams := make(map[string]string, len(scriptParams))
+			for _, p := range scriptParams {
+				k, v, ok := strings.Cut(p, "=")
+				if !ok || k == "" {
+					return fmt.Errorf("parameter %q must be in the form key=value", p)
+				}
+				parsedParams[k] = v
+			}
+
+			library, err := getQueryLibraryClient(ctx)
+			if err != nil {
+				return err
+			}
+			saved, err := library.Get(name)
+			if err != nil {
+				return err
+			}
+			return params.execute(ctx, saved.Query, parsedParams)
+		},
+	}
+}
+
+func newQueryListCmd() cli.Command {
+	return cli.Command{
+		Name:    "list",
+		Aliases: []string{"ls"},
+		Usage:   "List the saved Flux queries of the active config",
+		Before:  middleware.WithBeforeFns(withCli(), middleware.NoArgs),
+		Flags:   commonFlagsNoToken(),
+		Action: func(ctx *cli.Context) error {
+			client, err := getQueryLibraryClient(ctx)
+			if err != nil {
+				return err
+			}
+			return client.List()
+		},
+	}
+}
+
+func newQueryDeleteCmd() cli.Command {
+	return cli.Command{
+		Name:      "delete",
+		Usage:     "Delete a saved Flux query",
+		ArgsUsage: "<name>",
+		Before:    middleware.WithBeforeFns(withCli()),
+		Flags:     commonFlagsNoToken(),
+		Action: func(ctx *cli.Context) error {
+			name, err := getSavedQueryName(ctx)
+			if err != nil {
+				return err
+			}
+			client, err := getQueryLibraryClient(ctx)
+			if err != nil {
+				return err
+			}
+			return client.Delete(name)
+		},
+	}
+}
+
+func newQueryPromoteCmd() cli.Command {
+	var params script.CreateParams
+	return cli.Command{
+		Name:  "promote",
+		Usage: "Create an invokable script from a saved Flux query",
+		Description: `Create an invokable script from a query saved with 'influx query save'.
+
+The script is named after the saved query unless --script-name is given, and can be
+invoked with 'influx scripts invoke'. Parameters read from the 'params' record are
+passed through to the script.
+`,
+		ArgsUsage: "<name>",
+		Before:    middleware.WithBeforeFns(withCli(), withApi(true), middleware.CloudOnly),
+		Flags: append(
+			commonFlags(),
+			&cli.StringFlag{
+				Name:        "script-name, n",
+				Usage:       "Name of the new script, defaults to the name of the saved query",
+				Destination: &params.Name,
+			},
+			&cli.StringFlag{
+				Name:        "description, d",
+				Usage:       "Description of the new script, defaults to the description of the saved query",
+				Destination: &params.Description,
+			},
+		),
+		Action: func(ctx *cli.Context) error {
+			name, err := getSavedQueryName(ctx)
+			if err != nil {
+				return err
+			}
+			library, err := getQueryLibraryClient(ctx)
+			if err != nil {
+				return err
+			}
+			saved, err := library.Get(name)
+			if err != nil {
+				return err
+			}
+			if params.Name == "" {
+				params.Name = saved.Name
+			}
+			if params.Description == "" {
+				params.Description = saved.Description
+			}
+			params.Language = "flux"
+			params.Script = saved.Query
+
+			client := script.Client{
+				CLI:                 getCLI(ctx),
+				InvokableScriptsApi: getAPI(ctx).InvokableScriptsApi,
+			}
+			return client.Create(getContext(ctx), &params)
+		},
+	}
+}
@@ -19,11 +19,24 @@ func newRestoreCmd() cli.Command {
 Examples:
 	# backup all data
 	influx restore /path/to/restore
+
+	# restore all data from a backup in S3-compatible object storage
+	influx restore --s3-endpoint http://localhost:9000 s3://bucket/path/to/restore
+
+	# restore all data from an encrypted backup
+	influx restore --encryption-key-file /path/to/backup.key /path/to/restore
+
+	# restore buckets under the organizations and names given in a mapping file, with lines like
+	# 'orgA/bucket1 -> orgB/bucket1-copy' or 'orgA/* -> orgB/*', skipping buckets that already exist
+	influx restore --mapping-file /path/to/mapping.txt --on-conflict skip /path/to/restore
+
+	# show which organizations and buckets a full restore would create or overwrite, without restoring anything
+	influx restore --full --dry-run /path/to/restore
 `,
 		ArgsUsage: "path",
 		Before:    middleware.WithBeforeFns(withCli(), withApi(true)),
-		Flags: append(
-			commonFlagsNoPrint(),
+		Flags: append(append(
+			commonFlags(),
 			&cli.BoolFlag{
 				Name:        "full",
 				Usage:       "Fully restore and replace all data on server",
@@ -61,12 +74,33 @@ Examples:
 				Usage:       "New name to use for the restored organization",
 				Destination: &params.NewOrgName,
 			},
+			&cli.StringFlag{
+				Name:        "mapping-file",
+				Usage:       "File mapping backed-up buckets to the organizations and names to restore them as, one 'org/bucket -> new-org/new-bucket' per line",
+				Destination: &params.MappingFile,
+			},
+			&cli.GenericFlag{
+				Name:  "on-conflict",
+				Usage: "What to do with buckets which already exist: 'fail', 'skip', or 'suffix' to restore them with a '-restored' suffix",
+				Value: &params.OnConflict,
+			},
 			&cli.StringFlag{
 				Name:        "operator-token",
 				Usage:       "Operator token to use if backup lacks plaintext token",
 				Destination: &params.OperatorToken,
 			},
-		),
+			&cli.IntFlag{
+				Name:        "parallelism",
+				Usage:       "Number of shards to upload concurrently",
+				Value:       1,
+				Destination: &params.Parallelism,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "Show which organizations and buckets would be restored, overwritten or deleted without changing anything on the server",
+				Destination: &params.DryRun,
+			},
+		), append(s3Flags(&params.S3), encryptionFlags(&params.Encryption)...)...),
 		Action: func(ctx *cli.Context) error {
 			if ctx.NArg() != 1 {
 				return errors.New("restore path must be specified as a single positional argument")
@@ -78,7 +112,8 @@ Examples:
 				params.BucketName != "" ||
 				params.BucketID != "" ||
 				params.NewOrgName != "" ||
-				params.NewBucketName != "") {
+				params.NewBucketName != "" ||
+				params.MappingFile != "") {
 				return errors.New("--full restore cannot be limited to a single org or bucket")
 			}
 
@@ -88,6 +123,9 @@ Examples:
 			if params.NewBucketName != "" && params.BucketID == "" && params.BucketName == "" {
 				return errors.New("--bucket-id or --bucket must be set to use --new-bucket")
 			}
+			if params.MappingFile != "" && (params.NewOrgName != "" || params.NewBucketName != "") {
+				return errors.New("--mapping-file cannot be combined with --new-org or --new-bucket")
+			}
 
 			api := getAPI(ctx)
 			client := restore.Client{
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/influxdata/influx-cli/v2/clients/schema"
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/urfave/cli"
+)
+
+func newSchemaCmd() cli.Command {
+	return cli.Command{
+		Name:  "schema",
+		Usage: "Explore the measurements, fields and tags of a bucket",
+		Description: `Explore the schema of a bucket using the Flux schema package.
+
+Schema is explored from --start, which is either an RFC3339 time or a negative duration
+relative to now, and defaults to -30d.
+
+Examples:
+	# list the measurements in a bucket
+	influx schema measurements --bucket metrics
+
+	# list the fields of a measurement written in the last day
+	influx schema fields --bucket metrics --measurement cpu --start -1d
+
+	# list the values of a tag, as JSON
+	influx schema tag-values --bucket metrics --measurement cpu --tag host --json
+`,
+		Subcommands: []cli.Command{
+			newSchemaMeasurementsCmd(),
+			newSchemaFieldsCmd(),
+			newSchemaTagKeysCmd(),
+			newSchemaTagValuesCmd(),
+		},
+	}
+}
+
+func newSchemaMeasurementsCmd() cli.Command {
+	var params schema.Params
+	cmd := cli.Command{
+		Name:   "measurements",
+		Usage:  "List the measurements in a bucket",
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags:  schemaFlags(&params, false, false),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			return getSchemaClient(ctx).Measurements(getContext(ctx), &params)
+		},
+	}
+	cmd.BashComplete = schemaBashComplete(&cmd, &params)
+	return cmd
+}
+
+func newSchemaFieldsCmd() cli.Command {
+	var params schema.Params
+	cmd := cli.Command{
+		Name:   "fields",
+		Usage:  "List the field keys in a bucket, or in one of its measurements",
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags:  schemaFlags(&params, true, false),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			return getSchemaClient(ctx).Fields(getContext(ctx), &params)
+		},
+	}
+	cmd.BashComplete = schemaBashComplete(&cmd, &params)
+	return cmd
+}
+
+func newSchemaTagKeysCmd() cli.Command {
+	var params schema.Params
+	cmd := cli.Command{
+		Name:   "tag-keys",
+		Usage:  "List the tag keys in a bucket, or in one of its measurements",
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags:  schemaFlags(&params, true, false),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			return getSchemaClient(ctx).TagKeys(getContext(ctx), &params)
+		},
+	}
+	cmd.BashComplete = schemaBashComplete(&cmd, &params)
+	return cmd
+}
+
+func newSchemaTagValuesCmd() cli.Command {
+	var params schema.Params
+	cmd := cli.Command{
+		Name:   "tag-values",
+		Usage:  "List the values of a tag in a bucket, or in one of its measurements",
+		Before: middleware.WithBeforeFns(withCli(), withApi(true), middleware.NoArgs),
+		Flags:  schemaFlags(&params, true, true),
+		Action: func(ctx *cli.Context) error {
+			if err := checkOrgFlags(&params.OrgParams); err != nil {
+				return err
+			}
+			return getSchemaClient(ctx).TagValues(getContext(ctx), &params)
+		},
+	}
+	cmd.BashComplete = schemaBashComplete(&cmd, &params)
+	return cmd
+}
+
+func schemaFlags(params *schema.Params, withMeasurement, withTag bool) []cli.Flag {
+	flags := append(commonFlags(), getOrgFlags(&params.OrgParams)...)
+	flags = append(flags,
+		&cli.StringFlag{
+			Name:        "bucket, b",
+			Usage:       "The name of the bucket to explore",
+			Required:    true,
+			Destination: &params.Bucket,
+		},
+		&cli.StringFlag{
+			Name:        "start",
+			Usage:       "Start of the time range to explore, as an RFC3339 time or a negative duration (ex: -7d)",
+			Value:       schema.DefaultStart,
+			Destination: &params.Start,
+		},
+	)
+	if withMeasurement {
+		flags = append(flags, &cli.StringFlag{
+			Name:        "measurement, m",
+			Usage:       "The measurement to explore",
+			Destination: &params.Measurement,
+		})
+	}
+	if withTag {
+		flags = append(flags, &cli.StringFlag{
+			Name:        "tag",
+			Usage:       "The tag key to list values of",
+			Required:    true,
+			Destination: &params.Tag,
+		})
+	}
+	return flags
+}
+
+func getSchemaClient(ctx *cli.Context) schema.Client {
+	return schema.Client{
+		CLI:      getCLI(ctx),
+		QueryApi: getAPI(ctx).QueryApi,
+	}
+}
+
+// schemaBashComplete completes the values of --measurement and --tag by querying the bucket
+// given by --bucket, and falls back to the default flag completion otherwise.
+func schemaBashComplete(cmd *cli.Command, params *schema.Params) cli.BashCompleteFunc {
+	return func(ctx *cli.Context) {
+		var list func(schema.Client, context.Context, *schema.Params) ([]string, error)
+		if len(os.Args) > 2 && params.Bucket != "" {
+			switch os.Args[len(os.Args)-2] {
+			case "--measurement", "-m":
+				list = schema.Client.ListMeasurements
+			case "--tag":
+				list = schema.Client.ListTagKeys
+			}
+		}
+		if list == nil {
+			cli.DefaultCompleteWithFlags(cmd)(ctx)
+			return
+		}
+		// Before functions aren't run while completing, so the client is set up here.
+		// Errors are ignored so nothing but suggestions is printed.
+		if err := middleware.WithBeforeFns(withCli(), withApi(true))(ctx); err != nil {
+			return
+		}
+		if checkOrgFlags(&params.OrgParams) != nil {
+			return
+		}
+		values, err := list(getSchemaClient(ctx), context.Background(), params)
+		if err != nil {
+			return
+		}
+		for _, v := range values {
+			fmt.Fprintln(ctx.App.Writer, v)
+		}
+	}
+}
@@ -95,7 +95,7 @@ func (p *writeParams) Flags() []cli.Flag {
 		},
 		&cli.GenericFlag{
 			Name:  "format",
-			Usage: "Input format, either 'lp' (Line Protocol) or 'csv' (Comma Separated Values)",
+			Usage: "Input format, either 'lp' (Line Protocol), 'csv' (Comma Separated Values) or 'flux-csv' (annotated CSV returned by queries)",
 			Value: &p.Format,
 		},
 		&cli.StringSliceFlag{
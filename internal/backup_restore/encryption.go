@@ -0,0 +1,375 @@
+package backup_restore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	CipherAES256GCM = "aes-256-gcm"
+	KDFPBKDF2SHA256 = "pbkdf2-sha256"
+
+	defaultPBKDF2Iterations = 600_000
+	defaultEncryptionChunk  = 64 * 1024
+	encryptionKeySize       = 32
+	noncePrefixSize         = 7
+)
+
+// ErrWrongEncryptionKey is returned when a file was encrypted with a different key or passphrase.
+var ErrWrongEncryptionKey = errors.New("backup was encrypted with a different key or passphrase")
+
+// Encryption describes how a backup file was encrypted, so it can be decrypted given the
+// same passphrase or key.
+//
+// Files are split into chunks which are sealed with AES-256-GCM. Each file starts with a random
+// nonce prefix, and the nonce of each chunk is that prefix followed by the chunk's index and a
+// flag marking the final chunk, so chunks can't be reordered, dropped, or truncated unnoticed.
+type Encryption struct {
+	Cipher string `json:"cipher"`
+	// KDF is the function deriving the key from a passphrase, or empty if a key file was used.
+	KDF        string `json:"kdf,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	ChunkSize  int    `json:"chunkSize"`
+	// KeyCheck identifies the key without revealing it, so a wrong key is reported as such
+	// instead of as a corrupt file.
+	KeyCheck string `json:"keyCheck"`
+}
+
+// EncryptionParams configures the passphrase or key used to encrypt and decrypt backups.
+type EncryptionParams struct {
+	Passphrase string
+	// KeyFile is the path to a file holding a 32-byte key, either raw or encoded as hex or base64.
+	KeyFile string
+}
+
+// EncryptionSecret is a passphrase or key used to encrypt and decrypt backup files.
+type EncryptionSecret struct {
+	passphrase string
+	key        []byte
+
+	mu sync.Mutex
+	// derived caches keys derived from the passphrase, by salt and iteration count.
+	derived map[string][]byte
+}
+
+// NewEncryptionSecret loads the secret configured by params, returning nil if neither a
+// passphrase nor a key file is set.
+func NewEncryptionSecret(params EncryptionParams) (*EncryptionSecret, error) {
+	switch {
+	case params.Passphrase != "" && params.KeyFile != "":
+		return nil, errors.New("only one of an encryption passphrase or key file can be given")
+	case params.Passphrase != "":
+		return &EncryptionSecret{passphrase: params.Passphrase, derived: map[string][]byte{}}, nil
+	case params.KeyFile != "":
+		key, err := readKeyFile(params.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &EncryptionSecret{key: key}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+	if len(contents) == encryptionKeySize {
+		return contents, nil
+	}
+	text := string(bytes.TrimSpace(contents))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == encryptionKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == encryptionKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key file %q must hold a %d-byte key, either raw or encoded as hex or base64", path, encryptionKeySize)
+}
+
+// NewEncryption returns the parameters to encrypt the files of a new backup with, using a
+// fresh salt if the secret is a passphrase.
+func (s *EncryptionSecret) NewEncryption() (Encryption, error) {
+	e := Encryption{Cipher: CipherAES256GCM, ChunkSize: defaultEncryptionChunk}
+	if s.passphrase != "" {
+		e.KDF = KDFPBKDF2SHA256
+		e.Iterations = defaultPBKDF2Iterations
+		e.Salt = make([]byte, 16)
+		if _, err := rand.Read(e.Salt); err != nil {
+			return Encryption{}, err
+		}
+	}
+	key, err := s.keyFor(e)
+	if err != nil {
+		return Encryption{}, err
+	}
+	e.KeyCheck = keyCheck(key)
+	return e, nil
+}
+
+// Matches returns whether files encrypted with e can be decrypted with the secret.
+func (s *EncryptionSecret) Matches(e Encryption) bool {
+	_, err := s.checkedKey(e)
+	return err == nil
+}
+
+// checkedKey returns the key of the secret for files encrypted with e, checking it's the one they
+// were encrypted with.
+func (s *EncryptionSecret) checkedKey(e Encryption) ([]byte, error) {
+	if e.Cipher != CipherAES256GCM {
+		return nil, fmt.Errorf("unsupported encryption cipher %q", e.Cipher)
+	}
+	if e.ChunkSize <= 0 {
+		return nil, fmt.Errorf("invalid encryption chunk size %d", e.ChunkSize)
+	}
+	key, err := s.keyFor(e)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(keyCheck(key)), []byte(e.KeyCheck)) {
+		return nil, ErrWrongEncryptionKey
+	}
+	return key, nil
+}
+
+func (s *EncryptionSecret) keyFor(e Encryption) ([]byte, error) {
+	switch e.KDF {
+	case "":
+		if s.key == nil {
+			return nil, errors.New("backup was encrypted with a key file, not a passphrase")
+		}
+		return s.key, nil
+	case KDFPBKDF2SHA256:
+		if s.passphrase == "" {
+			return nil, errors.New("backup was encrypted with a passphrase, not a key file")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := fmt.Sprintf("%x/%d", e.Salt, e.Iterations)
+		if key, ok := s.derived[id]; ok {
+			return key, nil
+		}
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, e.Salt, e.Iterations, encryptionKeySize)
+		if err != nil {
+			return nil, err
+		}
+		s.derived[id] = key
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %q", e.KDF)
+	}
+}
+
+func keyCheck(key []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte("influx backup key check"))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Encrypt returns a writer encrypting everything written to it into w. The writer must be
+// closed to write the final chunk, which doesn't close w.
+func (s *EncryptionSecret) Encrypt(w io.Writer, e Encryption) (io.WriteCloser, error) {
+	aead, err := s.aead(e)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, nonce: newChunkNonce(prefix), chunkSize: e.ChunkSize}, nil
+}
+
+// Decrypt returns a reader of the decrypted contents of r, which was encrypted with e.
+func (s *EncryptionSecret) Decrypt(r io.Reader, e Encryption) (io.Reader, error) {
+	aead, err := s.aead(e)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	return &decryptReader{
+		r:     bufio.NewReader(r),
+		aead:  aead,
+		nonce: newChunkNonce(prefix),
+		chunk: make([]byte, e.ChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (s *EncryptionSecret) aead(e Encryption) (cipher.AEAD, error) {
+	key, err := s.checkedKey(e)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the nonce of the chunks of an encrypted file.
+type chunkNonce []byte
+
+func newChunkNonce(prefix []byte) chunkNonce {
+	n := make(chunkNonce, 12)
+	copy(n, prefix)
+	return n
+}
+
+// next returns the nonce for the given chunk, marking whether it's the final chunk.
+func (n chunkNonce) next(index uint32, final bool) []byte {
+	binary.BigEndian.PutUint32(n[noncePrefixSize:], index)
+	n[len(n)-1] = 0
+	if final {
+		n[len(n)-1] = 1
+	}
+	return n
+}
+
+type encryptWriter struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	nonce     chunkNonce
+	index     uint32
+	chunkSize int
+	buf       []byte
+	closed    bool
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encrypting writer")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, since the final chunk is sealed
+		// differently.
+		if len(w.buf) == w.chunkSize {
+			if err := w.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		take := w.chunkSize - len(w.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+		w.buf = append(w.buf, p[:take]...)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *encryptWriter) seal(final bool) error {
+	if w.index == ^uint32(0) {
+		return errors.New("file is too large to encrypt")
+	}
+	sealed := w.aead.Seal(nil, w.nonce.next(w.index, final), w.buf, nil)
+	w.index++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	nonce chunkNonce
+	index uint32
+	chunk []byte
+	plain []byte
+	done  bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.r, r.chunk)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return errors.New("encrypted file is truncated")
+		}
+		return err
+	}
+	final := n < len(r.chunk)
+	if !final {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			final = true
+		}
+	}
+	plain, err := r.aead.Open(r.chunk[:0], r.nonce.next(r.index, final), r.chunk[:n], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt chunk %d, the file is corrupt or truncated", r.index)
+	}
+	r.index++
+	r.plain = plain
+	r.done = final
+	return nil
+}
+
+// OpenFile returns a reader of the contents of a backup file in storage, decrypting them with
+// secret if the file is encrypted.
+func OpenFile(ctx context.Context, storage Storage, file ManifestFileEntry, secret *EncryptionSecret) (io.ReadCloser, error) {
+	if file.Encryption != nil && secret == nil {
+		return nil, errors.New("file is encrypted, but no encryption passphrase or key file was given")
+	}
+	f, err := storage.Open(ctx, file.Name())
+	if err != nil {
+		return nil, err
+	}
+	if file.Encryption == nil {
+		return f, nil
+	}
+	r, err := secret.Decrypt(f, *file.Encryption)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
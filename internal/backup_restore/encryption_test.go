@@ -0,0 +1,113 @@
+package backup_restore_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Parallel()
+
+	keyFile := filepath.Join(t.TempDir(), "backup.key")
+	key := bytes.Repeat([]byte{0xab}, 32)
+	require.NoError(t, os.WriteFile(keyFile, []byte(hex.EncodeToString(key)+"\n"), 0600))
+	otherKeyFile := filepath.Join(t.TempDir(), "other.key")
+	require.NoError(t, os.WriteFile(otherKeyFile, bytes.Repeat([]byte{0xcd}, 32), 0600))
+
+	newSecret := func(params br.EncryptionParams) *br.EncryptionSecret {
+		secret, err := br.NewEncryptionSecret(params)
+		require.NoError(t, err)
+		require.NotNil(t, secret)
+		return secret
+	}
+	passphrase := newSecret(br.EncryptionParams{Passphrase: "correct horse battery staple"})
+	fromKeyFile := newSecret(br.EncryptionParams{KeyFile: keyFile})
+
+	encrypt := func(secret *br.EncryptionSecret, e br.Encryption, plain []byte) []byte {
+		var out bytes.Buffer
+		w, err := secret.Encrypt(&out, e)
+		require.NoError(t, err)
+		// Write in uneven pieces, to cross chunk boundaries.
+		for p := plain; len(p) > 0; {
+			n := 7
+			if n > len(p) {
+				n = len(p)
+			}
+			_, err := w.Write(p[:n])
+			require.NoError(t, err)
+			p = p[n:]
+		}
+		require.NoError(t, w.Close())
+		return out.Bytes()
+	}
+	decrypt := func(secret *br.EncryptionSecret, e br.Encryption, sealed []byte) ([]byte, error) {
+		r, err := secret.Decrypt(bytes.NewReader(sealed), e)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+
+	for _, secret := range []*br.EncryptionSecret{passphrase, fromKeyFile} {
+		e, err := secret.NewEncryption()
+		require.NoError(t, err)
+		require.Equal(t, br.CipherAES256GCM, e.Cipher)
+		require.True(t, secret.Matches(e))
+		e.ChunkSize = 16
+
+		for _, size := range []int{0, 1, 15, 16, 17, 48, 100} {
+			plain := bytes.Repeat([]byte("0123456789"), 10)[:size]
+			sealed := encrypt(secret, e, plain)
+			require.NotContains(t, string(sealed), "0123456789")
+
+			decrypted, err := decrypt(secret, e, sealed)
+			require.NoError(t, err, "size %d", size)
+			require.Equal(t, plain, decrypted)
+
+			// Dropping the final chunk, or any byte, must be detected.
+			if size > 16 {
+				_, err = decrypt(secret, e, sealed[:len(sealed)-(size%16+16)])
+				require.Error(t, err, "size %d", size)
+			}
+			_, err = decrypt(secret, e, sealed[:len(sealed)-1])
+			require.Error(t, err, "size %d", size)
+			tampered := append([]byte{}, sealed...)
+			tampered[len(tampered)/2] ^= 1
+			_, err = decrypt(secret, e, tampered)
+			require.Error(t, err, "size %d", size)
+		}
+	}
+
+	fromPassphrase, err := passphrase.NewEncryption()
+	require.NoError(t, err)
+	require.Equal(t, br.KDFPBKDF2SHA256, fromPassphrase.KDF)
+	require.Len(t, fromPassphrase.Salt, 16)
+
+	wrongPassphrase := newSecret(br.EncryptionParams{Passphrase: "incorrect horse"})
+	require.False(t, wrongPassphrase.Matches(fromPassphrase))
+	_, err = decrypt(wrongPassphrase, fromPassphrase, encrypt(passphrase, fromPassphrase, []byte("tsm")))
+	require.ErrorIs(t, err, br.ErrWrongEncryptionKey)
+
+	fromKey, err := fromKeyFile.NewEncryption()
+	require.NoError(t, err)
+	require.False(t, newSecret(br.EncryptionParams{KeyFile: otherKeyFile}).Matches(fromKey))
+	require.False(t, passphrase.Matches(fromKey))
+	require.False(t, fromKeyFile.Matches(fromPassphrase))
+
+	secret, err := br.NewEncryptionSecret(br.EncryptionParams{})
+	require.NoError(t, err)
+	require.Nil(t, secret)
+	_, err = br.NewEncryptionSecret(br.EncryptionParams{Passphrase: "p", KeyFile: keyFile})
+	require.Error(t, err)
+	badKeyFile := filepath.Join(t.TempDir(), "bad.key")
+	require.NoError(t, os.WriteFile(badKeyFile, []byte("too short"), 0600))
+	_, err = br.NewEncryptionSecret(br.EncryptionParams{KeyFile: badKeyFile})
+	require.Error(t, err)
+}
@@ -2,6 +2,8 @@ package backup_restore
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/influxdata/influx-cli/v2/api"
@@ -39,7 +41,11 @@ func (c FileCompression) String() string {
 
 const (
 	ManifestExtension = "manifest"
-	ManifestVersion   = 2
+	// ManifestVersion is the latest version of the manifest.
+	ManifestVersion = 3
+	// CompatibleManifestVersion is the version of manifests that every CLI since v2.1.0 can
+	// restore. Backups are written with it unless they need features older CLIs would misread.
+	CompatibleManifestVersion = 2
 )
 
 type Manifest struct {
@@ -47,12 +53,88 @@ type Manifest struct {
 	KV      ManifestFileEntry     `json:"kv"`
 	SQL     *ManifestFileEntry    `json:"sql,omitempty"`
 	Buckets []ManifestBucketEntry `json:"buckets"`
+
+	// IncrementalFrom is the directory of the backup an incremental backup was taken from,
+	// relative to the directory of the manifest. Shards that hadn't changed since that backup
+	// refer to its files instead of being downloaded again.
+	IncrementalFrom string `json:"incrementalFrom,omitempty"`
+
+	// Start and Stop bound the time range of a backup limited to the shard groups overlapping
+	// [Start, Stop). Either is nil if the range is unbounded on that side.
+	Start *time.Time `json:"start,omitempty"`
+	Stop  *time.Time `json:"stop,omitempty"`
+}
+
+// MinVersion returns the version a backup must be written with so that older CLIs refuse to
+// restore it if they would restore it wrongly. They would upload encrypted files to the server
+// as they are, look for files captured by earlier backups in the wrong directory, and restore
+// backups limited to a time range as if they held every shard group, so manifests with encrypted
+// files, referring to earlier backups, or limited to a time range need the latest version.
+func (m Manifest) MinVersion() int {
+	if m.IsPartialRange() {
+		return ManifestVersion
+	}
+	for _, f := range m.Files() {
+		if f.Encryption != nil || f.Dir != "" {
+			return ManifestVersion
+		}
+	}
+	return CompatibleManifestVersion
+}
+
+// Files returns the entries of every file in the backup.
+func (m Manifest) Files() []ManifestFileEntry {
+	files := []ManifestFileEntry{m.KV}
+	if m.SQL != nil {
+		files = append(files, *m.SQL)
+	}
+	for _, b := range m.Buckets {
+		for _, rp := range b.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				for _, s := range sg.Shards {
+					files = append(files, s.ManifestFileEntry)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// IsPartialRange returns whether the backup only holds the shard groups overlapping a time range.
+func (m Manifest) IsPartialRange() bool {
+	return m.Start != nil || m.Stop != nil
+}
+
+// InRange returns whether a shard group with the given bounds overlaps the time range of the backup.
+func (m Manifest) InRange(start, end time.Time) bool {
+	return (m.Stop == nil || start.Before(*m.Stop)) && (m.Start == nil || end.After(*m.Start))
 }
 
 type ManifestFileEntry struct {
 	FileName    string          `json:"fileName"`
 	Size        int64           `json:"size"`
 	Compression FileCompression `json:"compression"`
+	// SHA256 is the hex-encoded checksum of the file as stored, which is empty in manifests
+	// written by older versions of the CLI.
+	SHA256 string `json:"sha256,omitempty"`
+	// Encryption describes how the file was encrypted, after being compressed. It's nil for
+	// files that aren't encrypted.
+	Encryption *Encryption `json:"encryption,omitempty"`
+
+	// Dir is the directory containing the file relative to the directory of the manifest,
+	// using forward slashes. It's only set for files captured by an earlier backup.
+	Dir string `json:"dir,omitempty"`
+}
+
+// Path returns the path of the file described by e, in a backup stored in dir.
+func (e ManifestFileEntry) Path(dir string) string {
+	return filepath.Join(dir, filepath.FromSlash(e.Dir), e.FileName)
+}
+
+// Name returns the name of the file described by e, relative to the root of the storage
+// holding its manifest.
+func (e ManifestFileEntry) Name() string {
+	return path.Join(e.Dir, e.FileName)
 }
 
 type ManifestBucketEntry struct {
@@ -182,3 +182,78 @@ func TestConvertBucketManifest(t *testing.T) {
 
 	require.Equal(t, expected, converted)
 }
+
+func TestManifest_MinVersion(t *testing.T) {
+	t.Parallel()
+
+	shard := func(file br.ManifestFileEntry) br.ManifestBucketEntry {
+		return br.ManifestBucketEntry{
+			RetentionPolicies: []br.ManifestRetentionPolicy{{
+				ShardGroups: []br.ManifestShardGroup{{Shards: []br.ManifestShardEntry{{ID: 1, ManifestFileEntry: file}}}},
+			}},
+		}
+	}
+	encrypted := br.ManifestFileEntry{FileName: "encrypted", Encryption: &br.Encryption{Cipher: br.CipherAES256GCM}}
+
+	testCases := []struct {
+		name     string
+		manifest br.Manifest
+		expected int
+	}{
+		{
+			name: "plain",
+			manifest: br.Manifest{
+				KV:      br.ManifestFileEntry{FileName: "kv"},
+				Buckets: []br.ManifestBucketEntry{shard(br.ManifestFileEntry{FileName: "shard"})},
+			},
+			expected: br.CompatibleManifestVersion,
+		},
+		{
+			name:     "encrypted KV",
+			manifest: br.Manifest{KV: encrypted},
+			expected: br.ManifestVersion,
+		},
+		{
+			name: "encrypted shard",
+			manifest: br.Manifest{
+				KV:      br.ManifestFileEntry{FileName: "kv"},
+				Buckets: []br.ManifestBucketEntry{shard(encrypted)},
+			},
+			expected: br.ManifestVersion,
+		},
+		{
+			name: "incremental",
+			manifest: br.Manifest{
+				KV:              br.ManifestFileEntry{FileName: "kv"},
+				Buckets:         []br.ManifestBucketEntry{shard(br.ManifestFileEntry{FileName: "shard", Dir: "../earlier"})},
+				IncrementalFrom: "../earlier",
+			},
+			expected: br.ManifestVersion,
+		},
+		{
+			name: "incremental without reused shards",
+			manifest: br.Manifest{
+				KV:              br.ManifestFileEntry{FileName: "kv"},
+				Buckets:         []br.ManifestBucketEntry{shard(br.ManifestFileEntry{FileName: "shard"})},
+				IncrementalFrom: "../earlier",
+			},
+			expected: br.CompatibleManifestVersion,
+		},
+		{
+			name: "partial range",
+			manifest: br.Manifest{
+				KV:    br.ManifestFileEntry{FileName: "kv"},
+				Start: &time.Time{},
+			},
+			expected: br.ManifestVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, tc.manifest.MinVersion())
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package backup_restore_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunParallel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bounded", func(t *testing.T) {
+		t.Parallel()
+
+		var running, maxRunning int32
+		var mu sync.Mutex
+		seen := map[int]bool{}
+		err := br.RunParallel(context.Background(), 3, 20, func(_ context.Context, i int) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			seen[i] = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, seen, 20)
+		require.LessOrEqual(t, maxRunning, int32(3))
+	})
+
+	t.Run("stops on error", func(t *testing.T) {
+		t.Parallel()
+
+		failure := errors.New("boom")
+		var calls int32
+		err := br.RunParallel(context.Background(), 2, 100, func(_ context.Context, i int) error {
+			atomic.AddInt32(&calls, 1)
+			if i == 3 {
+				return failure
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, failure)
+		require.Less(t, atomic.LoadInt32(&calls), int32(100))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := br.RunParallel(ctx, 1, 10, func(context.Context, int) error { return nil })
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,358 @@
+package backup_restore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultS3PartSize is the size of the parts of multipart uploads, which must be at
+	// least 5MiB for all parts but the last.
+	DefaultS3PartSize = 16 * 1024 * 1024
+	defaultS3Region   = "us-east-1"
+)
+
+// S3Storage keeps backup files in a bucket of S3-compatible object storage, under a prefix.
+// Files are streamed to storage using multipart uploads, buffering one part in memory.
+type S3Storage struct {
+	S3Config
+	Bucket string
+	Prefix string
+
+	Client   *http.Client
+	PartSize int
+}
+
+func NewS3Storage(config S3Config, bucket, prefix string) (*S3Storage, error) {
+	if config.Endpoint == "" {
+		region := config.Region
+		if region == "" {
+			region = defaultS3Region
+		}
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	if _, err := url.Parse(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", config.Endpoint, err)
+	}
+	if config.Region == "" {
+		config.Region = defaultS3Region
+	}
+	return &S3Storage{
+		S3Config: config,
+		Bucket:   bucket,
+		Prefix:   prefix,
+		Client:   http.DefaultClient,
+		PartSize: DefaultS3PartSize,
+	}, nil
+}
+
+func (s *S3Storage) Location(name string) string {
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, s.key(name))
+}
+
+// key returns the object key of the file with the given name.
+func (s *S3Storage) key(name string) string {
+	return strings.TrimPrefix(path.Join("/", s.Prefix, name), "/")
+}
+
+func (s *S3Storage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, s.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3Storage) List(ctx context.Context) ([]string, error) {
+	prefix := ""
+	if s.Prefix != "" {
+		prefix = s.Prefix + "/"
+	}
+	var names []string
+	query := url.Values{"list-type": {"2"}, "delimiter": {"/"}, "prefix": {prefix}}
+	for {
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := s.doXML(ctx, http.MethodGet, "", query, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, prefix))
+		}
+		if !result.IsTruncated {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *S3Storage) Create(ctx context.Context, name string) (FileWriter, error) {
+	return &s3Writer{s: s, ctx: ctx, key: s.key(name)}, nil
+}
+
+// s3Writer uploads a file in parts as it's written. Files smaller than a single part are
+// uploaded with a single request when the writer is closed.
+type s3Writer struct {
+	s   *S3Storage
+	ctx context.Context
+	key string
+
+	buf      bytes.Buffer
+	uploadID string
+	parts    []s3CompletedPart
+	err      error
+}
+
+type s3CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf.Write(p)
+	for w.buf.Len() >= w.s.PartSize {
+		if w.err = w.uploadPart(w.buf.Next(w.s.PartSize)); w.err != nil {
+			w.Abort()
+			return 0, w.err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *s3Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.uploadID == "" {
+		res, err := w.s.do(w.ctx, http.MethodPut, w.key, nil, w.buf.Bytes())
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+	if w.buf.Len() > 0 {
+		if w.err = w.uploadPart(w.buf.Bytes()); w.err != nil {
+			w.Abort()
+			return w.err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: w.parts})
+	if err != nil {
+		return err
+	}
+	if err := w.s.doXML(w.ctx, http.MethodPost, w.key, url.Values{"uploadId": {w.uploadID}}, body, nil); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to complete upload of %q: %w", w.key, err)
+	}
+	return nil
+}
+
+func (w *s3Writer) uploadPart(part []byte) error {
+	if w.uploadID == "" {
+		var result struct {
+			UploadId string
+		}
+		if err := w.s.doXML(w.ctx, http.MethodPost, w.key, url.Values{"uploads": {""}}, nil, &result); err != nil {
+			return fmt.Errorf("failed to start upload of %q: %w", w.key, err)
+		}
+		w.uploadID = result.UploadId
+	}
+
+	partNumber := len(w.parts) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {w.uploadID}}
+	res, err := w.s.do(w.ctx, http.MethodPut, w.key, query, part)
+	if err != nil {
+		return fmt.Errorf("failed to upload part %d of %q: %w", partNumber, w.key, err)
+	}
+	_ = res.Body.Close()
+	w.parts = append(w.parts, s3CompletedPart{PartNumber: partNumber, ETag: res.Header.Get("ETag")})
+	return nil
+}
+
+// Abort discards the parts uploaded so far, so they aren't kept (and billed) by the storage.
+func (w *s3Writer) Abort() {
+	if w.err == nil {
+		w.err = errors.New("upload was aborted")
+	}
+	if w.uploadID == "" {
+		return
+	}
+	uploadID := w.uploadID
+	w.uploadID = ""
+	// Use a fresh context, since the upload may have failed because w.ctx was cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if res, err := w.s.do(ctx, http.MethodDelete, w.key, url.Values{"uploadId": {uploadID}}, nil); err == nil {
+		_ = res.Body.Close()
+	}
+}
+
+// doXML sends a request, decoding the XML response body into result if it's not nil.
+func (s *S3Storage) doXML(ctx context.Context, method, key string, query url.Values, body []byte, result interface{}) error {
+	res, err := s.do(ctx, method, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := xml.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode S3 response: %w", err)
+	}
+	return nil
+}
+
+// do sends a signed request for the object with the given key, or for the bucket if the key
+// is empty, returning an error for responses without a 2xx status.
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/" + s3Escape(s.Bucket, false)
+	if key != "" {
+		u += "/" + s3Escape(key, true)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = s3CanonicalQuery(query)
+	s.sign(req, body, time.Now().UTC())
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var s3Err struct {
+		Code    string
+		Message string
+	}
+	if res.StatusCode == http.StatusNotFound && method == http.MethodGet && key != "" {
+		return nil, fmt.Errorf("s3://%s/%s not found", s.Bucket, key)
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&s3Err); err != nil || s3Err.Code == "" {
+		return nil, fmt.Errorf("S3 request %s %s failed with status %d", method, u, res.StatusCode)
+	}
+	return nil, fmt.Errorf("S3 request %s %s failed: %s: %s", method, u, s3Err.Code, s3Err.Message)
+}
+
+// sign adds an AWS Signature Version 4 to req. Requests are sent unsigned if no credentials
+// are configured.
+func (s *S3Storage) sign(req *http.Request, body []byte, now time.Time) {
+	if s.AccessKeyID == "" {
+		return
+	}
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.Join(v, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3CanonicalQuery encodes query as required by AWS signatures: sorted by key, with every
+// character but the unreserved ones escaped.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes every character of s but the unreserved ones, and optionally '/'.
+func s3Escape(s string, keepSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
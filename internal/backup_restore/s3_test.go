@@ -0,0 +1,101 @@
+package backup_restore_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	br "github.com/influxdata/influx-cli/v2/internal/backup_restore"
+	"github.com/influxdata/influx-cli/v2/internal/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Storage(t *testing.T) {
+	t.Parallel()
+
+	fake := testutils.NewFakeS3(t)
+	fake.AccessKeyID = "minio"
+	fake.MinPartSize = 4
+	fake.MaxKeys = 2
+	fake.PutObject("backups", "other/20210101T000000Z.manifest", []byte("{}"))
+
+	storage, err := br.NewStorage("s3://backups/influx/next", br.S3Config{
+		Endpoint:        fake.URL,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+	})
+	require.NoError(t, err)
+	s3Storage := storage.(*br.S3Storage)
+	s3Storage.PartSize = 4
+	ctx := context.Background()
+
+	write := func(name, contents string) {
+		w, err := storage.Create(ctx, name)
+		require.NoError(t, err)
+		// Write in uneven pieces, so parts are cut from the middle of writes.
+		for _, piece := range strings.SplitAfter(contents, " ") {
+			_, err := io.WriteString(w, piece)
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+	}
+	write("small", "abc")
+	write("20210101T000000Z.manifest", "{}")
+	write("20210101T000000Z.1.tar.gz", "a multipart upload of several parts")
+	write("../prev/20200101T000000Z.1.tar.gz", "prev")
+	write("sub/ignored", "nested files aren't listed")
+
+	require.Equal(t, map[string][]byte{
+		"backups/other/20210101T000000Z.manifest":       []byte("{}"),
+		"backups/influx/next/small":                     []byte("abc"),
+		"backups/influx/next/20210101T000000Z.manifest": []byte("{}"),
+		"backups/influx/next/20210101T000000Z.1.tar.gz": []byte("a multipart upload of several parts"),
+		"backups/influx/prev/20200101T000000Z.1.tar.gz": []byte("prev"),
+		"backups/influx/next/sub/ignored":               []byte("nested files aren't listed"),
+	}, fake.Objects())
+	require.Equal(t, 17, fake.UploadedParts())
+	require.Zero(t, fake.PendingUploads())
+
+	names, err := storage.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"20210101T000000Z.1.tar.gz", "20210101T000000Z.manifest", "small"}, names)
+
+	r, err := storage.Open(ctx, "../prev/20200101T000000Z.1.tar.gz")
+	require.NoError(t, err)
+	contents, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, "prev", string(contents))
+
+	_, err = storage.Open(ctx, "missing")
+	require.EqualError(t, err, "s3://backups/influx/next/missing not found")
+
+	// Uploads that fail part way are aborted.
+	s3Storage.AccessKeyID = "wrong"
+	w, err := storage.Create(ctx, "denied")
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte("x"), 10))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "AccessDenied: Access Denied.")
+	require.Error(t, w.Close())
+	require.Zero(t, fake.PendingUploads())
+	require.NotContains(t, fake.Objects(), "backups/influx/next/denied")
+}
+
+func TestNewStorage(t *testing.T) {
+	t.Parallel()
+
+	storage, err := br.NewStorage("/path/to/backup", br.S3Config{})
+	require.NoError(t, err)
+	require.Equal(t, br.LocalStorage{Dir: "/path/to/backup"}, storage)
+
+	storage, err = br.NewStorage("s3://bucket", br.S3Config{Region: "eu-west-1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://s3.eu-west-1.amazonaws.com", storage.(*br.S3Storage).Endpoint)
+	require.Equal(t, "s3://bucket/20210101T000000Z.manifest", storage.Location("20210101T000000Z.manifest"))
+
+	_, err = br.NewStorage("s3:///prefix", br.S3Config{})
+	require.EqualError(t, err, `backup location "s3:///prefix" is missing a bucket name`)
+}
@@ -0,0 +1,111 @@
+package backup_restore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Storage is where the files of a backup are kept. Files are named by slash-separated paths
+// relative to the root of the storage, which may refer to the parent of the root with "..".
+type Storage interface {
+	// Create returns a writer for a new file, replacing any existing file with the same name.
+	// The file is only guaranteed to be stored once the writer has been closed without error.
+	Create(ctx context.Context, name string) (FileWriter, error)
+	// Open returns a reader of the contents of a file.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the files directly within the root of the storage, sorted
+	// in ascending order.
+	List(ctx context.Context) ([]string, error)
+	// Location returns the path or URL of a file for messages, or of the root of the storage
+	// if name is empty.
+	Location(name string) string
+}
+
+// FileWriter writes a new file to storage.
+type FileWriter interface {
+	io.WriteCloser
+	// Abort discards the file, and is called instead of Close if writing it failed.
+	Abort()
+}
+
+// S3Config holds the settings used to connect to S3-compatible object storage.
+type S3Config struct {
+	// Endpoint is the URL of the object storage service, like https://s3.us-west-2.amazonaws.com.
+	// Buckets are addressed by path, so it can also be a MinIO server.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// NewStorage returns the storage at location, which is either a local directory or a URL
+// like s3://bucket/prefix.
+func NewStorage(location string, s3Config S3Config) (Storage, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return LocalStorage{Dir: location}, nil
+	}
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("backup location %q is missing a bucket name", location)
+	}
+	return NewS3Storage(s3Config, bucket, strings.Trim(prefix, "/"))
+}
+
+// LocalStorage keeps backup files in a local directory.
+type LocalStorage struct {
+	Dir string
+}
+
+func (s LocalStorage) Path(name string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(name))
+}
+
+func (s LocalStorage) Create(_ context.Context, name string) (FileWriter, error) {
+	p := s.Path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return localFileWriter{f}, nil
+}
+
+func (s LocalStorage) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(s.Path(name))
+}
+
+func (s LocalStorage) List(context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s LocalStorage) Location(name string) string {
+	return s.Path(name)
+}
+
+type localFileWriter struct {
+	*os.File
+}
+
+func (w localFileWriter) Abort() {
+	_ = w.File.Close()
+	_ = os.Remove(w.File.Name())
+}
@@ -0,0 +1,258 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/influxdata/influx-cli/v2/api (interfaces: RestoreApi)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	http "net/http"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	api "github.com/influxdata/influx-cli/v2/api"
+)
+
+// MockRestoreApi is a mock of RestoreApi interface.
+type MockRestoreApi struct {
+	ctrl     *gomock.Controller
+	recorder *MockRestoreApiMockRecorder
+}
+
+// MockRestoreApiMockRecorder is the mock recorder for MockRestoreApi.
+type MockRestoreApiMockRecorder struct {
+	mock *MockRestoreApi
+}
+
+// NewMockRestoreApi creates a new mock instance.
+func NewMockRestoreApi(ctrl *gomock.Controller) *MockRestoreApi {
+	mock := &MockRestoreApi{ctrl: ctrl}
+	mock.recorder = &MockRestoreApiMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRestoreApi) EXPECT() *MockRestoreApiMockRecorder {
+	return m.recorder
+}
+
+// PostRestoreBucketID mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketID(arg0 context.Context, arg1 string) api.ApiPostRestoreBucketIDRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketID", arg0, arg1)
+	ret0, _ := ret[0].(api.ApiPostRestoreBucketIDRequest)
+	return ret0
+}
+
+// PostRestoreBucketID indicates an expected call of PostRestoreBucketID.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketID", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketID), arg0, arg1)
+}
+
+// PostRestoreBucketIDExecute mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketIDExecute(arg0 api.ApiPostRestoreBucketIDRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketIDExecute", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostRestoreBucketIDExecute indicates an expected call of PostRestoreBucketIDExecute.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketIDExecute(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketIDExecute", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketIDExecute), arg0)
+}
+
+// PostRestoreBucketIDExecuteWithHttpInfo mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketIDExecuteWithHttpInfo(arg0 api.ApiPostRestoreBucketIDRequest) (string, *http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketIDExecuteWithHttpInfo", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*http.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PostRestoreBucketIDExecuteWithHttpInfo indicates an expected call of PostRestoreBucketIDExecuteWithHttpInfo.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketIDExecuteWithHttpInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketIDExecuteWithHttpInfo", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketIDExecuteWithHttpInfo), arg0)
+}
+
+// PostRestoreBucketMetadata mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketMetadata(arg0 context.Context) api.ApiPostRestoreBucketMetadataRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketMetadata", arg0)
+	ret0, _ := ret[0].(api.ApiPostRestoreBucketMetadataRequest)
+	return ret0
+}
+
+// PostRestoreBucketMetadata indicates an expected call of PostRestoreBucketMetadata.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketMetadata(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketMetadata", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketMetadata), arg0)
+}
+
+// PostRestoreBucketMetadataExecute mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketMetadataExecute(arg0 api.ApiPostRestoreBucketMetadataRequest) (api.RestoredBucketMappings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketMetadataExecute", arg0)
+	ret0, _ := ret[0].(api.RestoredBucketMappings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostRestoreBucketMetadataExecute indicates an expected call of PostRestoreBucketMetadataExecute.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketMetadataExecute(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketMetadataExecute", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketMetadataExecute), arg0)
+}
+
+// PostRestoreBucketMetadataExecuteWithHttpInfo mocks base method.
+func (m *MockRestoreApi) PostRestoreBucketMetadataExecuteWithHttpInfo(arg0 api.ApiPostRestoreBucketMetadataRequest) (api.RestoredBucketMappings, *http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreBucketMetadataExecuteWithHttpInfo", arg0)
+	ret0, _ := ret[0].(api.RestoredBucketMappings)
+	ret1, _ := ret[1].(*http.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PostRestoreBucketMetadataExecuteWithHttpInfo indicates an expected call of PostRestoreBucketMetadataExecuteWithHttpInfo.
+func (mr *MockRestoreApiMockRecorder) PostRestoreBucketMetadataExecuteWithHttpInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreBucketMetadataExecuteWithHttpInfo", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreBucketMetadataExecuteWithHttpInfo), arg0)
+}
+
+// PostRestoreKV mocks base method.
+func (m *MockRestoreApi) PostRestoreKV(arg0 context.Context) api.ApiPostRestoreKVRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreKV", arg0)
+	ret0, _ := ret[0].(api.ApiPostRestoreKVRequest)
+	return ret0
+}
+
+// PostRestoreKV indicates an expected call of PostRestoreKV.
+func (mr *MockRestoreApiMockRecorder) PostRestoreKV(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreKV", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreKV), arg0)
+}
+
+// PostRestoreKVExecute mocks base method.
+func (m *MockRestoreApi) PostRestoreKVExecute(arg0 api.ApiPostRestoreKVRequest) (api.PostRestoreKVResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreKVExecute", arg0)
+	ret0, _ := ret[0].(api.PostRestoreKVResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostRestoreKVExecute indicates an expected call of PostRestoreKVExecute.
+func (mr *MockRestoreApiMockRecorder) PostRestoreKVExecute(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreKVExecute", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreKVExecute), arg0)
+}
+
+// PostRestoreKVExecuteWithHttpInfo mocks base method.
+func (m *MockRestoreApi) PostRestoreKVExecuteWithHttpInfo(arg0 api.ApiPostRestoreKVRequest) (api.PostRestoreKVResponse, *http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreKVExecuteWithHttpInfo", arg0)
+	ret0, _ := ret[0].(api.PostRestoreKVResponse)
+	ret1, _ := ret[1].(*http.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PostRestoreKVExecuteWithHttpInfo indicates an expected call of PostRestoreKVExecuteWithHttpInfo.
+func (mr *MockRestoreApiMockRecorder) PostRestoreKVExecuteWithHttpInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreKVExecuteWithHttpInfo", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreKVExecuteWithHttpInfo), arg0)
+}
+
+// PostRestoreSQL mocks base method.
+func (m *MockRestoreApi) PostRestoreSQL(arg0 context.Context) api.ApiPostRestoreSQLRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreSQL", arg0)
+	ret0, _ := ret[0].(api.ApiPostRestoreSQLRequest)
+	return ret0
+}
+
+// PostRestoreSQL indicates an expected call of PostRestoreSQL.
+func (mr *MockRestoreApiMockRecorder) PostRestoreSQL(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreSQL", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreSQL), arg0)
+}
+
+// PostRestoreSQLExecute mocks base method.
+func (m *MockRestoreApi) PostRestoreSQLExecute(arg0 api.ApiPostRestoreSQLRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreSQLExecute", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PostRestoreSQLExecute indicates an expected call of PostRestoreSQLExecute.
+func (mr *MockRestoreApiMockRecorder) PostRestoreSQLExecute(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreSQLExecute", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreSQLExecute), arg0)
+}
+
+// PostRestoreSQLExecuteWithHttpInfo mocks base method.
+func (m *MockRestoreApi) PostRestoreSQLExecuteWithHttpInfo(arg0 api.ApiPostRestoreSQLRequest) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreSQLExecuteWithHttpInfo", arg0)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostRestoreSQLExecuteWithHttpInfo indicates an expected call of PostRestoreSQLExecuteWithHttpInfo.
+func (mr *MockRestoreApiMockRecorder) PostRestoreSQLExecuteWithHttpInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreSQLExecuteWithHttpInfo", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreSQLExecuteWithHttpInfo), arg0)
+}
+
+// PostRestoreShardId mocks base method.
+func (m *MockRestoreApi) PostRestoreShardId(arg0 context.Context, arg1 string) api.ApiPostRestoreShardIdRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreShardId", arg0, arg1)
+	ret0, _ := ret[0].(api.ApiPostRestoreShardIdRequest)
+	return ret0
+}
+
+// PostRestoreShardId indicates an expected call of PostRestoreShardId.
+func (mr *MockRestoreApiMockRecorder) PostRestoreShardId(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreShardId", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreShardId), arg0, arg1)
+}
+
+// PostRestoreShardIdExecute mocks base method.
+func (m *MockRestoreApi) PostRestoreShardIdExecute(arg0 api.ApiPostRestoreShardIdRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreShardIdExecute", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PostRestoreShardIdExecute indicates an expected call of PostRestoreShardIdExecute.
+func (mr *MockRestoreApiMockRecorder) PostRestoreShardIdExecute(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreShardIdExecute", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreShardIdExecute), arg0)
+}
+
+// PostRestoreShardIdExecuteWithHttpInfo mocks base method.
+func (m *MockRestoreApi) PostRestoreShardIdExecuteWithHttpInfo(arg0 api.ApiPostRestoreShardIdRequest) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostRestoreShardIdExecuteWithHttpInfo", arg0)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostRestoreShardIdExecuteWithHttpInfo indicates an expected call of PostRestoreShardIdExecuteWithHttpInfo.
+func (mr *MockRestoreApiMockRecorder) PostRestoreShardIdExecuteWithHttpInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostRestoreShardIdExecuteWithHttpInfo", reflect.TypeOf((*MockRestoreApi)(nil).PostRestoreShardIdExecuteWithHttpInfo), arg0)
+}
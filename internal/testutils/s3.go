@@ -0,0 +1,237 @@
+package testutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// FakeS3 is an in-memory stand-in for S3-compatible object storage like MinIO, supporting
+// the subset of the API used by backups: single and multipart uploads, downloads, and listing.
+type FakeS3 struct {
+	*httptest.Server
+	// AccessKeyID is required in the credentials of signed requests, if set.
+	AccessKeyID string
+	// MinPartSize is the minimum size of all but the last part of multipart uploads.
+	MinPartSize int
+	// MaxKeys is the number of keys listed per page.
+	MaxKeys int
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	uploadID int
+	parts    int
+}
+
+func NewFakeS3(t *testing.T) *FakeS3 {
+	f := &FakeS3{
+		MinPartSize: 5 * 1024 * 1024,
+		MaxKeys:     1000,
+		objects:     map[string][]byte{},
+		uploads:     map[string]map[int][]byte{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Objects returns a copy of the stored objects, keyed by "<bucket>/<key>".
+func (f *FakeS3) Objects() map[string][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	objects := make(map[string][]byte, len(f.objects))
+	for k, v := range f.objects {
+		objects[k] = v
+	}
+	return objects
+}
+
+// PendingUploads returns the number of multipart uploads that were neither completed nor aborted.
+func (f *FakeS3) PendingUploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+// UploadedParts returns the number of parts uploaded by multipart uploads.
+func (f *FakeS3) UploadedParts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.parts
+}
+
+// PutObject stores an object, as if uploaded by a client.
+func (f *FakeS3) PutObject(bucket, key string, contents []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[bucket+"/"+key] = contents
+}
+
+func (f *FakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if f.AccessKeyID != "" {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential="+f.AccessKeyID+"/") {
+			writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied.")
+			return
+		}
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			writeS3Error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.")
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	object := bucket + "/" + key
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, bucket, query)
+	case r.Method == http.MethodGet:
+		contents, ok := f.objects[object]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		_, _ = w.Write(contents)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+			return
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[partNumber] = body
+		f.parts++
+		w.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, partNumber, len(body)))
+	case r.Method == http.MethodPut:
+		f.objects[object] = body
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.uploadID++
+		id := strconv.Itoa(f.uploadID)
+		f.uploads[id] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucket, Key: key, UploadId: id})
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, object, query.Get("uploadId"), body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", "A header you provided implies functionality that is not implemented.")
+	}
+}
+
+func (f *FakeS3) complete(w http.ResponseWriter, object, uploadID string, body []byte) {
+	uploaded, ok := f.uploads[uploadID]
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+	var req struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	var contents []byte
+	for i, p := range req.Parts {
+		part, ok := uploaded[p.PartNumber]
+		if !ok || p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"%d-%d"`, p.PartNumber, len(part)) {
+			writeS3Error(w, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found.")
+			return
+		}
+		if i < len(req.Parts)-1 && len(part) < f.MinPartSize {
+			writeS3Error(w, http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.")
+			return
+		}
+		contents = append(contents, part...)
+	}
+	f.objects[object] = contents
+	delete(f.uploads, uploadID)
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	}{})
+}
+
+func (f *FakeS3) list(w http.ResponseWriter, bucket string, query map[string][]string) {
+	get := func(k string) string {
+		if v := query[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	prefix, delimiter, token := get("prefix"), get("delimiter"), get("continuation-token")
+	var keys []string
+	for object := range f.objects {
+		key := strings.TrimPrefix(object, bucket+"/")
+		if key == object || !strings.HasPrefix(key, prefix) || key <= token {
+			continue
+		}
+		if delimiter != "" && strings.Contains(strings.TrimPrefix(key, prefix), delimiter) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string
+		Size int
+	}
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+	if len(keys) > f.MaxKeys {
+		keys = keys[:f.MaxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		result.Contents = append(result.Contents, content{Key: k, Size: len(f.objects[bucket+"/"+k])})
+	}
+	writeXML(w, result)
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
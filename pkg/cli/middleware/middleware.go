@@ -25,7 +25,11 @@ func AddMWToCmds(cmds []cli.Command, mw cli.BeforeFunc) []cli.Command {
 	newCmds := make([]cli.Command, 0, len(cmds))
 
 	for _, cmd := range cmds {
-		cmd.Before = WithBeforeFns(cmd.Before, mw)
+		if cmd.Before == nil {
+			cmd.Before = mw
+		} else {
+			cmd.Before = WithBeforeFns(cmd.Before, mw)
+		}
 		newCmds = append(newCmds, cmd)
 	}
 
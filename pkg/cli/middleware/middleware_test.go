@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influx-cli/v2/pkg/cli/middleware"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func TestAddMWToCmds(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		before   bool
+		expected []string
+	}{
+		{
+			name:     "with before",
+			before:   true,
+			expected: []string{"before", "middleware", "action"},
+		},
+		{
+			// Commands with subcommands often don't need a Before func of their own.
+			name:     "without before",
+			expected: []string{"middleware", "action"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls []string
+			record := func(name string) func(*cli.Context) error {
+				return func(*cli.Context) error {
+					calls = append(calls, name)
+					return nil
+				}
+			}
+			cmd := cli.Command{Name: "command", Action: record("action")}
+			if tc.before {
+				cmd.Before = record("before")
+			}
+
+			app := cli.NewApp()
+			app.Commands = middleware.AddMWToCmds([]cli.Command{cmd}, record("middleware"))
+			require.NoError(t, app.Run([]string{"test", "command"}))
+			require.Equal(t, tc.expected, calls)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package csv2lp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/fluxcsv"
+)
+
+// fluxCsvSystemColumns are columns of flux query results that never become tags or fields
+var fluxCsvSystemColumns = map[string]bool{
+	"_start":           true,
+	"_stop":            true,
+	"_time":            true,
+	"_measurement":     true,
+	"_field":           true,
+	"_value":           true,
+	fluxcsv.ResultCol:  true,
+	fluxcsv.TableIdCol: true,
+}
+
+// FluxCsvToLineReader transforms the annotated CSV returned by flux queries back to line protocol.
+//
+// Group key columns become tags. Tables with _field and _value columns (as returned by from/range)
+// produce one field per row, and their other columns also become tags, as they do after group().
+// Tables without them (for example pivoted ones) turn every other column into a field.
+// Consecutive rows of the same series and time are merged into a single line.
+type FluxCsvToLineReader struct {
+	result *fluxcsv.QueryTableResult
+	// log row conversion errors to stderr and continue with processing
+	skipRowOnError bool
+
+	// line being assembled from consecutive rows
+	pendingSeries  []byte
+	pendingFields  []byte
+	pendingTime    time.Time
+	pendingHasTime bool
+
+	// reader results
+	buffer   []byte
+	index    int
+	finished error
+}
+
+// SkipRowOnError controls whether to fail on every conversion error (false) or to log the error and continue (true)
+func (state *FluxCsvToLineReader) SkipRowOnError(val bool) *FluxCsvToLineReader {
+	state.skipRowOnError = val
+	return state
+}
+
+// Read implements io.Reader that returns protocol lines
+func (state *FluxCsvToLineReader) Read(p []byte) (n int, err error) {
+	// state1: some data are in the buffer to copy
+	if len(state.buffer) > state.index {
+		n = copy(p, state.buffer[state.index:])
+		state.index += n
+		if state.index == len(state.buffer) {
+			state.buffer = state.buffer[:0]
+			state.index = 0
+		}
+		return n, nil
+	}
+	// state2: finished
+	if state.finished != nil {
+		return 0, state.finished
+	}
+	// state3: fill buffer with data to read from
+	for len(state.buffer) == 0 {
+		if !state.result.Next() {
+			state.flushPending()
+			state.finished = io.EOF
+			if err := state.result.Err(); err != nil {
+				state.finished = fmt.Errorf("failed to parse flux csv: %w", err)
+			}
+			break
+		}
+		record := state.result.Record()
+		if err := state.addRecord(record); err != nil {
+			err = fmt.Errorf("table %d: %w", record.TableId(), err)
+			if state.skipRowOnError {
+				log.Println(err)
+				continue
+			}
+			state.flushPending()
+			state.finished = err
+			break
+		}
+	}
+	return state.Read(p)
+}
+
+// addRecord adds the fields of record to the pending line, flushing the pending line to
+// the buffer first if record belongs to a different series or time.
+func (state *FluxCsvToLineReader) addRecord(record *fluxcsv.FluxRecord) error {
+	metadata := state.result.Metadata()
+	values := record.Values()
+
+	measurement, ok := values["_measurement"].(string)
+	if !ok || measurement == "" {
+		return errors.New("no _measurement value")
+	}
+	series := append(make([]byte, 0, 64), escapeMeasurement(measurement)...)
+	field, hasField := values["_field"].(string)
+	tags := make([]string, 0, len(metadata.Columns()))
+	for _, column := range metadata.Columns() {
+		name := column.Name()
+		if fluxCsvSystemColumns[name] || values[name] == nil {
+			continue
+		}
+		// columns outside of the group key are fields of pivoted tables, but can only be tags of
+		// tables with a _field column
+		if column.IsGroup() || hasField {
+			tags = append(tags, name)
+		}
+	}
+	sort.Strings(tags)
+	for _, name := range tags {
+		series = append(series, ',')
+		series = append(series, escapeTag(name)...)
+		series = append(series, '=')
+		series = append(series, escapeTag(fmt.Sprint(values[name]))...)
+	}
+
+	var fields []byte
+	var err error
+	if hasField {
+		if fields, err = appendFluxField(fields, field, values["_value"]); err != nil {
+			return err
+		}
+	} else {
+		for _, column := range metadata.Columns() {
+			name := column.Name()
+			if column.IsGroup() || fluxCsvSystemColumns[name] {
+				continue
+			}
+			if fields, err = appendFluxField(fields, name, values[name]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(fields) == 0 {
+		// nothing to write
+		return nil
+	}
+
+	timestamp, hasTime := values["_time"].(time.Time)
+	if state.pendingFields != nil && bytes.Equal(series, state.pendingSeries) &&
+		hasTime == state.pendingHasTime && timestamp.Equal(state.pendingTime) {
+		state.pendingFields = append(state.pendingFields, ',')
+		state.pendingFields = append(state.pendingFields, fields...)
+		return nil
+	}
+	state.flushPending()
+	state.pendingSeries = series
+	state.pendingFields = fields
+	state.pendingTime, state.pendingHasTime = timestamp, hasTime
+	return nil
+}
+
+// flushPending appends the pending line to the buffer
+func (state *FluxCsvToLineReader) flushPending() {
+	if state.pendingFields == nil {
+		return
+	}
+	state.buffer = append(state.buffer, state.pendingSeries...)
+	state.buffer = append(state.buffer, ' ')
+	state.buffer = append(state.buffer, state.pendingFields...)
+	if state.pendingHasTime {
+		state.buffer = append(state.buffer, ' ')
+		state.buffer = strconv.AppendInt(state.buffer, state.pendingTime.UnixNano(), 10)
+	}
+	state.buffer = append(state.buffer, '\n')
+	state.pendingSeries, state.pendingFields = nil, nil
+}
+
+// appendFluxField appends a key=value field to buffer, null values are skipped
+func appendFluxField(buffer []byte, key string, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return buffer, nil
+	case time.Time:
+		// line protocol has no time type, store times as nanoseconds since the epoch
+		value = v.UnixNano()
+	case []byte:
+		// binary values can only be stored as strings
+		value = base64.StdEncoding.EncodeToString(v)
+	}
+	if len(buffer) > 0 {
+		buffer = append(buffer, ',')
+	}
+	buffer = append(buffer, escapeTag(key)...)
+	buffer = append(buffer, '=')
+	buffer, err := appendProtocolValue(buffer, value)
+	if err != nil {
+		return buffer, fmt.Errorf("field %q: %w", key, err)
+	}
+	return buffer, nil
+}
+
+// FluxCsvToLineProtocol transforms annotated CSV returned by flux queries into line protocol data
+func FluxCsvToLineProtocol(reader io.Reader) *FluxCsvToLineReader {
+	return &FluxCsvToLineReader{
+		result: fluxcsv.NewQueryTableResult(io.NopCloser(reader)),
+	}
+}
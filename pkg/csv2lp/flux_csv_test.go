@@ -0,0 +1,129 @@
+package csv2lp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_FluxCsvToLineProtocol tests conversion of flux query results to line protocol data
+func Test_FluxCsvToLineProtocol(t *testing.T) {
+	var tests = []struct {
+		name  string
+		csv   string
+		lines string
+		err   string
+	}{
+		{
+			name: "field and value rows",
+			csv: `#group,false,false,true,true,false,false,true,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T00:00:01Z,1.5,usage,cpu,a
+,,0,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T00:00:02Z,2.5,usage,cpu,a
+
+#group,false,false,true,true,false,false,true,true,true
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,long,string,string,string
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,1,2021-01-01T00:00:00Z,2021-01-02T00:00:00Z,2021-01-01T00:00:01Z,3,count,cpu,b
+`,
+			lines: "cpu,host=a usage=1.5 1609459201000000000\n" +
+				"cpu,host=a usage=2.5 1609459202000000000\n" +
+				"cpu,host=b count=3i 1609459201000000000\n",
+		},
+		{
+			name: "ungrouped columns of field and value rows",
+			csv: `#group,false,false,false,false,false,true,false,false
+#datatype,string,long,dateTime:RFC3339,double,string,string,string,long
+#default,_result,,,,,,,
+,result,table,_time,_value,_field,_measurement,host,core
+,,0,2021-01-01T00:00:01Z,1.5,usage,cpu,a,1
+,,0,2021-01-01T00:00:01Z,2.5,usage,cpu,b,
+`,
+			lines: "cpu,core=1,host=a usage=1.5 1609459201000000000\n" +
+				"cpu,host=b usage=2.5 1609459201000000000\n",
+		},
+		{
+			name: "consecutive rows of a series are merged",
+			csv: `#group,false,false,false,false,true,true,true
+#datatype,string,long,dateTime:RFC3339,string,string,string,string
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,region
+,,0,2021-01-01T00:00:01Z,x,a,m,us west
+,,0,2021-01-01T00:00:01Z,"y ""z""",b,m,us west
+,,0,2021-01-01T00:00:02Z,w,a,m,us west
+`,
+			lines: "m,region=us\\ west a=\"x\",b=\"y \\\"z\\\"\" 1609459201000000000\n" +
+				"m,region=us\\ west a=\"w\" 1609459202000000000\n",
+		},
+		{
+			name: "pivoted rows",
+			csv: `#group,false,false,true,false,true,false,false,false
+#datatype,string,long,string,dateTime:RFC3339,string,double,unsignedLong,boolean
+#default,_result,,,,,,,
+,result,table,_measurement,_time,host,f1,f2,f3
+,,0,m,2021-01-01T00:00:01Z,a,1,2,true
+,,0,m,2021-01-01T00:00:02Z,a,,3,false
+`,
+			lines: "m,host=a f1=1,f2=2u,f3=true 1609459201000000000\n" +
+				"m,host=a f2=3u,f3=false 1609459202000000000\n",
+		},
+		{
+			name: "missing measurement",
+			csv: `#datatype,string,long,string,double
+#group,false,false,true,false
+#default,_result,,,
+,result,table,_field,_value
+,,0,f,1
+`,
+			err: "table 0: no _measurement value",
+		},
+		{
+			name: "query error",
+			csv: `#datatype,string,string
+#group,true,true
+#default,,
+,error,reference
+,failed to execute query,897
+`,
+			err: "failed to parse flux csv: failed to execute query,897",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			lines, err := io.ReadAll(FluxCsvToLineProtocol(strings.NewReader(test.csv)))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.lines, string(lines))
+		})
+	}
+}
+
+// Test_FluxCsvToLineProtocol_skipRowOnError tests that rows which can't be converted are skipped
+func Test_FluxCsvToLineProtocol_skipRowOnError(t *testing.T) {
+	csv := `#group,false,false,false,false,true,true
+#datatype,string,long,dateTime:RFC3339,double,string,string
+#default,_result,,,,,
+,result,table,_time,_value,_field,_measurement
+,,0,2021-01-01T00:00:01Z,NaN,f,m
+,,0,2021-01-01T00:00:02Z,1,f,m
+`
+	_, err := io.ReadAll(FluxCsvToLineProtocol(strings.NewReader(csv)))
+	require.EqualError(t, err, `table 0: field "f": value is NaN`)
+
+	var out bytes.Buffer
+	_, err = io.Copy(&out, FluxCsvToLineProtocol(strings.NewReader(csv)).SkipRowOnError(true))
+	require.NoError(t, err)
+	require.Equal(t, "m f=1 1609459202000000000\n", out.String())
+}
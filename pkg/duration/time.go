@@ -0,0 +1,26 @@
+package duration
+
+import (
+	"strings"
+	"time"
+)
+
+// ParseTime parses s as an RFC3339 time, a negative duration relative to now like -7d, or "now".
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(s, "-") {
+		d, err := RawDurationToTimeDuration(s[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// IsRelativeTime returns true if s is parsed by ParseTime relative to the current time.
+func IsRelativeTime(s string) bool {
+	return s == "now" || strings.HasPrefix(s, "-")
+}
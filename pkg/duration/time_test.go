@@ -0,0 +1,78 @@
+package duration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-cli/v2/pkg/duration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name        string
+		input       string
+		expected    time.Time
+		relative    bool
+		expectedErr string
+	}{
+		{
+			name:     "rfc3339",
+			input:    "2021-01-02T03:04:05Z",
+			expected: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "rfc3339 nanos",
+			input:    "2021-01-02T03:04:05.123456789+01:00",
+			expected: time.Date(2021, 1, 2, 2, 4, 5, 123456789, time.UTC),
+		},
+		{
+			name:     "now",
+			input:    "now",
+			expected: now,
+			relative: true,
+		},
+		{
+			name:     "negative duration",
+			input:    "-7d",
+			expected: now.Add(-7 * duration.Day),
+			relative: true,
+		},
+		{
+			name:     "negative compound duration",
+			input:    "-1w2h",
+			expected: now.Add(-duration.Week - 2*time.Hour),
+			relative: true,
+		},
+		{
+			name:        "bad duration",
+			input:       "-7x",
+			relative:    true,
+			expectedErr: duration.ErrInvalidUnit.Error(),
+		},
+		{
+			name:        "positive duration",
+			input:       "7d",
+			expectedErr: `parsing time "7d" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "7d" as "2006"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.relative, duration.IsRelativeTime(tc.input))
+			parsed, err := duration.ParseTime(tc.input, now)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.expected.Equal(parsed), "expected %s, got %s", tc.expected, parsed)
+		})
+	}
+}